@@ -1,7 +1,6 @@
 package auth
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +9,7 @@ import (
 	"time"
 
 	"satplan/models"
+	"satplan/store"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -107,7 +107,7 @@ func Middleware(next http.Handler) http.Handler {
 }
 
 // LoginHandler handles user login requests
-func LoginHandler(db *sql.DB) http.HandlerFunc {
+func LoginHandler(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -134,11 +134,8 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Query user from database
-		var user models.User
-		err := db.QueryRow("SELECT id, user_name, password, email FROM sys_user WHERE user_name = ?", loginReq.Username).
-			Scan(&user.ID, &user.Username, &user.Password, &user.Email)
-
-		if err == sql.ErrNoRows {
+		user, err := st.Users.GetByUsername(r.Context(), loginReq.Username)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "Invalid username or password",
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"satplan/models"
+	"satplan/store"
+)
+
+// GenerateFeedToken returns a new random feed token and the hash stored for it
+func GenerateFeedToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, HashFeedToken(token), nil
+}
+
+// HashFeedToken returns the SHA-256 hash under which a feed token is stored
+func HashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FeedTokenMiddleware returns an HTTP middleware that authenticates calendar feed
+// requests by the "token" query parameter, since calendar clients cannot send a JWT
+func FeedTokenMiddleware(st *store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				w.Header().Set("Content-Type", "application/json")
+				response := models.Response{
+					Success: false,
+					Message: "Feed token required",
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			tokenID, user, err := st.FeedTokens.Lookup(r.Context(), HashFeedToken(token))
+			if err != nil {
+				if err != store.ErrNotFound {
+					log.Printf("Failed to look up feed token: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				response := models.Response{
+					Success: false,
+					Message: "Invalid or revoked feed token",
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			if err := st.FeedTokens.MarkUsed(r.Context(), tokenID, time.Now().Unix()); err != nil {
+				log.Printf("Failed to record feed token use: %v", err)
+			}
+
+			// Add user info to request context
+			r.Header.Set("X-User-ID", fmt.Sprintf("%d", user.ID))
+			r.Header.Set("X-Username", user.Username)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Package czml writes CZML documents for replaying plans in Cesium
+package czml
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+// Packet is a single CZML packet
+type Packet struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Availability string    `json:"availability,omitempty"`
+	Clock        *Clock    `json:"clock,omitempty"`
+	Position     *Position `json:"position,omitempty"`
+	Point        *Point    `json:"point,omitempty"`
+	Label        *Label    `json:"label,omitempty"`
+	Path         *Path     `json:"path,omitempty"`
+	Polygon      *Polygon  `json:"polygon,omitempty"`
+	Polyline     *Polyline `json:"polyline,omitempty"`
+}
+
+// Clock configures the playback clock of the document packet
+type Clock struct {
+	Interval    string `json:"interval"`
+	CurrentTime string `json:"currentTime"`
+	Multiplier  int    `json:"multiplier"`
+	Range       string `json:"range"`
+	Step        string `json:"step"`
+}
+
+// Position is a sampled position in the earth-fixed frame, in metres
+type Position struct {
+	Epoch                  string    `json:"epoch"`
+	ReferenceFrame         string    `json:"referenceFrame"`
+	InterpolationAlgorithm string    `json:"interpolationAlgorithm"`
+	InterpolationDegree    int       `json:"interpolationDegree"`
+	Cartesian              []float64 `json:"cartesian"`
+}
+
+// Color is an RGBA colour with components 0-255
+type Color struct {
+	RGBA [4]int `json:"rgba"`
+}
+
+// Material is a solid colour material
+type Material struct {
+	SolidColor struct {
+		Color Color `json:"color"`
+	} `json:"solidColor"`
+}
+
+// Point is a point graphic
+type Point struct {
+	PixelSize    int   `json:"pixelSize"`
+	Color        Color `json:"color"`
+	OutlineColor Color `json:"outlineColor"`
+	OutlineWidth int   `json:"outlineWidth"`
+}
+
+// Label is a text label graphic
+type Label struct {
+	Text        string     `json:"text"`
+	Font        string     `json:"font"`
+	FillColor   Color      `json:"fillColor"`
+	PixelOffset Cartesian2 `json:"pixelOffset"`
+}
+
+// Cartesian2 is a 2D offset
+type Cartesian2 struct {
+	Cartesian2 [2]float64 `json:"cartesian2"`
+}
+
+// Path is the orbit trail graphic of a moving entity
+type Path struct {
+	Width      int      `json:"width"`
+	LeadTime   float64  `json:"leadTime"`
+	TrailTime  float64  `json:"trailTime"`
+	Resolution int      `json:"resolution"`
+	Material   Material `json:"material"`
+}
+
+// Positions is a list of cartographic positions, optionally valid only in an interval
+type Positions struct {
+	Interval            string    `json:"interval,omitempty"`
+	CartographicDegrees []float64 `json:"cartographicDegrees"`
+}
+
+// Polygon is a ground polygon graphic
+type Polygon struct {
+	Positions    interface{} `json:"positions"`
+	Material     Material    `json:"material"`
+	Outline      bool        `json:"outline"`
+	OutlineColor Color       `json:"outlineColor"`
+	Height       float64     `json:"height"`
+}
+
+// Polyline is a line graphic
+type Polyline struct {
+	Positions     interface{} `json:"positions"`
+	Width         int         `json:"width"`
+	Material      Material    `json:"material"`
+	ClampToGround bool        `json:"clampToGround"`
+}
+
+// Track is the sampled earth-fixed trajectory of one satellite
+type Track struct {
+	NoradID  string
+	Name     string
+	HexColor string
+	Period   time.Duration
+	States   []orbit.State
+}
+
+// Options controls the content of a generated document
+type Options struct {
+	Name           string
+	Start, End     time.Time
+	TerminatorStep time.Duration
+}
+
+// Build assembles a CZML document with satellite positions, strip polygons and the
+// day/night terminator for the given time window
+func Build(opts Options, tracks []Track, strips []models.Strip) []Packet {
+	interval := Interval(opts.Start, opts.End)
+	packets := []Packet{{
+		ID:      "document",
+		Name:    opts.Name,
+		Version: "1.0",
+		Clock: &Clock{
+			Interval:    interval,
+			CurrentTime: formatTime(opts.Start),
+			Multiplier:  60,
+			Range:       "LOOP_STOP",
+			Step:        "SYSTEM_CLOCK_MULTIPLIER",
+		},
+	}}
+
+	for _, track := range tracks {
+		packets = append(packets, satellitePacket(track, interval))
+	}
+	for i, strip := range strips {
+		packets = append(packets, stripPacket(i, strip))
+	}
+	packets = append(packets, terminatorPackets(opts)...)
+
+	return packets
+}
+
+// Interval formats an ISO 8601 time interval
+func Interval(start, end time.Time) string {
+	return formatTime(start) + "/" + formatTime(end)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+func satellitePacket(track Track, interval string) Packet {
+	color := ParseHexColor(track.HexColor, 255)
+	cartesian := make([]float64, 0, len(track.States)*4)
+	var epoch time.Time
+	if len(track.States) > 0 {
+		epoch = track.States[0].Time
+	}
+	for _, s := range track.States {
+		cartesian = append(cartesian, s.Time.Sub(epoch).Seconds(),
+			s.Position.X*1000, s.Position.Y*1000, s.Position.Z*1000)
+	}
+
+	// half an orbit ahead and behind shows the ground track without cluttering the globe
+	period := track.Period.Seconds()
+
+	return Packet{
+		ID:           "sat-" + track.NoradID,
+		Name:         track.Name,
+		Description:  fmt.Sprintf("NORAD %s", track.NoradID),
+		Availability: interval,
+		Position: &Position{
+			Epoch:                  formatTime(epoch),
+			ReferenceFrame:         "FIXED",
+			InterpolationAlgorithm: "LAGRANGE",
+			InterpolationDegree:    5,
+			Cartesian:              cartesian,
+		},
+		Point: &Point{
+			PixelSize:    8,
+			Color:        color,
+			OutlineColor: Color{RGBA: [4]int{255, 255, 255, 255}},
+			OutlineWidth: 1,
+		},
+		Label: &Label{
+			Text:        track.Name,
+			Font:        "12pt sans-serif",
+			FillColor:   color,
+			PixelOffset: Cartesian2{Cartesian2: [2]float64{12, 0}},
+		},
+		Path: &Path{
+			Width:      1,
+			LeadTime:   period / 2,
+			TrailTime:  period / 2,
+			Resolution: 60,
+			Material:   solidMaterial(color),
+		},
+	}
+}
+
+func stripPacket(index int, strip models.Strip) Packet {
+	start := time.Unix(strip.StartTimestamp, 0)
+	stop := time.Unix(strip.StopTimestamp, 0)
+	color := ParseHexColor(strip.HexColor, 255)
+	fill := color
+	fill.RGBA[3] = 90
+
+	degrees := make([]float64, 0, len(strip.Coordinates)*3)
+	for _, c := range strip.Coordinates {
+		degrees = append(degrees, c[0], c[1], 0)
+	}
+
+	return Packet{
+		ID:   "strip-" + strconv.Itoa(index),
+		Name: fmt.Sprintf("%s %s", strip.SatName, strip.SensorName),
+		Description: fmt.Sprintf("%s / %s<br>Start: %s<br>Stop: %s<br>Side angle: %.1f°",
+			strip.SatName, strip.SensorName, formatTime(start), formatTime(stop), strip.SideAngle),
+		Availability: Interval(start, stop),
+		Polygon: &Polygon{
+			Positions:    Positions{CartographicDegrees: degrees},
+			Material:     solidMaterial(fill),
+			Outline:      true,
+			OutlineColor: color,
+		},
+	}
+}
+
+// terminatorPackets renders the night side and terminator line as interval-valued
+// geometry, refreshed every TerminatorStep
+func terminatorPackets(opts Options) []Packet {
+	step := opts.TerminatorStep
+	if step <= 0 {
+		step = 10 * time.Minute
+	}
+
+	night := []Positions{}
+	line := []Positions{}
+	for t := opts.Start; t.Before(opts.End); t = t.Add(step) {
+		next := t.Add(step)
+		if next.After(opts.End) {
+			next = opts.End
+		}
+		interval := Interval(t, next)
+		mid := t.Add(next.Sub(t) / 2)
+
+		ring := orbit.NightPolygon(mid, 180)
+		degrees := make([]float64, 0, len(ring)*3)
+		for _, p := range ring {
+			degrees = append(degrees, p[0], p[1], 0)
+		}
+		night = append(night, Positions{Interval: interval, CartographicDegrees: degrees})
+
+		sunLat, sunLon := orbit.SubSolarPoint(mid)
+		points := orbit.TerminatorPoints(sunLat, sunLon, 180)
+		degrees = make([]float64, 0, len(points)*3)
+		for _, p := range points {
+			degrees = append(degrees, p[0], p[1], 0)
+		}
+		line = append(line, Positions{Interval: interval, CartographicDegrees: degrees})
+	}
+
+	interval := Interval(opts.Start, opts.End)
+	return []Packet{
+		{
+			ID:           "night",
+			Name:         "Night side",
+			Availability: interval,
+			Polygon: &Polygon{
+				Positions:    night,
+				Material:     solidMaterial(Color{RGBA: [4]int{0, 0, 0, 60}}),
+				OutlineColor: Color{RGBA: [4]int{0, 0, 0, 0}},
+			},
+		},
+		{
+			ID:           "terminator",
+			Name:         "Day/night terminator",
+			Availability: interval,
+			Polyline: &Polyline{
+				Positions:     line,
+				Width:         2,
+				Material:      solidMaterial(Color{RGBA: [4]int{245, 158, 11, 240}}),
+				ClampToGround: true,
+			},
+		},
+	}
+}
+
+func solidMaterial(c Color) Material {
+	var m Material
+	m.SolidColor.Color = c
+	return m
+}
+
+// ParseHexColor converts a #rrggbb colour to RGBA, falling back to the strip default colour
+func ParseHexColor(hex string, alpha int) Color {
+	if len(hex) == 7 && hex[0] == '#' {
+		if v, err := strconv.ParseUint(hex[1:], 16, 32); err == nil {
+			return Color{RGBA: [4]int{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), alpha}}
+		}
+	}
+	return Color{RGBA: [4]int{255, 204, 51, alpha}}
+}
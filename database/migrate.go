@@ -0,0 +1,520 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the numbered schema migrations, named NNNN_name.up.sql and
+// NNNN_name.down.sql. A migration must not change once it has been released; schema
+// changes are made by adding the next number.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// postgresMigrationFiles holds the migrations of a PostgreSQL database. They start from
+// the schema the SQLite migrations had reached when PostgreSQL support was added.
+//
+//go:embed migrations/postgres/*.sql
+var postgresMigrationFiles embed.FS
+
+//go:embed seed.sql
+var seedSQL string
+
+// seedPostgresSQL runs after seedSQL on PostgreSQL
+//
+//go:embed seed_postgres.sql
+var seedPostgresSQL string
+
+// Migration is a numbered schema change with the scripts applying and reverting it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // "" when the migration cannot be reverted
+}
+
+// MigrationStatus is a migration and, when it has been applied, the time it was applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time // zero when pending
+}
+
+// Migrations returns the embedded SQLite migrations ordered by version
+func Migrations() ([]Migration, error) {
+	return readMigrations(migrationFiles, "migrations")
+}
+
+// migrationsFor returns the migrations for the kind of database db is
+func migrationsFor(db *sql.DB) ([]Migration, error) {
+	if isPostgres(db) {
+		return readMigrations(postgresMigrationFiles, "migrations/postgres")
+	}
+	return Migrations()
+}
+
+// readMigrations reads the migration scripts of a directory ordered by version
+func readMigrations(files embed.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		number, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || !found || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		script, err := files.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestVersion returns the schema version reached by applying every migration for the
+// kind of database db is
+func LatestVersion(db *sql.DB) (int, error) {
+	migrations, err := migrationsFor(db)
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// Version returns the schema version of a database, 0 when no migration has been applied
+func Version(db *sql.DB) (int, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_version')"
+	if isPostgres(db) {
+		query = "SELECT to_regclass('schema_version') IS NOT NULL"
+	}
+	err := db.QueryRow(query).Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// Status lists every migration with the time it was applied
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := migrationsFor(db)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int]int64{}
+	if version, err := Version(db); err != nil {
+		return nil, err
+	} else if version > 0 {
+		rows, err := db.Query("SELECT version, applied_at FROM schema_version")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int
+			var at int64
+			if err := rows.Scan(&v, &at); err != nil {
+				return nil, err
+			}
+			applied[v] = at
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	status := []MigrationStatus{}
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			s.AppliedAt = time.Unix(at, 0)
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Migrate applies or reverts migrations until the database is at the target version.
+// Each migration runs in its own transaction together with its schema_version update, so
+// a failing migration leaves the database at the previous version.
+func Migrate(db *sql.DB, target int) error {
+	migrations, err := migrationsFor(db)
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > len(migrations) {
+		return fmt.Errorf("version %d does not exist, the latest is %d", target, len(migrations))
+	}
+	if err := Baseline(db); err != nil {
+		return err
+	}
+
+	version, err := Version(db)
+	if err != nil {
+		return err
+	}
+	for version < target {
+		m := migrations[version]
+		log.Printf("Applying migration %d %s", m.Version, m.Name)
+		err := runMigration(db, m.Up,
+			bind(db, "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)"), m.Version, m.Name, time.Now().Unix())
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %v", m.Version, m.Name, err)
+		}
+		version++
+	}
+	for version > target {
+		m := migrations[version-1]
+		if m.Down == "" {
+			return fmt.Errorf("migration %d %s cannot be reverted", m.Version, m.Name)
+		}
+		log.Printf("Reverting migration %d %s", m.Version, m.Name)
+		if err := runMigration(db, m.Down, bind(db, "DELETE FROM schema_version WHERE version = ?"), m.Version); err != nil {
+			return fmt.Errorf("reverting migration %d %s: %v", m.Version, m.Name, err)
+		}
+		version--
+	}
+	return nil
+}
+
+// Baseline creates the schema_version table and records the version of a database
+// created from init.sql before migrations existed, so that Version reports where the
+// migrations continue. Migrate calls it first. Both happen in one transaction, so a
+// failed adoption leaves the database as it was.
+func Baseline(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS "schema_version" (
+		"version"	INTEGER NOT NULL,
+		"name"	TEXT NOT NULL,
+		"applied_at"	BIGINT NOT NULL,
+		PRIMARY KEY("version")
+	)`); err != nil {
+		return err
+	}
+	if !isPostgres(db) {
+		migrations, err := Migrations()
+		if err != nil {
+			return err
+		}
+		if err := adoptLegacySchema(tx, migrations); err != nil {
+			return fmt.Errorf("failed to adopt existing schema: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// runMigration executes a migration script and the statement recording it in one
+// transaction. On SQLite, foreign key enforcement is switched off meanwhile, as SQLite
+// requires for rebuilding tables, and the foreign keys are checked before committing.
+func runMigration(db *sql.DB, script, record string, args ...interface{}) error {
+	ctx := context.Background()
+	if isPostgres(db) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(record, args...); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	violation := rows.Next()
+	rows.Close()
+	if violation {
+		return fmt.Errorf("foreign key check failed")
+	}
+	return tx.Commit()
+}
+
+// Seed loads the initial satellites, sensors, admin user and TLE source into a new
+// database
+func Seed(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(seedSQL); err != nil {
+		return err
+	}
+	if isPostgres(db) {
+		if _, err := tx.Exec(seedPostgresSQL); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// adoptLegacySchema records the schema version of a database created from init.sql
+// before migrations existed. Such a database has tables but no schema_version rows.
+// With sensor.sat_id it was created or upgraded by the last init.sql and is adopted at
+// version 2, otherwise at version 1. It is compared with the schema of the adopted
+// migrations, its missing tables, columns and indexes are added and the remaining
+// migrations apply as usual.
+func adoptLegacySchema(tx *sql.Tx, migrations []Migration) error {
+	var version int
+	err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	if err != nil || version > 0 {
+		return err
+	}
+	var legacy, foreignKeys bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'satellite')").Scan(&legacy)
+	if err != nil || !legacy {
+		return err
+	}
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM pragma_table_info('sensor') WHERE name = 'sat_id')").Scan(&foreignKeys)
+	if err != nil {
+		return err
+	}
+
+	adopted := 1
+	if foreignKeys {
+		adopted = 2
+	}
+	if err := alignSchema(tx, migrations[:adopted]); err != nil {
+		return err
+	}
+	log.Printf("Existing database without schema_version, recording it as version %d", adopted)
+	now := time.Now().Unix()
+	for _, m := range migrations[:adopted] {
+		if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execQuerier is implemented by *sql.DB and *sql.Tx
+type execQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// schemaObject is a table or index as recorded in sqlite_master
+type schemaObject struct {
+	kind, name, table, sql string
+}
+
+// columnInfo is a column as reported by PRAGMA table_info
+type columnInfo struct {
+	name, colType string
+	notNull, pk   bool
+	dflt          sql.NullString
+}
+
+// alignSchema adds to db the tables, columns and indexes of the SQLite schema built by
+// migrations that it lacks. The migrations run on an empty in-memory database and the two
+// schemas are compared through sqlite_master and PRAGMA table_info; data is never
+// changed. A missing column that SQLite cannot add, such as a primary key or a NOT NULL
+// column without a default, is an error.
+func alignSchema(db execQuerier, migrations []Migration) error {
+	ref, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return err
+	}
+	defer ref.Close()
+	// every connection would get a database of its own
+	ref.SetMaxOpenConns(1)
+	for _, m := range migrations {
+		if _, err := ref.Exec(m.Up); err != nil {
+			return fmt.Errorf("reference schema of migration %d: %v", m.Version, err)
+		}
+	}
+
+	want, err := schemaObjects(ref)
+	if err != nil {
+		return err
+	}
+	have, err := schemaObjects(db)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, o := range have {
+		existing[o.kind+" "+o.name] = true
+	}
+
+	// tables first, so that the indexes of new tables find them
+	for _, o := range want {
+		if o.kind != "table" {
+			continue
+		}
+		if !existing["table "+o.name] {
+			log.Printf("Creating table %s", o.name)
+			if _, err := db.Exec(o.sql); err != nil {
+				return fmt.Errorf("table %s: %v", o.name, err)
+			}
+			continue
+		}
+		if err := addMissingColumns(db, ref, o.name); err != nil {
+			return err
+		}
+	}
+	for _, o := range want {
+		if o.kind == "index" && !existing["index "+o.name] {
+			log.Printf("Creating index %s on %s", o.name, o.table)
+			if _, err := db.Exec(o.sql); err != nil {
+				return fmt.Errorf("index %s: %v", o.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addMissingColumns adds the columns of table in ref that db lacks
+func addMissingColumns(db, ref execQuerier, table string) error {
+	want, err := tableColumns(ref, table)
+	if err != nil {
+		return err
+	}
+	have, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, c := range have {
+		existing[c.name] = true
+	}
+	for _, c := range want {
+		if existing[c.name] {
+			continue
+		}
+		if c.pk || (c.notNull && !c.dflt.Valid) {
+			return fmt.Errorf("column %s.%s cannot be added to an existing table", table, c.name)
+		}
+		definition := fmt.Sprintf("%q %s", c.name, c.colType)
+		if c.notNull {
+			definition += " NOT NULL"
+		}
+		if c.dflt.Valid {
+			definition += " DEFAULT " + c.dflt.String
+		}
+		log.Printf("Adding column %s.%s", table, c.name)
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %q ADD COLUMN %s", table, definition)); err != nil {
+			return fmt.Errorf("column %s.%s: %v", table, c.name, err)
+		}
+	}
+	return nil
+}
+
+// schemaObjects lists the tables and explicitly created indexes of a SQLite database
+func schemaObjects(db execQuerier) ([]schemaObject, error) {
+	rows, err := db.Query(`SELECT type, name, tbl_name, sql FROM sqlite_master
+		WHERE type IN ('table', 'index') AND sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	objects := []schemaObject{}
+	for rows.Next() {
+		var o schemaObject
+		if err := rows.Scan(&o.kind, &o.name, &o.table, &o.sql); err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// tableColumns returns the columns of a table in their declared order
+func tableColumns(db execQuerier, table string) ([]columnInfo, error) {
+	rows, err := db.Query("SELECT name, type, \"notnull\", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := []columnInfo{}
+	for rows.Next() {
+		var c columnInfo
+		var pk int
+		if err := rows.Scan(&c.name, &c.colType, &c.notNull, &c.dflt, &pk); err != nil {
+			return nil, err
+		}
+		c.pk = pk > 0
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,252 @@
+CREATE TABLE IF NOT EXISTS "satellite" (
+	"id"	INTEGER NOT NULL,
+	"noard_id"	TEXT,
+	"name"	text,
+	"hex_color"	TEXT,
+	"max_roll_rate"	real,
+	"max_pitch_rate"	real,
+	"roll_acceleration"	real,
+	"pitch_acceleration"	real,
+	"settle_time"	real,
+	"recorder_capacity"	real,
+	"downlink_rate"	real,
+	"intl_designator"	TEXT,
+	"operator"	TEXT,
+	"country"	TEXT,
+	"launch_date"	TEXT,
+	"status"	TEXT,
+	"orbit_class"	TEXT,
+	"notes"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "sensor" (
+	"id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT,
+	"sat_name"	text,
+	"name"	text,
+	"resolution"	real,
+	"width"	real,
+	"right_side_angle"	real,
+	"left_side_angle"	real,
+	"observe_angle"	real,
+	"hex_color"	TEXT,
+	"init_angle"	real,
+	"data_rate"	real,
+	"max_on_time"	real,
+	"max_acquisitions"	INTEGER,
+	"min_gap"	real,
+	"eclipse_aware"	INTEGER DEFAULT 0,
+	"max_pitch_angle"	real,
+	"sensor_type"	TEXT DEFAULT 'optical',
+	"near_incidence"	real,
+	"far_incidence"	real,
+	"look_side"	TEXT,
+	"orbit_direction"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "sys_user" (
+	"id"	INTEGER NOT NULL,
+	"user_name"	,
+	"password"	,
+	"email"	,
+	PRIMARY KEY("id")
+);
+CREATE TABLE IF NOT EXISTS "tle" (
+	"id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT,
+	"time"	INTEGER,
+	"line1"	TEXT,
+	"line2"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "tle_site" (
+	"id"	INTEGER NOT NULL,
+	"site"	,
+	"url"	,
+	"description"	,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "plan" (
+	"id"	INTEGER NOT NULL,
+	"user_id"	INTEGER,
+	"name"	TEXT,
+	"min_lon"	real,
+	"max_lon"	real,
+	"min_lat"	real,
+	"max_lat"	real,
+	"start_time"	INTEGER,
+	"end_time"	INTEGER,
+	"created_at"	INTEGER,
+	"include_inactive"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "plan_sensor" (
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER NOT NULL,
+	"side_angle"	real,
+	"mode_id"	INTEGER,
+	PRIMARY KEY("plan_id","sensor_id")
+);
+CREATE TABLE IF NOT EXISTS "feed_token" (
+	"id"	INTEGER NOT NULL,
+	"user_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"token_hash"	TEXT NOT NULL UNIQUE,
+	"created_at"	INTEGER,
+	"last_used_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "tasking_request" (
+	"id"	INTEGER NOT NULL,
+	"user_id"	INTEGER,
+	"name"	TEXT,
+	"description"	TEXT,
+	"min_lon"	real,
+	"max_lon"	real,
+	"min_lat"	real,
+	"max_lat"	real,
+	"start_time"	INTEGER,
+	"end_time"	INTEGER,
+	"max_resolution"	real,
+	"priority"	INTEGER,
+	"max_cloud_cover"	real,
+	"min_sun_elevation"	real,
+	"max_sun_elevation"	real,
+	"status"	TEXT,
+	"created_at"	INTEGER,
+	"updated_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "tasking_request_sensor" (
+	"request_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER NOT NULL,
+	PRIMARY KEY("request_id","sensor_id")
+);
+CREATE TABLE IF NOT EXISTS "tasking_request_event" (
+	"id"	INTEGER NOT NULL,
+	"request_id"	INTEGER NOT NULL,
+	"user_id"	INTEGER,
+	"action"	TEXT,
+	"from_status"	TEXT,
+	"to_status"	TEXT,
+	"changes"	TEXT,
+	"note"	TEXT,
+	"created_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "ground_station" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"lat"	real,
+	"lon"	real,
+	"alt"	real,
+	"min_elevation"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "point_target" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"lat"	real,
+	"lon"	real,
+	"alt"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "sensor_mode" (
+	"id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"type"	TEXT,
+	"resolution"	real,
+	"width"	real,
+	"right_side_angle"	real,
+	"left_side_angle"	real,
+	"observe_angle"	real,
+	"data_rate"	real,
+	"near_incidence"	real,
+	"far_incidence"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "band" (
+	"id"	INTEGER NOT NULL,
+	"mode_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"center_wavelength"	real,
+	"bandwidth"	real,
+	"gsd"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "conjunction_run" (
+	"id"	INTEGER NOT NULL,
+	"status"	TEXT,
+	"started_at"	INTEGER,
+	"finished_at"	INTEGER,
+	"window_start"	INTEGER,
+	"window_end"	INTEGER,
+	"threshold"	real,
+	"site_id"	INTEGER,
+	"objects"	INTEGER,
+	"pairs"	INTEGER,
+	"conjunctions"	INTEGER,
+	"message"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "conjunction" (
+	"id"	INTEGER NOT NULL,
+	"run_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT,
+	"sat_name"	TEXT,
+	"other_noard_id"	TEXT,
+	"other_name"	TEXT,
+	"tca"	INTEGER,
+	"miss_distance"	real,
+	"relative_velocity"	real,
+	"radial"	real,
+	"in_track"	real,
+	"cross_track"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "ephemeris" (
+	"id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT NOT NULL,
+	"format"	TEXT,
+	"object_name"	TEXT,
+	"frame"	TEXT,
+	"time_system"	TEXT,
+	"start_time"	INTEGER,
+	"end_time"	INTEGER,
+	"points"	INTEGER,
+	"interpolation"	TEXT,
+	"degree"	INTEGER,
+	"has_velocity"	INTEGER,
+	"created_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "ephemeris_point" (
+	"ephemeris_id"	INTEGER NOT NULL,
+	"epoch"	real,
+	"x"	real,
+	"y"	real,
+	"z"	real,
+	"vx"	real,
+	"vy"	real,
+	"vz"	real
+);
+CREATE TABLE IF NOT EXISTS "satellite_group" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"kind"	TEXT,
+	"parent_id"	INTEGER,
+	"description"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE TABLE IF NOT EXISTS "satellite_group_member" (
+	"group_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT NOT NULL,
+	PRIMARY KEY("group_id","sat_noard_id")
+);
+CREATE TABLE IF NOT EXISTS "plan_group" (
+	"plan_id"	INTEGER NOT NULL,
+	"group_id"	INTEGER NOT NULL,
+	"side_angle"	real,
+	PRIMARY KEY("plan_id","group_id")
+);
@@ -0,0 +1,54 @@
+-- Sensors and TLEs refer to their satellite by sat_id instead of a copy of its NORAD ID
+-- and name. Sensors and TLEs of satellites that no longer exist are dropped; the unique
+-- index fails when two satellites share a NORAD ID.
+CREATE UNIQUE INDEX "satellite_noard_id" ON "satellite"("noard_id");
+
+CREATE TABLE "sensor_new" (
+	"id"	INTEGER NOT NULL,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE RESTRICT,
+	"name"	text,
+	"resolution"	real,
+	"width"	real,
+	"right_side_angle"	real,
+	"left_side_angle"	real,
+	"observe_angle"	real,
+	"hex_color"	TEXT,
+	"init_angle"	real,
+	"data_rate"	real,
+	"max_on_time"	real,
+	"max_acquisitions"	INTEGER,
+	"min_gap"	real,
+	"eclipse_aware"	INTEGER DEFAULT 0,
+	"max_pitch_angle"	real,
+	"sensor_type"	TEXT DEFAULT 'optical',
+	"near_incidence"	real,
+	"far_incidence"	real,
+	"look_side"	TEXT,
+	"orbit_direction"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "sensor_new" ("id", "sat_id", "name", "resolution", "width", "right_side_angle",
+	"left_side_angle", "observe_angle", "hex_color", "init_angle", "data_rate", "max_on_time",
+	"max_acquisitions", "min_gap", "eclipse_aware", "max_pitch_angle", "sensor_type",
+	"near_incidence", "far_incidence", "look_side", "orbit_direction")
+SELECT s."id", sat."id", s."name", s."resolution", s."width", s."right_side_angle",
+	s."left_side_angle", s."observe_angle", s."hex_color", s."init_angle", s."data_rate", s."max_on_time",
+	s."max_acquisitions", s."min_gap", s."eclipse_aware", s."max_pitch_angle", s."sensor_type",
+	s."near_incidence", s."far_incidence", s."look_side", s."orbit_direction"
+FROM "sensor" s JOIN "satellite" sat ON sat."noard_id" = s."sat_noard_id";
+DROP TABLE "sensor";
+ALTER TABLE "sensor_new" RENAME TO "sensor";
+
+CREATE TABLE "tle_new" (
+	"id"	INTEGER NOT NULL,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	"time"	INTEGER,
+	"line1"	TEXT,
+	"line2"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "tle_new" ("id", "sat_id", "time", "line1", "line2")
+SELECT t."id", sat."id", t."time", t."line1", t."line2"
+FROM "tle" t JOIN "satellite" sat ON sat."noard_id" = t."sat_noard_id";
+DROP TABLE "tle";
+ALTER TABLE "tle_new" RENAME TO "tle";
@@ -0,0 +1,18 @@
+-- Ground footprints of the strips computed for plans. The ring is stored as JSON
+-- [lon, lat] pairs with its bounding box for area searches.
+CREATE TABLE "footprint" (
+	"id"	INTEGER NOT NULL,
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER,
+	"sat_noard_id"	TEXT,
+	"start_time"	INTEGER,
+	"stop_time"	INTEGER,
+	"coordinates"	TEXT NOT NULL,
+	"min_lon"	real,
+	"max_lon"	real,
+	"min_lat"	real,
+	"max_lat"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+CREATE INDEX "footprint_plan_id" ON "footprint"("plan_id");
+CREATE INDEX "footprint_bounds" ON "footprint"("min_lat", "max_lat", "min_lon", "max_lon");
@@ -0,0 +1,83 @@
+-- Ephemerides, group memberships, conjunctions and footprints go back to storing the
+-- NORAD ID of their satellite
+CREATE TABLE "ephemeris_old" (
+	"id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT NOT NULL,
+	"format"	TEXT,
+	"object_name"	TEXT,
+	"frame"	TEXT,
+	"time_system"	TEXT,
+	"start_time"	INTEGER,
+	"end_time"	INTEGER,
+	"points"	INTEGER,
+	"interpolation"	TEXT,
+	"degree"	INTEGER,
+	"has_velocity"	INTEGER,
+	"created_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "ephemeris_old" ("id", "sat_noard_id", "format", "object_name", "frame", "time_system", "start_time",
+	"end_time", "points", "interpolation", "degree", "has_velocity", "created_at")
+SELECT e."id", sat."noard_id", e."format", e."object_name", e."frame", e."time_system", e."start_time",
+	e."end_time", e."points", e."interpolation", e."degree", e."has_velocity", e."created_at"
+FROM "ephemeris" e JOIN "satellite" sat ON sat."id" = e."sat_id";
+DROP TABLE "ephemeris";
+ALTER TABLE "ephemeris_old" RENAME TO "ephemeris";
+
+CREATE TABLE "satellite_group_member_old" (
+	"group_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT NOT NULL,
+	PRIMARY KEY("group_id","sat_noard_id")
+);
+INSERT INTO "satellite_group_member_old" ("group_id", "sat_noard_id")
+SELECT m."group_id", sat."noard_id"
+FROM "satellite_group_member" m JOIN "satellite" sat ON sat."id" = m."sat_id";
+DROP TABLE "satellite_group_member";
+ALTER TABLE "satellite_group_member_old" RENAME TO "satellite_group_member";
+
+CREATE TABLE "conjunction_old" (
+	"id"	INTEGER NOT NULL,
+	"run_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT,
+	"sat_name"	TEXT,
+	"other_noard_id"	TEXT,
+	"other_name"	TEXT,
+	"tca"	INTEGER,
+	"miss_distance"	real,
+	"relative_velocity"	real,
+	"radial"	real,
+	"in_track"	real,
+	"cross_track"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "conjunction_old" ("id", "run_id", "sat_noard_id", "sat_name", "other_noard_id", "other_name", "tca",
+	"miss_distance", "relative_velocity", "radial", "in_track", "cross_track")
+SELECT c."id", c."run_id", sat."noard_id", c."sat_name", c."other_noard_id", c."other_name", c."tca",
+	c."miss_distance", c."relative_velocity", c."radial", c."in_track", c."cross_track"
+FROM "conjunction" c JOIN "satellite" sat ON sat."id" = c."sat_id";
+DROP TABLE "conjunction";
+ALTER TABLE "conjunction_old" RENAME TO "conjunction";
+
+CREATE TABLE "footprint_old" (
+	"id"	INTEGER NOT NULL,
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER,
+	"sat_noard_id"	TEXT,
+	"start_time"	INTEGER,
+	"stop_time"	INTEGER,
+	"coordinates"	TEXT NOT NULL,
+	"min_lon"	real,
+	"max_lon"	real,
+	"min_lat"	real,
+	"max_lat"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "footprint_old" ("id", "plan_id", "sensor_id", "sat_noard_id", "start_time", "stop_time", "coordinates",
+	"min_lon", "max_lon", "min_lat", "max_lat")
+SELECT f."id", f."plan_id", f."sensor_id", sat."noard_id", f."start_time", f."stop_time", f."coordinates",
+	f."min_lon", f."max_lon", f."min_lat", f."max_lat"
+FROM "footprint" f JOIN "satellite" sat ON sat."id" = f."sat_id";
+DROP TABLE "footprint";
+ALTER TABLE "footprint_old" RENAME TO "footprint";
+CREATE INDEX "footprint_plan_id" ON "footprint"("plan_id");
+CREATE INDEX "footprint_bounds" ON "footprint"("min_lat", "max_lat", "min_lon", "max_lon");
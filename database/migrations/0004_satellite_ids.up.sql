@@ -0,0 +1,87 @@
+-- Ephemerides, group memberships, conjunctions and footprints refer to their satellite by
+-- sat_id, so that they follow a change of NORAD ID and go with the satellite. Rows of
+-- satellites that no longer exist are dropped. The other object of a conjunction may be
+-- a catalogue object and keeps its NORAD ID.
+CREATE TABLE "ephemeris_new" (
+	"id"	INTEGER NOT NULL,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	"format"	TEXT,
+	"object_name"	TEXT,
+	"frame"	TEXT,
+	"time_system"	TEXT,
+	"start_time"	INTEGER,
+	"end_time"	INTEGER,
+	"points"	INTEGER,
+	"interpolation"	TEXT,
+	"degree"	INTEGER,
+	"has_velocity"	INTEGER,
+	"created_at"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "ephemeris_new" ("id", "sat_id", "format", "object_name", "frame", "time_system", "start_time",
+	"end_time", "points", "interpolation", "degree", "has_velocity", "created_at")
+SELECT e."id", sat."id", e."format", e."object_name", e."frame", e."time_system", e."start_time",
+	e."end_time", e."points", e."interpolation", e."degree", e."has_velocity", e."created_at"
+FROM "ephemeris" e JOIN "satellite" sat ON sat."noard_id" = e."sat_noard_id";
+DROP TABLE "ephemeris";
+ALTER TABLE "ephemeris_new" RENAME TO "ephemeris";
+CREATE INDEX "ephemeris_sat_id" ON "ephemeris"("sat_id");
+DELETE FROM "ephemeris_point" WHERE "ephemeris_id" NOT IN (SELECT "id" FROM "ephemeris");
+
+CREATE TABLE "satellite_group_member_new" (
+	"group_id"	INTEGER NOT NULL,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	PRIMARY KEY("group_id","sat_id")
+);
+INSERT INTO "satellite_group_member_new" ("group_id", "sat_id")
+SELECT m."group_id", sat."id"
+FROM "satellite_group_member" m JOIN "satellite" sat ON sat."noard_id" = m."sat_noard_id";
+DROP TABLE "satellite_group_member";
+ALTER TABLE "satellite_group_member_new" RENAME TO "satellite_group_member";
+
+CREATE TABLE "conjunction_new" (
+	"id"	INTEGER NOT NULL,
+	"run_id"	INTEGER NOT NULL,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	"sat_name"	TEXT,
+	"other_noard_id"	TEXT,
+	"other_name"	TEXT,
+	"tca"	INTEGER,
+	"miss_distance"	real,
+	"relative_velocity"	real,
+	"radial"	real,
+	"in_track"	real,
+	"cross_track"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "conjunction_new" ("id", "run_id", "sat_id", "sat_name", "other_noard_id", "other_name", "tca",
+	"miss_distance", "relative_velocity", "radial", "in_track", "cross_track")
+SELECT c."id", c."run_id", sat."id", c."sat_name", c."other_noard_id", c."other_name", c."tca",
+	c."miss_distance", c."relative_velocity", c."radial", c."in_track", c."cross_track"
+FROM "conjunction" c JOIN "satellite" sat ON sat."noard_id" = c."sat_noard_id";
+DROP TABLE "conjunction";
+ALTER TABLE "conjunction_new" RENAME TO "conjunction";
+
+CREATE TABLE "footprint_new" (
+	"id"	INTEGER NOT NULL,
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	"start_time"	INTEGER,
+	"stop_time"	INTEGER,
+	"coordinates"	TEXT NOT NULL,
+	"min_lon"	real,
+	"max_lon"	real,
+	"min_lat"	real,
+	"max_lat"	real,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+INSERT INTO "footprint_new" ("id", "plan_id", "sensor_id", "sat_id", "start_time", "stop_time", "coordinates",
+	"min_lon", "max_lon", "min_lat", "max_lat")
+SELECT f."id", f."plan_id", f."sensor_id", sat."id", f."start_time", f."stop_time", f."coordinates",
+	f."min_lon", f."max_lon", f."min_lat", f."max_lat"
+FROM "footprint" f JOIN "satellite" sat ON sat."noard_id" = f."sat_noard_id";
+DROP TABLE "footprint";
+ALTER TABLE "footprint_new" RENAME TO "footprint";
+CREATE INDEX "footprint_plan_id" ON "footprint"("plan_id");
+CREATE INDEX "footprint_bounds" ON "footprint"("min_lat", "max_lat", "min_lon", "max_lon");
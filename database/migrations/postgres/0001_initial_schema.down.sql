@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS "footprint";
+DROP TABLE IF EXISTS "plan_group";
+DROP TABLE IF EXISTS "satellite_group_member";
+DROP TABLE IF EXISTS "satellite_group";
+DROP TABLE IF EXISTS "ephemeris_point";
+DROP TABLE IF EXISTS "ephemeris";
+DROP TABLE IF EXISTS "conjunction";
+DROP TABLE IF EXISTS "conjunction_run";
+DROP TABLE IF EXISTS "band";
+DROP TABLE IF EXISTS "sensor_mode";
+DROP TABLE IF EXISTS "point_target";
+DROP TABLE IF EXISTS "ground_station";
+DROP TABLE IF EXISTS "tasking_request_event";
+DROP TABLE IF EXISTS "tasking_request_sensor";
+DROP TABLE IF EXISTS "tasking_request";
+DROP TABLE IF EXISTS "feed_token";
+DROP TABLE IF EXISTS "plan_sensor";
+DROP TABLE IF EXISTS "plan";
+DROP TABLE IF EXISTS "tle_site";
+DROP TABLE IF EXISTS "tle";
+DROP TABLE IF EXISTS "sys_user";
+DROP TABLE IF EXISTS "sensor";
+DROP TABLE IF EXISTS "satellite";
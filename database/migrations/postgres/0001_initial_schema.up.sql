@@ -0,0 +1,256 @@
+-- The schema of SQLite migrations 1 to 3 for PostgreSQL. Target areas of plans and
+-- tasking requests and the strip footprints are PostGIS geometries in WGS 84.
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE "satellite" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"noard_id"	TEXT NOT NULL UNIQUE,
+	"name"	TEXT,
+	"hex_color"	TEXT,
+	"max_roll_rate"	DOUBLE PRECISION,
+	"max_pitch_rate"	DOUBLE PRECISION,
+	"roll_acceleration"	DOUBLE PRECISION,
+	"pitch_acceleration"	DOUBLE PRECISION,
+	"settle_time"	DOUBLE PRECISION,
+	"recorder_capacity"	DOUBLE PRECISION,
+	"downlink_rate"	DOUBLE PRECISION,
+	"intl_designator"	TEXT,
+	"operator"	TEXT,
+	"country"	TEXT,
+	"launch_date"	TEXT,
+	"status"	TEXT,
+	"orbit_class"	TEXT,
+	"notes"	TEXT
+);
+CREATE TABLE "sensor" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE RESTRICT,
+	"name"	TEXT,
+	"resolution"	DOUBLE PRECISION,
+	"width"	DOUBLE PRECISION,
+	"right_side_angle"	DOUBLE PRECISION,
+	"left_side_angle"	DOUBLE PRECISION,
+	"observe_angle"	DOUBLE PRECISION,
+	"hex_color"	TEXT,
+	"init_angle"	DOUBLE PRECISION,
+	"data_rate"	DOUBLE PRECISION,
+	"max_on_time"	DOUBLE PRECISION,
+	"max_acquisitions"	INTEGER,
+	"min_gap"	DOUBLE PRECISION,
+	"eclipse_aware"	INTEGER DEFAULT 0,
+	"max_pitch_angle"	DOUBLE PRECISION,
+	"sensor_type"	TEXT DEFAULT 'optical',
+	"near_incidence"	DOUBLE PRECISION,
+	"far_incidence"	DOUBLE PRECISION,
+	"look_side"	TEXT,
+	"orbit_direction"	TEXT
+);
+CREATE INDEX "sensor_sat_id" ON "sensor"("sat_id");
+CREATE TABLE "sys_user" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"user_name"	TEXT UNIQUE,
+	"password"	TEXT,
+	"email"	TEXT
+);
+CREATE TABLE "tle" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"sat_id"	INTEGER NOT NULL REFERENCES "satellite"("id") ON DELETE CASCADE,
+	"time"	BIGINT,
+	"line1"	TEXT,
+	"line2"	TEXT
+);
+CREATE INDEX "tle_sat_id_time" ON "tle"("sat_id", "time");
+CREATE TABLE "tle_site" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"site"	TEXT,
+	"url"	TEXT,
+	"description"	TEXT
+);
+CREATE TABLE "plan" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"user_id"	INTEGER,
+	"name"	TEXT,
+	"min_lon"	DOUBLE PRECISION,
+	"max_lon"	DOUBLE PRECISION,
+	"min_lat"	DOUBLE PRECISION,
+	"max_lat"	DOUBLE PRECISION,
+	"start_time"	BIGINT,
+	"end_time"	BIGINT,
+	"created_at"	BIGINT,
+	"include_inactive"	INTEGER,
+	"aoi"	geometry(Polygon, 4326) GENERATED ALWAYS AS (ST_MakeEnvelope("min_lon", "min_lat", "max_lon", "max_lat", 4326)) STORED
+);
+CREATE INDEX "plan_aoi" ON "plan" USING GIST ("aoi");
+CREATE TABLE "plan_sensor" (
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER NOT NULL,
+	"side_angle"	DOUBLE PRECISION,
+	"mode_id"	INTEGER,
+	PRIMARY KEY("plan_id", "sensor_id")
+);
+CREATE TABLE "feed_token" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"user_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"token_hash"	TEXT NOT NULL UNIQUE,
+	"created_at"	BIGINT,
+	"last_used_at"	BIGINT
+);
+CREATE TABLE "tasking_request" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"user_id"	INTEGER,
+	"name"	TEXT,
+	"description"	TEXT,
+	"min_lon"	DOUBLE PRECISION,
+	"max_lon"	DOUBLE PRECISION,
+	"min_lat"	DOUBLE PRECISION,
+	"max_lat"	DOUBLE PRECISION,
+	"start_time"	BIGINT,
+	"end_time"	BIGINT,
+	"max_resolution"	DOUBLE PRECISION,
+	"priority"	INTEGER,
+	"max_cloud_cover"	DOUBLE PRECISION,
+	"min_sun_elevation"	DOUBLE PRECISION,
+	"max_sun_elevation"	DOUBLE PRECISION,
+	"status"	TEXT,
+	"created_at"	BIGINT,
+	"updated_at"	BIGINT,
+	"aoi"	geometry(Polygon, 4326) GENERATED ALWAYS AS (ST_MakeEnvelope("min_lon", "min_lat", "max_lon", "max_lat", 4326)) STORED
+);
+CREATE INDEX "tasking_request_aoi" ON "tasking_request" USING GIST ("aoi");
+CREATE TABLE "tasking_request_sensor" (
+	"request_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER NOT NULL,
+	PRIMARY KEY("request_id", "sensor_id")
+);
+CREATE TABLE "tasking_request_event" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"request_id"	INTEGER NOT NULL,
+	"user_id"	INTEGER,
+	"action"	TEXT,
+	"from_status"	TEXT,
+	"to_status"	TEXT,
+	"changes"	TEXT,
+	"note"	TEXT,
+	"created_at"	BIGINT
+);
+CREATE TABLE "ground_station" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"name"	TEXT,
+	"lat"	DOUBLE PRECISION,
+	"lon"	DOUBLE PRECISION,
+	"alt"	DOUBLE PRECISION,
+	"min_elevation"	DOUBLE PRECISION
+);
+CREATE TABLE "point_target" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"name"	TEXT,
+	"lat"	DOUBLE PRECISION,
+	"lon"	DOUBLE PRECISION,
+	"alt"	DOUBLE PRECISION
+);
+CREATE TABLE "sensor_mode" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"sensor_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"type"	TEXT,
+	"resolution"	DOUBLE PRECISION,
+	"width"	DOUBLE PRECISION,
+	"right_side_angle"	DOUBLE PRECISION,
+	"left_side_angle"	DOUBLE PRECISION,
+	"observe_angle"	DOUBLE PRECISION,
+	"data_rate"	DOUBLE PRECISION,
+	"near_incidence"	DOUBLE PRECISION,
+	"far_incidence"	DOUBLE PRECISION
+);
+CREATE TABLE "band" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"mode_id"	INTEGER NOT NULL,
+	"name"	TEXT,
+	"center_wavelength"	DOUBLE PRECISION,
+	"bandwidth"	DOUBLE PRECISION,
+	"gsd"	DOUBLE PRECISION
+);
+CREATE TABLE "conjunction_run" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"status"	TEXT,
+	"started_at"	BIGINT,
+	"finished_at"	BIGINT,
+	"window_start"	BIGINT,
+	"window_end"	BIGINT,
+	"threshold"	DOUBLE PRECISION,
+	"site_id"	INTEGER,
+	"objects"	INTEGER,
+	"pairs"	INTEGER,
+	"conjunctions"	INTEGER,
+	"message"	TEXT
+);
+CREATE TABLE "conjunction" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"run_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT,
+	"sat_name"	TEXT,
+	"other_noard_id"	TEXT,
+	"other_name"	TEXT,
+	"tca"	BIGINT,
+	"miss_distance"	DOUBLE PRECISION,
+	"relative_velocity"	DOUBLE PRECISION,
+	"radial"	DOUBLE PRECISION,
+	"in_track"	DOUBLE PRECISION,
+	"cross_track"	DOUBLE PRECISION
+);
+CREATE TABLE "ephemeris" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"sat_noard_id"	TEXT NOT NULL,
+	"format"	TEXT,
+	"object_name"	TEXT,
+	"frame"	TEXT,
+	"time_system"	TEXT,
+	"start_time"	BIGINT,
+	"end_time"	BIGINT,
+	"points"	INTEGER,
+	"interpolation"	TEXT,
+	"degree"	INTEGER,
+	"has_velocity"	INTEGER,
+	"created_at"	BIGINT
+);
+CREATE TABLE "ephemeris_point" (
+	"ephemeris_id"	INTEGER NOT NULL,
+	"epoch"	DOUBLE PRECISION,
+	"x"	DOUBLE PRECISION,
+	"y"	DOUBLE PRECISION,
+	"z"	DOUBLE PRECISION,
+	"vx"	DOUBLE PRECISION,
+	"vy"	DOUBLE PRECISION,
+	"vz"	DOUBLE PRECISION
+);
+CREATE INDEX "ephemeris_point_ephemeris_id" ON "ephemeris_point"("ephemeris_id", "epoch");
+CREATE TABLE "satellite_group" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"name"	TEXT NOT NULL,
+	"kind"	TEXT,
+	"parent_id"	INTEGER,
+	"description"	TEXT
+);
+CREATE TABLE "satellite_group_member" (
+	"group_id"	INTEGER NOT NULL,
+	"sat_noard_id"	TEXT NOT NULL,
+	PRIMARY KEY("group_id", "sat_noard_id")
+);
+CREATE TABLE "plan_group" (
+	"plan_id"	INTEGER NOT NULL,
+	"group_id"	INTEGER NOT NULL,
+	"side_angle"	DOUBLE PRECISION,
+	PRIMARY KEY("plan_id", "group_id")
+);
+CREATE TABLE "footprint" (
+	"id"	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	"plan_id"	INTEGER NOT NULL,
+	"sensor_id"	INTEGER,
+	"sat_noard_id"	TEXT,
+	"start_time"	BIGINT,
+	"stop_time"	BIGINT,
+	"geom"	geometry(Polygon, 4326) NOT NULL
+);
+CREATE INDEX "footprint_plan_id" ON "footprint"("plan_id");
+CREATE INDEX "footprint_geom" ON "footprint" USING GIST ("geom");
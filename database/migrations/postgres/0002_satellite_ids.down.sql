@@ -0,0 +1,18 @@
+-- Ephemerides, group memberships, conjunctions and footprints go back to storing the
+-- NORAD ID of their satellite
+ALTER TABLE "ephemeris" ADD COLUMN "sat_noard_id" TEXT;
+UPDATE "ephemeris" e SET "sat_noard_id" = sat."noard_id" FROM "satellite" sat WHERE sat."id" = e."sat_id";
+ALTER TABLE "ephemeris" ALTER COLUMN "sat_noard_id" SET NOT NULL, DROP COLUMN "sat_id";
+
+ALTER TABLE "satellite_group_member" ADD COLUMN "sat_noard_id" TEXT;
+UPDATE "satellite_group_member" m SET "sat_noard_id" = sat."noard_id" FROM "satellite" sat WHERE sat."id" = m."sat_id";
+ALTER TABLE "satellite_group_member" DROP CONSTRAINT "satellite_group_member_pkey", DROP COLUMN "sat_id",
+	ALTER COLUMN "sat_noard_id" SET NOT NULL, ADD PRIMARY KEY ("group_id", "sat_noard_id");
+
+ALTER TABLE "conjunction" ADD COLUMN "sat_noard_id" TEXT;
+UPDATE "conjunction" c SET "sat_noard_id" = sat."noard_id" FROM "satellite" sat WHERE sat."id" = c."sat_id";
+ALTER TABLE "conjunction" DROP COLUMN "sat_id";
+
+ALTER TABLE "footprint" ADD COLUMN "sat_noard_id" TEXT;
+UPDATE "footprint" f SET "sat_noard_id" = sat."noard_id" FROM "satellite" sat WHERE sat."id" = f."sat_id";
+ALTER TABLE "footprint" DROP COLUMN "sat_id";
@@ -0,0 +1,26 @@
+-- Ephemerides, group memberships, conjunctions and footprints refer to their satellite by
+-- sat_id, so that they follow a change of NORAD ID and go with the satellite. Rows of
+-- satellites that no longer exist are dropped. The other object of a conjunction may be
+-- a catalogue object and keeps its NORAD ID.
+ALTER TABLE "ephemeris" ADD COLUMN "sat_id" INTEGER REFERENCES "satellite"("id") ON DELETE CASCADE;
+UPDATE "ephemeris" e SET "sat_id" = sat."id" FROM "satellite" sat WHERE sat."noard_id" = e."sat_noard_id";
+DELETE FROM "ephemeris_point" WHERE "ephemeris_id" IN (SELECT "id" FROM "ephemeris" WHERE "sat_id" IS NULL);
+DELETE FROM "ephemeris" WHERE "sat_id" IS NULL;
+ALTER TABLE "ephemeris" ALTER COLUMN "sat_id" SET NOT NULL, DROP COLUMN "sat_noard_id";
+CREATE INDEX "ephemeris_sat_id" ON "ephemeris"("sat_id");
+
+ALTER TABLE "satellite_group_member" ADD COLUMN "sat_id" INTEGER REFERENCES "satellite"("id") ON DELETE CASCADE;
+UPDATE "satellite_group_member" m SET "sat_id" = sat."id" FROM "satellite" sat WHERE sat."noard_id" = m."sat_noard_id";
+DELETE FROM "satellite_group_member" WHERE "sat_id" IS NULL;
+ALTER TABLE "satellite_group_member" DROP CONSTRAINT "satellite_group_member_pkey", DROP COLUMN "sat_noard_id",
+	ADD PRIMARY KEY ("group_id", "sat_id");
+
+ALTER TABLE "conjunction" ADD COLUMN "sat_id" INTEGER REFERENCES "satellite"("id") ON DELETE CASCADE;
+UPDATE "conjunction" c SET "sat_id" = sat."id" FROM "satellite" sat WHERE sat."noard_id" = c."sat_noard_id";
+DELETE FROM "conjunction" WHERE "sat_id" IS NULL;
+ALTER TABLE "conjunction" ALTER COLUMN "sat_id" SET NOT NULL, DROP COLUMN "sat_noard_id";
+
+ALTER TABLE "footprint" ADD COLUMN "sat_id" INTEGER REFERENCES "satellite"("id") ON DELETE CASCADE;
+UPDATE "footprint" f SET "sat_id" = sat."id" FROM "satellite" sat WHERE sat."noard_id" = f."sat_noard_id";
+DELETE FROM "footprint" WHERE "sat_id" IS NULL;
+ALTER TABLE "footprint" ALTER COLUMN "sat_id" SET NOT NULL, DROP COLUMN "sat_noard_id";
@@ -0,0 +1,11 @@
+-- Initial data for a new database, loaded after all migrations have been applied
+INSERT INTO "satellite" ("id","noard_id","name","hex_color","intl_designator","operator","country","launch_date","status","orbit_class") VALUES (1,'33321','HJ-1A','#92d581','2008-041B','CRESDA','PRC','2008-09-06','active','LEO');
+INSERT INTO "satellite" ("id","noard_id","name","hex_color","intl_designator","operator","country","launch_date","status","orbit_class") VALUES (2,'33320','HJ-1B','#e77780','2008-041A','CRESDA','PRC','2008-09-06','active','LEO');
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (1,1,'CCD1',30.0,360.0,0.0,0.0,30.0,'#9983E9',-14.5);
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (2,1,'CCD2',30.0,360.0,0.0,0.0,30.0,'#FF8055',14.5);
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (3,1,'HSI',100.0,50.0,30.0,30.0,4.5,'#CC6633',0.0);
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (4,2,'CCD1',30.0,360.0,0.0,0.0,30.0,'#99E6FF',-14.5);
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (5,2,'CCD2',30.0,360.0,0.0,0.0,30.0,'#8fbc8f',14.5);
+INSERT INTO "sensor" ("id","sat_id","name","resolution","width","right_side_angle","left_side_angle","observe_angle","hex_color","init_angle") VALUES (6,2,'IRS',300.0,720.0,0.0,0.0,60.0,'#b87333',0.0);
+INSERT INTO "sys_user" VALUES (1,'admin','$2a$10$6l9rd9MGzWeYog0OggMP4OPi36rSkihsQ.8.6YMrFk8oWuGx1c5bq','test@test.com');
+INSERT INTO "tle_site" VALUES (1,'celestrak_resources','https://celestrak.org/NORAD/elements/gp.php?GROUP=resource&FORMAT=tle','celestrak');
@@ -0,0 +1,5 @@
+-- The seed data sets the IDs of its rows, so the identity sequences continue after them
+SELECT setval(pg_get_serial_sequence('"satellite"', 'id'), (SELECT MAX("id") FROM "satellite"));
+SELECT setval(pg_get_serial_sequence('"sensor"', 'id'), (SELECT MAX("id") FROM "sensor"));
+SELECT setval(pg_get_serial_sequence('"sys_user"', 'id'), (SELECT MAX("id") FROM "sys_user"));
+SELECT setval(pg_get_serial_sequence('"tle_site"', 'id'), (SELECT MAX("id") FROM "tle_site"));
@@ -0,0 +1,47 @@
+// Package geojson holds the minimal RFC 7946 types needed to export analysis grids
+package geojson
+
+// ContentType is the media type of GeoJSON documents
+const ContentType = "application/geo+json"
+
+// Geometry is a GeoJSON geometry object
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Feature is a GeoJSON feature
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection returns an empty feature collection
+func NewFeatureCollection() FeatureCollection {
+	return FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+}
+
+// Polygon returns a polygon geometry with a single exterior ring of [lon, lat] points
+func Polygon(ring [][]float64) Geometry {
+	return Geometry{Type: "Polygon", Coordinates: [][][]float64{ring}}
+}
+
+// Point returns a point geometry
+func Point(lon, lat float64) Geometry {
+	return Geometry{Type: "Point", Coordinates: []float64{lon, lat}}
+}
+
+// NewFeature returns a feature with the given geometry and properties
+func NewFeature(g Geometry, properties map[string]interface{}) Feature {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	return Feature{Type: "Feature", Geometry: g, Properties: properties}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// AutoSelectPlanStrips runs the greedy auto-select over the strips of a saved plan
+func AutoSelectPlanStrips(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var req models.AutoSelectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if req.Objective == "" {
+			req.Objective = string(planner.MaxCoverage)
+		}
+		if req.Method == "" {
+			req.Method = "grid"
+		}
+		if req.Method != "grid" && req.Method != "exact" {
+			response := models.Response{
+				Success: false,
+				Message: "method must be \"grid\" or \"exact\"",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		strips, err := computePlanStrips(r.Context(), st, &plan, filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		opts := planner.AutoSelectOptions{
+			Objective: planner.Objective(req.Objective),
+			GridSize:  req.GridSize,
+			Exact:     req.Method == "exact",
+			Weights:   req.Weights,
+		}
+		result, err := planner.AutoSelect(strips, planArea(&plan), opts)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := models.AutoSelectResponse{
+			Objective:  req.Objective,
+			Method:     req.Method,
+			Candidates: len(strips),
+			Coverage:   result.Coverage,
+			Strips:     []models.Strip{},
+		}
+		if !opts.Exact {
+			data.GridSize = req.GridSize
+			if data.GridSize == 0 {
+				data.GridSize = planner.DefaultGridSize
+			}
+		}
+		for _, idx := range result.Selected {
+			data.Strips = append(data.Strips, strips[idx])
+			data.TotalDuration += strips[idx].StopTimestamp - strips[idx].StartTimestamp
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Selected %d of %d strip(s) covering %.1f%% of the area", len(data.Strips), len(strips), result.Coverage),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// Screening defaults and limits
+const (
+	defaultScreeningDays      = 3.0
+	maxScreeningDays          = 7.0
+	defaultScreeningThreshold = 5.0 // km
+)
+
+// ScreenConjunctions starts a close-approach screening of our satellites against each
+// other and, with a site_id, against that site's catalogue. The run continues in the
+// background; its progress is read from /conjunction/runs/{id}.
+func ScreenConjunctions(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req models.ScreeningRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if req.Days == 0 {
+			req.Days = defaultScreeningDays
+		}
+		if req.Threshold == 0 {
+			req.Threshold = defaultScreeningThreshold
+		}
+		if req.Days < 0 || req.Days > maxScreeningDays || req.Threshold < 0 {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("days must be between 0 and %g and threshold must be positive", maxScreeningDays),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var site models.TLESite
+		if req.SiteID != 0 {
+			var err error
+			site, err = st.TLESites.Get(r.Context(), req.SiteID)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "TLE site not found",
+				}
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		now := time.Now().UTC()
+		run := models.ScreeningRun{
+			Status:    models.ScreeningRunning,
+			StartedAt: now.Unix(),
+			Start:     now.Unix(),
+			End:       now.Add(time.Duration(req.Days * float64(24*time.Hour))).Unix(),
+			Threshold: req.Threshold,
+			SiteID:    req.SiteID,
+		}
+		release, err := st.Screenings.Start(r.Context(), &run)
+		if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert screening run: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		go func() {
+			defer release()
+			runScreening(st, run, site)
+		}()
+
+		response := models.Response{
+			Success: true,
+			Message: "Screening started",
+			Data:    run,
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// runScreening propagates the objects of a run, stores the approaches found and records
+// the outcome on the run
+func runScreening(st *store.Store, run models.ScreeningRun, site models.TLESite) {
+	ctx := context.Background()
+	fail := func(err error) {
+		log.Printf("Screening run %d failed: %v", run.ID, err)
+		st.Screenings.Fail(ctx, run.ID, err.Error())
+	}
+
+	satellites, err := st.Satellites.List(ctx, store.SatelliteFilter{})
+	if err != nil {
+		fail(err)
+		return
+	}
+	noradIDs := []string{}
+	for _, sat := range satellites {
+		noradIDs = append(noradIDs, sat.NoardID)
+	}
+
+	start, end := time.Unix(run.Start, 0).UTC(), time.Unix(run.End, 0).UTC()
+	objects := []planner.ScreenObject{}
+	own := map[string]bool{}
+	notes := []string{}
+	for _, noradID := range noradIDs {
+		sat, err := loadSatellite(ctx, st, noradID, start, end)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", noradID, err))
+			continue
+		}
+		own[noradID] = true
+		objects = append(objects, planner.ScreenObject{
+			NoradID: noradID, Name: sat.Satellite.Name, Prop: sat.Propagator, Primary: true,
+		})
+	}
+	if len(objects) == 0 {
+		fail(fmt.Errorf("no satellite has a usable TLE"))
+		return
+	}
+
+	if site.ID != 0 {
+		catalogue, err := fetchNamedTLEs(site.URL)
+		if err != nil {
+			fail(fmt.Errorf("catalogue %s: %v", site.Site, err))
+			return
+		}
+		unsupported := 0
+		for _, t := range catalogue {
+			if own[t.SatNoardID] {
+				continue
+			}
+			prop, err := orbit.NewPropagatorFromTLE(t.Line1, t.Line2)
+			if err != nil {
+				unsupported++ // deep-space or malformed element sets
+				continue
+			}
+			objects = append(objects, planner.ScreenObject{NoradID: t.SatNoardID, Name: t.Name, Prop: prop})
+		}
+		if unsupported > 0 {
+			notes = append(notes, fmt.Sprintf("%d catalogue object(s) not propagated", unsupported))
+		}
+	}
+
+	conjunctions, stats := planner.Screen(objects, start, end, run.Threshold)
+	if stats.Skipped > 0 {
+		notes = append(notes, fmt.Sprintf("%d object(s) failed to propagate", stats.Skipped))
+	}
+
+	message := ""
+	for i, n := range notes {
+		if i > 0 {
+			message += "; "
+		}
+		message += n
+	}
+	run.Objects = len(objects)
+	run.Pairs = stats.Pairs
+	run.Message = message
+	found := make([]models.Conjunction, 0, len(conjunctions))
+	for _, c := range conjunctions {
+		p, s := objects[c.Primary], objects[c.Secondary]
+		found = append(found, models.Conjunction{
+			RunID: run.ID, SatNoardID: p.NoradID, SatName: p.Name, OtherNoardID: s.NoradID, OtherName: s.Name,
+			TCA: c.TCA.Unix(), MissDistance: c.MissDistance, RelativeVelocity: c.RelativeVelocity,
+			Radial: c.Radial, InTrack: c.InTrack, CrossTrack: c.CrossTrack,
+		})
+	}
+	// the insert transaction is closed before fail writes the run status, as SQLite would
+	// otherwise wait on its own write lock
+	if err := st.Screenings.Finish(ctx, run, found); err != nil {
+		fail(err)
+		return
+	}
+	log.Printf("Screening run %d: %d object(s), %d pair(s), %d conjunction(s)", run.ID, len(objects), stats.Pairs, len(conjunctions))
+}
+
+// GetScreeningRuns returns all screening runs, newest first
+func GetScreeningRuns(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		runs, err := st.Screenings.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query screening runs: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Screening runs retrieved successfully",
+			Data:    runs,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetScreeningRunById returns a single screening run by ID
+func GetScreeningRunById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		run, err := st.Screenings.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Screening run not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Screening run retrieved successfully",
+			Data:    run,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteScreeningRun deletes a finished screening run and its conjunctions
+func DeleteScreeningRun(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Screenings.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Screening run not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete screening run: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Screening run deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetConjunctions returns the conjunctions of a screening run by time of closest approach.
+// The run is given by ?run_id=, by default the latest finished one; ?sat_noard_id=,
+// ?max_miss= (km) and ?start=/?end= (unix seconds) narrow the list.
+func GetConjunctions(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		filter := store.ConjunctionFilter{SatNoardID: query.Get("sat_noard_id")}
+		if s := query.Get("run_id"); s != "" {
+			filter.RunID, _ = strconv.Atoi(s)
+		} else {
+			var err error
+			filter.RunID, err = st.Screenings.Latest(r.Context())
+			if err == store.ErrNotFound {
+				response := models.Response{
+					Success: false,
+					Message: "No screening run has finished yet",
+				}
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(response)
+				return
+			} else if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Database error: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		for _, p := range []struct {
+			name  string
+			value **float64
+		}{
+			{"max_miss", &filter.MaxMiss},
+			{"start", &filter.Start},
+			{"end", &filter.End},
+		} {
+			s := query.Get(p.name)
+			if s == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: p.name + " must be a number",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			*p.value = &v
+		}
+
+		conjunctions, err := st.Screenings.Conjunctions(r.Context(), filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query conjunctions: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d conjunction(s) in run %d", len(conjunctions), filter.RunID),
+			Data:    conjunctions,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/czml"
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// GetPlanCZML exports a saved plan as a CZML document for playback in Cesium.
+// The optional "step" query parameter sets the position sampling interval in seconds.
+func GetPlanCZML(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		step := 60 * time.Second
+		if s := r.URL.Query().Get("step"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds < 1 || seconds > 3600 {
+				response := models.Response{
+					Success: false,
+					Message: "step must be an integer number of seconds between 1 and 3600",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			step = time.Duration(seconds) * time.Second
+		}
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sats, err := loadPlanSatellites(r.Context(), st, &plan)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to load plan satellites: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		start := time.Unix(plan.StartTime, 0).UTC()
+		end := time.Unix(plan.EndTime, 0).UTC()
+
+		tracks := []czml.Track{}
+		for _, sat := range sats {
+			states, err := planner.Sample(sat.Propagator, start, end, step)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Failed to propagate satellite %s: %v", sat.Satellite.Name, err),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			tracks = append(tracks, czml.Track{
+				NoradID:  sat.Satellite.NoardID,
+				Name:     sat.Satellite.Name,
+				HexColor: sat.Satellite.HexColor,
+				Period:   sat.Propagator.Elements.Period(),
+				States:   states,
+			})
+		}
+
+		strips, err := computePlanStrips(r.Context(), st, &plan, filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		doc := czml.Build(czml.Options{Name: plan.Name, Start: start, End: end}, tracks, strips)
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"plan-%d.czml\"", plan.ID))
+		json.NewEncoder(w).Encode(doc)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// decayThresholds are the limits beyond which a satellite is flagged as decaying. They
+// are read once from the environment.
+var decayThresholds = struct {
+	minPerigee  float64 // km
+	maxRate     float64 // km/day of altitude loss
+	minLifetime float64 // days
+	trendSpan   time.Duration
+}{
+	minPerigee:  envFloat("DECAY_MIN_PERIGEE", 350),
+	maxRate:     envFloat("DECAY_MAX_RATE", 0.05),
+	minLifetime: envFloat("DECAY_MIN_LIFETIME", 365),
+	trendSpan:   time.Duration(envFloat("DECAY_TREND_DAYS", 30) * float64(24*time.Hour)),
+}
+
+// envFloat reads a number from the environment, falling back to def when it is unset or
+// not a number
+func envFloat(key string, def float64) float64 {
+	s := os.Getenv(key)
+	if s == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		log.Printf("Ignoring %s=%q: not a number", key, s)
+		return def
+	}
+	return v
+}
+
+// loadTLEHistory returns the parsed TLEs of a satellite, oldest first, with their IDs.
+// Sets that fail to parse are skipped.
+func loadTLEHistory(ctx context.Context, st *store.Store, noradID string) ([]*orbit.Elements, []int, error) {
+	tles, err := st.TLEs.ListBySatellite(ctx, noradID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sets := []*orbit.Elements{}
+	ids := []int{}
+	for i := len(tles) - 1; i >= 0; i-- {
+		el, err := orbit.ParseTLE(tles[i].Line1, tles[i].Line2)
+		if err != nil {
+			continue
+		}
+		sets = append(sets, el)
+		ids = append(ids, tles[i].ID)
+	}
+	return sets, ids, nil
+}
+
+// loadRecentTLEHistories returns the parsed TLEs of every satellite fetched within the
+// trend span of its latest one, by NORAD ID. Sets that fail to parse are skipped.
+func loadRecentTLEHistories(ctx context.Context, st *store.Store) (map[string][]*orbit.Elements, error) {
+	tles, err := st.TLEs.ListRecent(ctx, int64(decayThresholds.trendSpan/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	histories := map[string][]*orbit.Elements{}
+	for _, t := range tles {
+		el, err := orbit.ParseTLE(t.Line1, t.Line2)
+		if err != nil {
+			continue
+		}
+		histories[t.SatNoardID] = append(histories[t.SatNoardID], el)
+	}
+	return histories, nil
+}
+
+// satelliteDecay computes the decay indicators of a TLE history and flags the thresholds
+// crossed
+func satelliteDecay(history []*orbit.Elements) (*models.SatelliteDecay, error) {
+	d, err := planner.OrbitDecay(history, decayThresholds.trendSpan)
+	if err != nil {
+		return nil, err
+	}
+	decay := &models.SatelliteDecay{
+		Epoch:          d.Epoch.Unix(),
+		MeanMotion:     d.MeanMotion,
+		MeanMotionRate: d.MeanMotionRate,
+		Altitude:       d.Altitude,
+		Perigee:        d.Perigee,
+		Apogee:         d.Apogee,
+		AltitudeRate:   d.AltitudeRate,
+		BStar:          d.BStar,
+		Source:         "history",
+		Samples:        d.Samples,
+		Lifetime:       d.Lifetime,
+		Warnings:       []string{},
+	}
+	if d.Samples == 1 {
+		decay.Source = "ndot"
+	}
+	if d.Perigee < decayThresholds.minPerigee {
+		decay.Warnings = append(decay.Warnings, models.DecayLowPerigee)
+	}
+	if -d.AltitudeRate > decayThresholds.maxRate {
+		decay.Warnings = append(decay.Warnings, models.DecayFast)
+	}
+	if d.Lifetime > 0 && d.Lifetime < decayThresholds.minLifetime {
+		decay.Warnings = append(decay.Warnings, models.DecayShortLifetime)
+	}
+	decay.Flagged = len(decay.Warnings) > 0
+	return decay, nil
+}
+
+// GetSatelliteDecay returns the decay indicators of a satellite with the TLE history
+// they were derived from
+func GetSatelliteDecay(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		sat, err := st.Satellites.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		history, ids, err := loadTLEHistory(r.Context(), st, sat.NoardID)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query TLE data: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		decay, err := satelliteDecay(history)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("No decay estimate for %s: %v", sat.Name, err),
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := models.SatelliteDecayHistory{
+			SatID:      sat.ID,
+			SatNoardID: sat.NoardID,
+			SatName:    sat.Name,
+			Decay:      *decay,
+			History:    []models.DecaySample{},
+		}
+		for i, el := range history {
+			data.History = append(data.History, models.DecaySample{
+				TLEID:      ids[i],
+				Epoch:      el.Epoch.Unix(),
+				MeanMotion: el.MeanMotion,
+				Altitude:   el.SemiMajorAxis() - orbit.MeanEarthRadius,
+				NDot:       el.NDot,
+				BStar:      el.BStar,
+			})
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Decay indicators retrieved successfully",
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
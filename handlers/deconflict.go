@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+)
+
+// DeconflictRequests assigns strips to all approved tasking requests by priority and
+// deadline, with at most one pointing per satellite at a time. With ?apply=true the
+// requests that got an acquisition are moved to "scheduled". Only the sensors of active
+// satellites are used unless ?include_inactive=true.
+func DeconflictRequests(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		apply := false
+		if s := r.URL.Query().Get("apply"); s != "" {
+			v, err := strconv.ParseBool(s)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "apply must be true or false",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			apply = v
+		}
+
+		requests, err := st.Requests.List(r.Context(), store.TaskingRequestFilter{Status: models.RequestApproved})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query tasking requests: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sensors, err := st.Sensors.List(r.Context())
+		if err == nil && r.URL.Query().Get("include_inactive") != "true" {
+			sensors, err = activeSensors(r.Context(), st, sensors)
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query sensors: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// satellites are shared between requests, so they are loaded for the span of all of them
+		var spanStart, spanEnd int64
+		for i, t := range requests {
+			if i == 0 || t.StartTime < spanStart {
+				spanStart = t.StartTime
+			}
+			spanEnd = max(spanEnd, t.EndTime)
+		}
+
+		opts := planner.ScheduleOptions{Agility: map[string]planner.Agility{}, DutyCycles: map[int]planner.DutyCycle{}}
+		satellites := map[string]*planSatellite{}
+		satErrors := map[string]error{}
+		tasks := []planner.TaskRequest{}
+		byID := map[int]models.TaskingRequest{}
+		for i := range requests {
+			t := &requests[i]
+			byID[t.ID] = *t
+
+			task := planner.TaskRequest{ID: t.ID, Name: fmt.Sprintf("%s (priority %d)", t.Name, t.Priority)}
+			eligible := eligibleSensors(t, sensors)
+			if len(eligible) == 0 {
+				task.Rejected = "no sensor meets the sensor and resolution requirements"
+				tasks = append(tasks, task)
+				continue
+			}
+
+			// group the eligible sensors by satellite, at every side angle they can reach
+			groups := []planSatellite{}
+			for _, s := range eligible {
+				sat, ok := satellites[s.SatNoardID]
+				if !ok && satErrors[s.SatNoardID] == nil {
+					sat, err = loadSatellite(r.Context(), st, s.SatNoardID, time.Unix(spanStart, 0).UTC(), time.Unix(spanEnd, 0).UTC())
+					if err != nil {
+						satErrors[s.SatNoardID] = err
+					} else {
+						satellites[s.SatNoardID] = sat
+						opts.Agility[s.SatNoardID] = planner.AgilityOf(sat.Satellite)
+					}
+				}
+				if sat == nil {
+					if task.Rejected == "" {
+						task.Rejected = fmt.Sprintf("satellite %s: %v", s.SatName, satErrors[s.SatNoardID])
+					}
+					continue
+				}
+
+				g := -1
+				for k := range groups {
+					if groups[k].Satellite.NoardID == s.SatNoardID {
+						g = k
+					}
+				}
+				if g < 0 {
+					groups = append(groups, planSatellite{Satellite: sat.Satellite, Propagator: sat.Propagator})
+					g = len(groups) - 1
+				}
+				for _, angle := range planner.SideAngles(s) {
+					groups[g].Sensors = append(groups[g].Sensors, planner.Sensor{Sensor: s, SideAngle: angle})
+				}
+			}
+
+			for id, d := range dutyCycles(groups) {
+				if _, ok := opts.DutyCycles[id]; !ok {
+					opts.DutyCycles[id] = d
+				}
+			}
+
+			window := &models.Plan{
+				ID: t.ID, Name: t.Name,
+				MinLon: t.MinLon, MaxLon: t.MaxLon, MinLat: t.MinLat, MaxLat: t.MaxLat,
+				StartTime: t.StartTime, EndTime: t.EndTime,
+			}
+			if time.Duration(window.EndTime-window.StartTime)*time.Second > maxPlanDuration {
+				window.EndTime = window.StartTime + int64(maxPlanDuration/time.Second)
+			}
+			filter := planner.StripFilter{MinSunElevation: t.MinSunElevation, MaxSunElevation: t.MaxSunElevation}
+			if t.MaxResolution > 0 {
+				// the effective GSD off nadir must meet the request too, not only the nominal one
+				filter.MaxGSD = &t.MaxResolution
+			}
+			task.Candidates, err = satelliteStrips(groups, window, filter)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to compute strips: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			if len(task.Candidates) == 0 && task.Rejected == "" {
+				task.Rejected = "no strip of an eligible sensor over the target area before the deadline"
+				if t.MinSunElevation != nil || t.MaxSunElevation != nil {
+					task.Rejected += " within the sun elevation limits"
+				}
+			}
+			tasks = append(tasks, task)
+		}
+
+		if err := storageOptions(r.Context(), st, &opts, satellites, tasks); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute downlink contacts: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		result := planner.Schedule(tasks, opts)
+
+		data := models.DeconflictResponse{
+			Satellites:  []models.RequestSchedule{},
+			Unsatisfied: []models.UnsatisfiedRequest{},
+		}
+		for noradID, timeline := range result.Timelines {
+			schedule := models.RequestSchedule{SatNoardID: noradID, SatName: satellites[noradID].Satellite.Name}
+			for _, a := range timeline {
+				t := byID[a.Request]
+				schedule.Acquisitions = append(schedule.Acquisitions, models.RequestAcquisition{
+					RequestID:    t.ID,
+					RequestName:  t.Name,
+					Priority:     t.Priority,
+					SlewTime:     a.SlewTime,
+					DataVolume:   a.Volume / 1000,
+					RecorderFill: a.RecorderFill / 1000,
+					Strip:        a.Strip,
+				})
+			}
+			if downlinks, ok := result.Downlinks[noradID]; ok {
+				schedule.Downlinks = downlinkModels(downlinks)
+			}
+			data.Satellites = append(data.Satellites, schedule)
+		}
+		sort.Slice(data.Satellites, func(i, j int) bool {
+			return data.Satellites[i].SatName < data.Satellites[j].SatName
+		})
+		for _, u := range result.Unscheduled {
+			t := byID[u.Request]
+			data.Unsatisfied = append(data.Unsatisfied, models.UnsatisfiedRequest{
+				RequestID:   t.ID,
+				RequestName: t.Name,
+				Priority:    t.Priority,
+				Deadline:    t.EndTime,
+				Candidates:  u.Candidates,
+				Reason:      u.Reason,
+			})
+		}
+
+		if apply {
+			userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+			stale, err := scheduleAcquisitions(r.Context(), st, data.Satellites, userID)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to schedule tasking requests: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			if len(stale) > 0 {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Tasking request(s) %v are no longer approved, nothing was scheduled; run the deconfliction again", stale),
+					Data:    data,
+				}
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			data.Applied = true
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Assigned %d of %d approved request(s)", len(requests)-len(data.Unsatisfied), len(requests)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// scheduleAcquisitions moves the requests of the acquisitions to "scheduled" within one
+// transaction. If any of them is no longer approved, or has been deleted, nothing is
+// changed and their IDs are returned.
+func scheduleAcquisitions(ctx context.Context, st *store.Store, schedules []models.RequestSchedule, userID int) ([]int, error) {
+	notes := map[int]string{}
+	for _, schedule := range schedules {
+		for _, a := range schedule.Acquisitions {
+			notes[a.RequestID] = fmt.Sprintf("%s %s at %s UTC, side angle %.1f°", a.Strip.SatName, a.Strip.SensorName,
+				time.Unix(a.Strip.StartTimestamp, 0).UTC().Format("2006-01-02 15:04:05"), a.Strip.SideAngle)
+		}
+	}
+
+	rejected, err := st.Requests.Transition(ctx, "schedule", requestTransitions["schedule"], userID, notes)
+	if err != nil || len(rejected) == 0 {
+		return nil, err
+	}
+	stale := []int{}
+	for id := range rejected {
+		stale = append(stale, id)
+	}
+	sort.Ints(stale)
+	return stale, nil
+}
+
+// eligibleSensors returns the sensors that meet a request's sensor and resolution
+// requirements
+func eligibleSensors(t *models.TaskingRequest, sensors []models.Sensor) []models.Sensor {
+	allowed := map[int]bool{}
+	for _, id := range t.SensorIDs {
+		allowed[id] = true
+	}
+	eligible := []models.Sensor{}
+	for _, s := range sensors {
+		if len(allowed) > 0 && !allowed[s.ID] {
+			continue
+		}
+		if t.MaxResolution > 0 && s.Resolution > t.MaxResolution {
+			continue
+		}
+		eligible = append(eligible, s)
+	}
+	return eligible
+}
+
+// activeSensors returns the sensors whose satellite is active
+func activeSensors(ctx context.Context, st *store.Store, sensors []models.Sensor) ([]models.Sensor, error) {
+	active := map[string]bool{}
+	kept := []models.Sensor{}
+	for _, s := range sensors {
+		ok, seen := active[s.SatNoardID]
+		if !seen {
+			var err error
+			if ok, err = satelliteActive(ctx, st, s.SatNoardID); err != nil {
+				return nil, err
+			}
+			active[s.SatNoardID] = ok
+		}
+		if ok {
+			kept = append(kept, s)
+		}
+	}
+	return kept, nil
+}
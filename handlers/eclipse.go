@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+)
+
+// maxEclipseDuration bounds the time span of an eclipse search
+const maxEclipseDuration = 7 * 24 * time.Hour
+
+// GetEclipses computes the umbra and penumbra passages and the sunlit fraction of each
+// orbit of every satellite, or of the one given by ?sat_id=, between ?start= and ?end=
+// (unix seconds, default the next 24 hours)
+func GetEclipses(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		start, end, err := parseTimeRange(r, maxEclipseDuration)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var satellites []models.Satellite
+		if s := r.URL.Query().Get("sat_id"); s != "" {
+			id, _ := strconv.Atoi(s)
+			var sat models.Satellite
+			sat, err = st.Satellites.Get(r.Context(), id)
+			satellites = []models.Satellite{sat}
+		} else {
+			satellites, err = st.Satellites.List(r.Context(), store.SatelliteFilter{})
+		}
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query satellites: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := []models.SatelliteEclipses{}
+		for _, sat := range satellites {
+			entry := models.SatelliteEclipses{
+				SatID:      sat.ID,
+				SatNoardID: sat.NoardID,
+				SatName:    sat.Name,
+				Eclipses:   []models.Eclipse{},
+				Orbits:     []models.OrbitIllumination{},
+			}
+			loaded, err := loadSatellite(r.Context(), st, sat.NoardID, start, end)
+			if err != nil {
+				entry.Error = err.Error()
+				data = append(data, entry)
+				continue
+			}
+			eclipses, orbits, err := planner.Eclipses(loaded.Propagator, start, end)
+			if err != nil {
+				entry.Error = err.Error()
+				data = append(data, entry)
+				continue
+			}
+			for _, e := range eclipses {
+				entry.Eclipses = append(entry.Eclipses, models.Eclipse{
+					PenumbraStart: e.PenumbraStart,
+					UmbraStart:    e.UmbraStart,
+					UmbraEnd:      e.UmbraEnd,
+					PenumbraEnd:   e.PenumbraEnd,
+					Duration:      float64(e.PenumbraEnd - e.PenumbraStart),
+				})
+			}
+			for _, o := range orbits {
+				entry.Orbits = append(entry.Orbits, models.OrbitIllumination{
+					Start:          o.Start,
+					End:            o.End,
+					Umbra:          o.Umbra,
+					Penumbra:       o.Penumbra,
+					SunlitFraction: o.SunlitFraction,
+				})
+			}
+			data = append(data, entry)
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Computed eclipses of %d satellite(s)", len(data)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetSatellitePositions returns where each active satellite is at ?time= (unix seconds,
+// default now) and whether it is sunlit or in the earth's penumbra or umbra. Satellites
+// without a TLE or ephemeris for that time are left out.
+func GetSatellitePositions(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		t := time.Now().UTC()
+		if s := r.URL.Query().Get("time"); s != "" {
+			unix, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "time must be unix seconds",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			t = time.Unix(unix, 0).UTC()
+		}
+
+		satellites, err := st.Satellites.List(r.Context(), store.SatelliteFilter{Status: []string{models.SatelliteActive}})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query satellites: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := []models.SatellitePosition{}
+		for _, sat := range satellites {
+			loaded, err := loadSatellite(r.Context(), st, sat.NoardID, t, t)
+			if err != nil {
+				continue
+			}
+			state, err := loaded.Propagator.StateAt(t)
+			if err != nil {
+				continue
+			}
+			illumination := orbit.Illumination(state.Position, t)
+			shadow := models.ShadowPenumbra
+			switch illumination {
+			case 1:
+				shadow = models.ShadowSunlit
+			case 0:
+				shadow = models.ShadowUmbra
+			}
+			data = append(data, models.SatellitePosition{
+				SatNoardID:   sat.NoardID,
+				SatName:      sat.Name,
+				HexColor:     sat.HexColor,
+				Time:         t.Unix(),
+				Lat:          state.Geodetic.Lat,
+				Lon:          state.Geodetic.Lon,
+				Alt:          state.Geodetic.Alt,
+				Illumination: illumination,
+				Shadow:       shadow,
+			})
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Computed the positions of %d satellite(s)", len(data)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"satplan/models"
+	"satplan/oem"
+	"satplan/orbit"
+	"satplan/sp3"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// Interpolation degree limits of stored ephemerides
+const (
+	defaultEphemerisDegree = 7
+	maxEphemerisDegree     = 15
+)
+
+// ImportEphemeris stores a precise ephemeris of a satellite from a CCSDS OEM (KVN or XML)
+// or SP3 file in the request body. The query gives sat_noard_id, and optionally format
+// (oem or sp3, detected by default), sp3_id for the vehicle of a multi-vehicle SP3
+// file, interpolation (lagrange or hermite) and degree. States are converted to TEME and
+// UTC on import.
+func ImportEphemeris(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		noradID := query.Get("sat_noard_id")
+		sat, err := st.Satellites.GetByNoradID(r.Context(), noradID)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		format := strings.ToLower(query.Get("format"))
+		if format == "" {
+			format = "oem"
+			if bytes.HasPrefix(bytes.TrimSpace(body), []byte("#")) {
+				format = "sp3"
+			}
+		}
+
+		eph := models.Ephemeris{SatNoardID: noradID, Format: format, CreatedAt: time.Now().Unix()}
+		var points []orbit.EphemerisPoint
+		switch format {
+		case "oem":
+			points, err = ephemerisFromOEM(body, &eph)
+		case "sp3":
+			points, err = ephemerisFromSP3(body, query.Get("sp3_id"), &eph)
+		default:
+			err = fmt.Errorf("format must be oem or sp3")
+		}
+		if err == nil {
+			err = settleInterpolation(&eph, query.Get("interpolation"), query.Get("degree"))
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid ephemeris: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// the stored span is rounded inwards to whole seconds
+		eph.StartTime = int64(math.Ceil(float64(points[0].Time.UnixNano()) / 1e9))
+		eph.EndTime = points[len(points)-1].Time.Unix()
+		eph.Points = len(points)
+
+		err = st.Ephemerides.Create(r.Context(), &eph, points)
+		if err == store.ErrSatelliteNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert ephemeris: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Imported %d state(s) for %s", eph.Points, sat.Name),
+			Data:    eph,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// ephemerisFromOEM reads the segments of an OEM into TEME points, filling the metadata
+// of eph
+func ephemerisFromOEM(body []byte, eph *models.Ephemeris) ([]orbit.EphemerisPoint, error) {
+	segments, err := oem.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	points := []orbit.EphemerisPoint{}
+	for i, s := range segments {
+		system := s.TimeSystem
+		if system == "" {
+			system = "UTC"
+		}
+		if i == 0 {
+			eph.ObjectName = s.ObjectName
+			eph.Frame = s.Frame
+			eph.TimeSystem = system
+			eph.Interpolation = strings.ToLower(s.Interpolation)
+			eph.Degree = s.Degree
+		}
+		for _, st := range s.States {
+			t, err := orbit.ToUTC(st.Epoch, system)
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %v", i+1, err)
+			}
+			pos, vel, err := orbit.ToTEME(s.Frame, st.Position, st.Velocity, t)
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %v", i+1, err)
+			}
+			points = append(points, orbit.EphemerisPoint{Time: t, Position: pos, Velocity: vel})
+		}
+	}
+	eph.HasVelocity = true
+	return sortEphemerisPoints(points)
+}
+
+// ephemerisFromSP3 reads the records of one vehicle of an SP3 file into TEME points,
+// filling the metadata of eph
+func ephemerisFromSP3(body []byte, vehicle string, eph *models.Ephemeris) ([]orbit.EphemerisPoint, error) {
+	f, err := sp3.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if vehicle == "" {
+		if len(f.Vehicles) != 1 {
+			return nil, fmt.Errorf("the file holds %d vehicles (%s); choose one with sp3_id", len(f.Vehicles), strings.Join(f.Vehicles, ", "))
+		}
+		vehicle = f.Vehicles[0]
+	}
+	records, ok := f.Records[vehicle]
+	if !ok {
+		return nil, fmt.Errorf("vehicle %s is not in the file", vehicle)
+	}
+
+	eph.ObjectName = vehicle
+	eph.Frame = f.CoordinateSystem
+	eph.TimeSystem = f.TimeSystem
+	eph.HasVelocity = true
+	points := []orbit.EphemerisPoint{}
+	for _, rec := range records {
+		t, err := orbit.ToUTC(rec.Epoch, f.TimeSystem)
+		if err != nil {
+			return nil, err
+		}
+		pos, vel, err := orbit.ToTEME(f.CoordinateSystem, rec.Position, rec.Velocity, t)
+		if err != nil {
+			return nil, err
+		}
+		eph.HasVelocity = eph.HasVelocity && rec.HasVelocity
+		points = append(points, orbit.EphemerisPoint{Time: t, Position: pos, Velocity: vel})
+	}
+	if !eph.HasVelocity {
+		// velocities are derived by interpolation when any record lacks them
+		for i := range points {
+			points[i].Velocity = orbit.Vector{}
+		}
+	}
+	return sortEphemerisPoints(points)
+}
+
+// sortEphemerisPoints orders points by time and drops repeated epochs, as found at the
+// boundaries of OEM segments
+func sortEphemerisPoints(points []orbit.EphemerisPoint) ([]orbit.EphemerisPoint, error) {
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	unique := points[:0]
+	for _, p := range points {
+		if n := len(unique); n > 0 && unique[n-1].Time.Equal(p.Time) {
+			continue
+		}
+		unique = append(unique, p)
+	}
+	if len(unique) < 2 {
+		return nil, fmt.Errorf("an ephemeris needs at least two states")
+	}
+	return unique, nil
+}
+
+// settleInterpolation applies the requested interpolation method and degree, falling
+// back to those of the file and then to Hermite with velocities or Lagrange without
+func settleInterpolation(eph *models.Ephemeris, method, degree string) error {
+	if method != "" {
+		eph.Interpolation = strings.ToLower(method)
+	}
+	if eph.Interpolation != orbit.Lagrange && eph.Interpolation != orbit.Hermite {
+		if method != "" {
+			return fmt.Errorf("interpolation must be lagrange or hermite")
+		}
+		eph.Interpolation = orbit.Lagrange
+		if eph.HasVelocity {
+			eph.Interpolation = orbit.Hermite
+		}
+	}
+	if eph.Interpolation == orbit.Hermite && !eph.HasVelocity {
+		return fmt.Errorf("hermite interpolation needs velocities")
+	}
+
+	if degree != "" {
+		d, err := strconv.Atoi(degree)
+		if err != nil {
+			return fmt.Errorf("degree must be an integer")
+		}
+		eph.Degree = d
+	}
+	if eph.Degree == 0 {
+		eph.Degree = defaultEphemerisDegree
+	}
+	if eph.Degree < 1 || eph.Degree > maxEphemerisDegree {
+		return fmt.Errorf("degree must be between 1 and %d", maxEphemerisDegree)
+	}
+	return nil
+}
+
+// GetEphemerides returns the stored ephemerides, of one satellite with ?sat_noard_id=
+func GetEphemerides(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		ephemerides, err := st.Ephemerides.List(r.Context(), r.URL.Query().Get("sat_noard_id"))
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query ephemerides: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ephemerides retrieved successfully",
+			Data:    ephemerides,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetEphemerisById returns the metadata of a stored ephemeris
+func GetEphemerisById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		e, err := st.Ephemerides.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Ephemeris not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ephemeris retrieved successfully",
+			Data:    e,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteEphemeris deletes a stored ephemeris and its states
+func DeleteEphemeris(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Ephemerides.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Ephemeris not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete ephemeris: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ephemeris deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/auth"
+	"satplan/ical"
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// GetFeedTokens returns the authenticated user's calendar feed tokens
+func GetFeedTokens(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+		tokens, err := st.FeedTokens.List(r.Context(), userID)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query feed tokens: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Feed tokens retrieved successfully",
+			Data:    tokens,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddFeedToken creates a calendar feed token for the authenticated user.
+// The token itself is only returned in this response; only its hash is stored.
+func AddFeedToken(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req models.FeedToken
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if req.Name == "" {
+			response := models.Response{
+				Success: false,
+				Message: "name is required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		token, hash, err := auth.GenerateFeedToken()
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to generate feed token: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		feedToken := models.FeedToken{
+			Name:      req.Name,
+			Token:     token,
+			CreatedAt: time.Now().Unix(),
+		}
+		feedToken.UserID, _ = strconv.Atoi(r.Header.Get("X-User-ID"))
+
+		if err := st.FeedTokens.Create(r.Context(), &feedToken, hash); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert feed token: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Feed token created successfully. Store it now, it will not be shown again",
+			Data:    feedToken,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteFeedToken revokes one of the authenticated user's feed tokens
+func DeleteFeedToken(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+		userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+		err := st.FeedTokens.Delete(r.Context(), id, userID)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Feed token not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to revoke feed token: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Feed token revoked successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetPlanCalendar serves a plan's upcoming strips as an iCalendar feed with one VEVENT per
+// strip. The feed covers the rest of the plan's time window from now, or with "days" only
+// its next N days.
+func GetPlanCalendar(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		now := time.Now().UTC()
+		plan.StartTime = max(plan.StartTime, now.Unix())
+		if d := r.URL.Query().Get("days"); d != "" {
+			days, err := strconv.Atoi(d)
+			if err != nil || days < 1 || time.Duration(days)*24*time.Hour > maxPlanDuration {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("days must be an integer between 1 and %d", int(maxPlanDuration.Hours()/24)),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			plan.EndTime = min(plan.EndTime, now.Add(time.Duration(days)*24*time.Hour).Unix())
+		}
+
+		// a plan whose window has passed has no upcoming strips
+		strips := []models.Strip{}
+		if plan.EndTime > plan.StartTime {
+			strips, err = computePlanStrips(r.Context(), st, &plan, filter)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to compute strips: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		cal := ical.Calendar{Name: "SatPlan: " + plan.Name}
+		for _, s := range strips {
+			start := time.Unix(s.StartTimestamp, 0).UTC()
+			stop := time.Unix(s.StopTimestamp, 0).UTC()
+			center := planner.Centroid(s.Coordinates)
+			cal.Events = append(cal.Events, ical.Event{
+				UID:     fmt.Sprintf("plan%d-sat%s-sen%d-%d@satplan", plan.ID, s.SatNoardID, s.SensorID, s.StartTimestamp),
+				Start:   start,
+				End:     stop,
+				Summary: fmt.Sprintf("%s %s over %s", s.SatName, s.SensorName, plan.Name),
+				Description: fmt.Sprintf("Satellite: %s (NORAD %s)\nSensor: %s\nStart: %s UTC\nStop: %s UTC\nOff-nadir angle: %.1f°\nSide angle: %.1f°\nResolution: %g m",
+					s.SatName, s.SatNoardID, s.SensorName,
+					start.Format("2006-01-02 15:04:05"), stop.Format("2006-01-02 15:04:05"),
+					s.OffNadirAngle, s.SideAngle, s.Resolution),
+				Location: plan.Name,
+				Lat:      center[1],
+				Lon:      center[0],
+				HasGeo:   true,
+			})
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"plan-%d.ics\"", plan.ID))
+		if _, err := cal.WriteTo(w); err != nil {
+			log.Printf("Failed to write calendar for plan %d: %v", plan.ID, err)
+		}
+	}
+}
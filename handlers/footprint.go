@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// SavePlanFootprints computes the strips of a saved plan and stores their ground
+// footprints in place of those saved before, so that they can be searched by area
+func SavePlanFootprints(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		strips, err := computePlanStrips(r.Context(), st, &plan, planner.StripFilter{})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := st.Footprints.Replace(r.Context(), plan.ID, strips); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to save footprints: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Saved %d footprint(s)", len(strips)),
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// SearchFootprints returns the saved footprints overlapping the area given by ?min_lon=,
+// ?max_lon=, ?min_lat= and ?max_lat=, optionally acquired between ?start= and ?end=
+// (unix seconds) by the sensor given by ?sensor_id=
+func SearchFootprints(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		var q store.FootprintQuery
+		for _, p := range []struct {
+			name  string
+			value *float64
+		}{
+			{"min_lon", &q.Area.West},
+			{"max_lon", &q.Area.East},
+			{"min_lat", &q.Area.South},
+			{"max_lat", &q.Area.North},
+		} {
+			v, err := strconv.ParseFloat(query.Get(p.name), 64)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: p.name + " is required and must be a number",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			*p.value = v
+		}
+		if !q.Area.Valid() {
+			response := models.Response{
+				Success: false,
+				Message: "min_lon/max_lon/min_lat/max_lat must describe a non-empty box",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		for _, p := range []struct {
+			name  string
+			value *int64
+		}{
+			{"start", &q.Start},
+			{"end", &q.End},
+		} {
+			s := query.Get(p.name)
+			if s == "" {
+				continue
+			}
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: p.name + " must be a unix timestamp",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			*p.value = v
+		}
+		if s := query.Get("sensor_id"); s != "" {
+			q.SensorID, _ = strconv.Atoi(s)
+		}
+
+		footprints, err := st.Footprints.Search(r.Context(), q)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d footprint(s)", len(footprints)),
+			Data:    footprints,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"satplan/models"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// validateGroup checks the name and kind of a group
+func validateGroup(g models.SatelliteGroup) string {
+	if g.Name == "" {
+		return "name is required"
+	}
+	switch g.Kind {
+	case models.GroupConstellation, models.GroupOperator, models.GroupMission:
+	default:
+		return fmt.Sprintf("kind must be %s, %s or %s", models.GroupConstellation, models.GroupOperator, models.GroupMission)
+	}
+	return ""
+}
+
+// checkGroupParent verifies that parentID names an existing group other than id and its
+// descendants, so that the groups stay a forest. It returns a message for the client, or
+// an error if the database failed.
+func checkGroupParent(ctx context.Context, st *store.Store, id, parentID int) (string, error) {
+	for p := parentID; p != 0; {
+		if p == id {
+			return "a group cannot be nested inside itself or its subgroups", nil
+		}
+		next, err := st.Groups.Parent(ctx, p)
+		if err == store.ErrNotFound {
+			if p == parentID {
+				return fmt.Sprintf("parent group %d not found", parentID), nil
+			}
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		p = next
+	}
+	return "", nil
+}
+
+// GetSatelliteGroups returns all satellite groups with their direct members
+func GetSatelliteGroups(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		groups, err := st.Groups.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query groups: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Groups retrieved successfully",
+			Data:    groups,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetSatelliteGroupById returns a single satellite group by ID
+func GetSatelliteGroupById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		g, err := st.Groups.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Group not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group retrieved successfully",
+			Data:    g,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddSatelliteGroup adds a new satellite group, with its initial members if any
+func AddSatelliteGroup(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var g models.SatelliteGroup
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		msg := validateGroup(g)
+		var err error
+		if msg == "" {
+			msg, err = checkGroupParent(r.Context(), st, 0, g.ParentID)
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		unknown, err := st.Groups.Create(r.Context(), &g)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert group: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if len(unknown) > 0 {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("Satellites not found: %v", unknown),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group added successfully",
+			Data:    g,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateSatelliteGroup updates the name, kind, parent and description of a group. Its
+// members are changed through the member routes.
+func UpdateSatelliteGroup(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Group not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var g models.SatelliteGroup
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		msg := validateGroup(g)
+		if msg == "" {
+			msg, err = checkGroupParent(r.Context(), st, id, g.ParentID)
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		g.ID = id
+		err = st.Groups.Update(r.Context(), g)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Group not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update group: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group updated successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteSatelliteGroup deletes a group and its memberships. Groups that still have
+// subgroups or are selected by a plan are not deleted.
+func DeleteSatelliteGroup(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Groups.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Group not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete group: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddGroupMembers adds satellites, by NORAD ID, to a group
+func AddGroupMembers(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var req models.GroupMembersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if len(req.SatNoardIDs) == 0 {
+			response := models.Response{
+				Success: false,
+				Message: "sat_noard_ids is required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		g, err := st.Groups.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Group not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		unknown, err := st.Groups.AddMembers(r.Context(), g.ID, req.SatNoardIDs)
+		if err == nil && len(unknown) == 0 {
+			g, err = st.Groups.Get(r.Context(), g.ID)
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert group members: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if len(unknown) > 0 {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("Satellites not found: %v", unknown),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group members added successfully",
+			Data:    g,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteGroupMember removes a satellite, by NORAD ID, from a group
+func DeleteGroupMember(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+		noradID := vars["norad_id"]
+
+		err := st.Groups.RemoveMember(r.Context(), id, noradID)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Group member not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete group member: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group member deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetGroupTree returns the satellites and their sensors nested by group. A satellite
+// appears under every group it belongs to; satellites in no group hang off the root.
+func GetGroupTree(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		satelliteNodes, err := loadSatelliteNodes(r.Context(), st)
+		if err == nil {
+			var root []models.TreeNode
+			root, err = buildGroupTree(r.Context(), st, satelliteNodes)
+			satelliteNodes = root
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query groups: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		rootNode := models.TreeNode{
+			ID:       0,
+			Type:     "root",
+			Name:     "Satellites",
+			Children: satelliteNodes,
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Group tree retrieved successfully",
+			Data:    rootNode,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// buildGroupTree nests satellite nodes under group nodes and returns the children of
+// the root: the top-level groups followed by the ungrouped satellites
+func buildGroupTree(ctx context.Context, st *store.Store, satelliteNodes []models.TreeNode) ([]models.TreeNode, error) {
+	groups, err := st.Groups.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members := map[int]map[string]bool{}
+	grouped := map[string]bool{}
+	for _, g := range groups {
+		members[g.ID] = map[string]bool{}
+		for _, noradID := range g.SatNoardIDs {
+			members[g.ID][noradID] = true
+			grouped[noradID] = true
+		}
+	}
+
+	known := map[int]bool{}
+	for _, g := range groups {
+		known[g.ID] = true
+	}
+	subgroups := map[int][]models.SatelliteGroup{}
+	for _, g := range groups {
+		parent := g.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+		subgroups[parent] = append(subgroups[parent], g)
+	}
+
+	var build func(g models.SatelliteGroup) models.TreeNode
+	build = func(g models.SatelliteGroup) models.TreeNode {
+		node := models.TreeNode{ID: g.ID, Type: "group", Name: g.Name, Kind: g.Kind, Children: []models.TreeNode{}}
+		for _, sub := range subgroups[g.ID] {
+			node.Children = append(node.Children, build(sub))
+		}
+		for _, sat := range satelliteNodes {
+			if members[g.ID][sat.SatNoradID] {
+				node.Children = append(node.Children, sat)
+			}
+		}
+		return node
+	}
+
+	children := []models.TreeNode{}
+	for _, g := range subgroups[0] {
+		children = append(children, build(g))
+	}
+	ungrouped := []models.TreeNode{}
+	for _, sat := range satelliteNodes {
+		if !grouped[sat.SatNoradID] {
+			ungrouped = append(ungrouped, sat)
+		}
+	}
+	return append(children, ungrouped...), nil
+}
@@ -1,23 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"net/http"
 
 	"satplan/models"
+	"satplan/store"
 )
 
 // HealthCheck returns the health status of the API
-func HealthCheck(db *sql.DB) http.HandlerFunc {
+func HealthCheck(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		// Count records
-		var satCount, sensorCount, tleCount int
-		db.QueryRow("SELECT COUNT(*) FROM satellite").Scan(&satCount)
-		db.QueryRow("SELECT COUNT(*) FROM sensor").Scan(&sensorCount)
-		db.QueryRow("SELECT COUNT(*) FROM tle").Scan(&tleCount)
+		satCount, _ := st.Satellites.Count(r.Context())
+		sensorCount, _ := st.Sensors.Count(r.Context())
+		tleCount, _ := st.TLEs.Count(r.Context())
 
 		response := models.Response{
 			Success: true,
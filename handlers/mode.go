@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// modeTypes are the accepted sensor mode types
+var modeTypes = map[string]bool{
+	"pan":           true,
+	"multispectral": true,
+	"hyperspectral": true,
+	"thermal":       true,
+	"sar_stripmap":  true,
+	"sar_spotlight": true,
+	"sar_scansar":   true,
+}
+
+// spectralRegions are the wavelength ranges in nm, [from, to), of the band names the
+// capability search understands
+var spectralRegions = map[string][2]float64{
+	"blue":     {450, 520},
+	"green":    {520, 600},
+	"red":      {600, 700},
+	"red_edge": {700, 760},
+	"nir":      {760, 1000},
+	"swir":     {1000, 2500},
+	"mwir":     {3000, 5000},
+	"tir":      {8000, 15000},
+}
+
+// minPanBandwidth is the bandwidth in nm above which a visible band counts as panchromatic
+const minPanBandwidth = 200
+
+// GetSensorModes returns the modes of a sensor with their bands
+func GetSensorModes(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		if _, err := st.Sensors.Get(r.Context(), id); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		modes, err := st.Modes.ListBySensor(r.Context(), id)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query sensor modes: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Sensor modes retrieved successfully",
+			Data:    modes,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetSensorModeById returns a single sensor mode by ID
+func GetSensorModeById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		mode, err := st.Modes.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor mode not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Sensor mode retrieved successfully",
+			Data:    mode,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddSensorMode adds a mode to a sensor together with its bands
+func AddSensorMode(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var mode models.SensorMode
+		if err := json.NewDecoder(r.Body).Decode(&mode); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validateMode(&mode); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		err := st.Modes.Create(r.Context(), &mode)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert sensor mode: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Sensor mode added successfully",
+			Data:    mode,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateSensorMode updates a sensor mode and replaces its bands
+func UpdateSensorMode(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var mode models.SensorMode
+		if err := json.NewDecoder(r.Body).Decode(&mode); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		existing, err := st.Modes.Get(r.Context(), id)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor mode not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// a mode stays with its sensor
+		mode.ID, mode.SensorID = existing.ID, existing.SensorID
+		if err := validateMode(&mode); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		err = st.Modes.Update(r.Context(), &mode)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor mode not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update sensor mode: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Sensor mode updated successfully",
+			Data:    mode,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteSensorMode deletes a sensor mode and its bands. Plans that selected the mode
+// skip the sensor until another mode is chosen.
+func DeleteSensorMode(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Modes.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor mode not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete sensor mode: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Sensor mode deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// SearchSensors finds the sensor modes meeting capability requirements given as query
+// parameters: max_gsd (metres), band (repeatable or comma separated: a band name, a
+// spectral region such as nir or swir, pan, or a wavelength in nm), type and
+// sat_noard_id. Every required band must be present at max_gsd or finer. Sensors without
+// modes are matched on their own resolution when no band or type is required.
+func SearchSensors(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		maxGSD := 0.0
+		if s := query.Get("max_gsd"); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v <= 0 {
+				response := models.Response{
+					Success: false,
+					Message: "max_gsd must be a positive number of metres",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			maxGSD = v
+		}
+		bands := []string{}
+		for _, v := range query["band"] {
+			for _, b := range strings.Split(v, ",") {
+				if b = strings.TrimSpace(b); b != "" {
+					bands = append(bands, b)
+				}
+			}
+		}
+		modeType := query.Get("type")
+		if modeType != "" && !modeTypes[modeType] {
+			response := models.Response{
+				Success: false,
+				Message: "unknown mode type " + modeType,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var sensors []models.Sensor
+		var err error
+		if sat := query.Get("sat_noard_id"); sat != "" {
+			sensors, err = st.Sensors.ListBySatellite(r.Context(), sat)
+		} else {
+			sensors, err = st.Sensors.List(r.Context())
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query sensors: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		modes, err := st.Modes.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query sensor modes: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		bySensor := map[int][]models.SensorMode{}
+		for _, m := range modes {
+			bySensor[m.SensorID] = append(bySensor[m.SensorID], m)
+		}
+
+		matches := []models.SensorMatch{}
+		for _, s := range sensors {
+			if len(bySensor[s.ID]) == 0 {
+				if len(bands) == 0 && modeType == "" && (maxGSD == 0 || s.Resolution <= maxGSD) {
+					matches = append(matches, models.SensorMatch{Sensor: s, GSD: s.Resolution})
+				}
+				continue
+			}
+			for _, m := range bySensor[s.ID] {
+				if modeType != "" && m.Type != modeType {
+					continue
+				}
+				if match, ok := matchMode(m, bands, maxGSD); ok {
+					mode := m
+					match.Sensor, match.Mode = s, &mode
+					matches = append(matches, match)
+				}
+			}
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d matching sensor mode(s)", len(matches)),
+			Data:    matches,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// matchMode checks a mode against the required bands and GSD, returning the bands that
+// met each requirement
+func matchMode(m models.SensorMode, required []string, maxGSD float64) (models.SensorMatch, bool) {
+	match := models.SensorMatch{}
+	if len(required) == 0 {
+		match.GSD = m.Resolution
+		return match, maxGSD == 0 || m.Resolution <= maxGSD
+	}
+
+	for _, req := range required {
+		found := false
+		for _, b := range m.Bands {
+			gsd := bandGSD(b, m)
+			if !bandMatches(b, req) || (maxGSD > 0 && gsd > maxGSD) {
+				continue
+			}
+			match.Bands = append(match.Bands, b)
+			match.GSD = math.Max(match.GSD, gsd)
+			found = true
+			break
+		}
+		if !found {
+			return match, false
+		}
+	}
+	return match, true
+}
+
+// bandGSD returns the GSD of a band, falling back to its mode's resolution
+func bandGSD(b models.Band, m models.SensorMode) float64 {
+	if b.GSD > 0 {
+		return b.GSD
+	}
+	return m.Resolution
+}
+
+// bandMatches reports whether a band meets a requirement: its own name, a spectral region,
+// pan, or a wavelength in nm within the band
+func bandMatches(b models.Band, req string) bool {
+	if strings.EqualFold(b.Name, req) {
+		return true
+	}
+	if nm, err := strconv.ParseFloat(req, 64); err == nil {
+		half := math.Max(b.Bandwidth/2, 0.5)
+		return nm >= b.CenterWavelength-half && nm <= b.CenterWavelength+half
+	}
+	key := strings.ToLower(req)
+	if key == "pan" {
+		return b.Bandwidth >= minPanBandwidth && b.CenterWavelength >= 400 && b.CenterWavelength < 1000
+	}
+	region, ok := spectralRegions[key]
+	return ok && b.CenterWavelength >= region[0] && b.CenterWavelength < region[1]
+}
+
+// validateMode checks a sensor mode and its bands
+func validateMode(m *models.SensorMode) error {
+	if m.SensorID <= 0 || m.Name == "" {
+		return fmt.Errorf("sensor_id and name are required")
+	}
+	if !modeTypes[m.Type] {
+		return fmt.Errorf("type must be one of pan, multispectral, hyperspectral, thermal, sar_stripmap, sar_spotlight or sar_scansar")
+	}
+	if strings.HasPrefix(m.Type, "sar_") {
+		if m.Resolution <= 0 || m.NearIncidence <= 0 || m.FarIncidence >= 90 || m.NearIncidence >= m.FarIncidence {
+			return fmt.Errorf("a SAR mode needs a positive resolution and 0 < near_incidence < far_incidence < 90")
+		}
+	} else if m.Resolution <= 0 || m.ObserveAngle <= 0 {
+		return fmt.Errorf("resolution and observe_angle must be positive")
+	}
+	if m.Width < 0 || m.DataRate < 0 || m.LeftSideAngle < 0 || m.RightSideAngle < 0 {
+		return fmt.Errorf("width, data_rate and side angles must not be negative")
+	}
+	for i, b := range m.Bands {
+		if b.Name == "" {
+			return fmt.Errorf("band %d: name is required", i+1)
+		}
+		if b.CenterWavelength <= 0 || b.Bandwidth < 0 || b.GSD < 0 {
+			return fmt.Errorf("band %s: center_wavelength must be positive and bandwidth and gsd must not be negative", b.Name)
+		}
+	}
+	if m.Bands == nil {
+		m.Bands = []models.Band{}
+	}
+	return nil
+}
+
+// applyMode replaces a sensor's geometry with that of one of its modes; a SAR mode makes
+// the sensor a SAR sensor imaging its incidence range
+func applyMode(s *models.Sensor, m models.SensorMode) {
+	if strings.HasPrefix(m.Type, "sar_") {
+		s.SensorType = planner.SAR
+		s.NearIncidence, s.FarIncidence = m.NearIncidence, m.FarIncidence
+		if s.LookSide == "" {
+			s.LookSide = planner.LookRight
+		}
+	}
+	s.Name = s.Name + " " + m.Name
+	s.Resolution = m.Resolution
+	s.ObserveAngle = m.ObserveAngle
+	s.LeftSideAngle = m.LeftSideAngle
+	s.RightSideAngle = m.RightSideAngle
+	if m.Width > 0 {
+		s.Width = m.Width
+	}
+	if m.DataRate > 0 {
+		s.DataRate = m.DataRate
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"satplan/models"
+	"satplan/oem"
+	"satplan/orbit"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxOEMDuration bounds the time span of an ephemeris export
+	maxOEMDuration = 31 * 24 * time.Hour
+	// maxOEMStates bounds the number of ephemeris lines of an export
+	maxOEMStates = 100000
+)
+
+// oemFrames maps the frame query values to the OEM REF_FRAME names
+var oemFrames = map[string]string{"teme": "TEME", "gcrf": "GCRF", "itrf": "ITRF"}
+
+// GetSatelliteOEM exports a satellite's predicted ephemeris as a CCSDS OEM. The query
+// takes start and end (unix seconds, default the next 24 hours), step (seconds, default
+// 60), frame (teme, gcrf or itrf, default gcrf), format (kvn or xml, default kvn),
+// tle_id to predict from a TLE other than the latest, and covariance=true to add zero
+// covariance placeholders.
+func GetSatelliteOEM(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+		query := r.URL.Query()
+
+		start, end, err := parseTimeRange(r, maxOEMDuration)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		step := 60 * time.Second
+		if s := query.Get("step"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds < 1 || seconds > 3600 {
+				response := models.Response{
+					Success: false,
+					Message: "step must be an integer number of seconds between 1 and 3600",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			step = time.Duration(seconds) * time.Second
+		}
+		if end.Sub(start)/step >= maxOEMStates {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("The export would exceed %d states; use a longer step or a shorter span", maxOEMStates),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		frame := "gcrf"
+		if f := query.Get("frame"); f != "" {
+			frame = strings.ToLower(f)
+		}
+		format := "kvn"
+		if f := query.Get("format"); f != "" {
+			format = strings.ToLower(f)
+		}
+		if oemFrames[frame] == "" || (format != "kvn" && format != "xml") {
+			response := models.Response{
+				Success: false,
+				Message: "frame must be teme, gcrf or itrf and format must be kvn or xml",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sat, err := st.Satellites.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var tle models.TLE
+		if s := query.Get("tle_id"); s != "" {
+			tleID, _ := strconv.Atoi(s)
+			tle, err = st.TLEs.Get(r.Context(), tleID)
+			if err == nil && tle.SatID != sat.ID {
+				err = store.ErrNotFound
+			}
+		} else {
+			tle, err = st.TLEs.Latest(r.Context(), sat.ID)
+		}
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "No TLE available for satellite " + sat.Name,
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		prop, err := orbit.NewPropagatorFromTLE(tle.Line1, tle.Line2)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("Satellite %s: %v", sat.Name, err),
+			}
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		msg := oem.Message{
+			Originator:    "SATPLAN",
+			Created:       time.Now(),
+			ObjectName:    sat.Name,
+			ObjectID:      internationalDesignator(tle.Line1),
+			Frame:         oemFrames[frame],
+			Start:         start,
+			Stop:          end,
+			Interpolation: "LAGRANGE",
+			Degree:        7,
+			Comments: []string{
+				fmt.Sprintf("Predicted with SGP4 from the TLE of epoch %s UTC", prop.Elements.Epoch.Format("2006-01-02T15:04:05")),
+			},
+		}
+		switch frame {
+		case "gcrf":
+			msg.Comments = append(msg.Comments, "TEME to GCRF by IAU 1976 precession and IAU 1980 nutation")
+		case "itrf":
+			msg.Comments = append(msg.Comments, "TEME to ITRF by GMST rotation; polar motion neglected and UT1 taken as UTC")
+		}
+		for t := start; !t.After(end); t = t.Add(step) {
+			pos, vel, err := prop.Propagate(t)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Failed to propagate satellite %s: %v", sat.Name, err),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			switch frame {
+			case "gcrf":
+				pos, vel = orbit.TEMEToGCRF(pos, vel, t)
+			case "itrf":
+				pos, vel = orbit.TEMEToECEF(pos, vel, t)
+			}
+			msg.States = append(msg.States, oem.State{Epoch: t, Position: pos, Velocity: vel})
+		}
+		if query.Get("covariance") == "true" {
+			msg.Covariances = []oem.Covariance{{
+				Epoch:   start,
+				Frame:   msg.Frame,
+				Comment: "Placeholder: no covariance is estimated from a TLE",
+			}}
+		}
+
+		filename := fmt.Sprintf("%s-%s.oem", sat.NoardID, start.Format("20060102T150405"))
+		if format == "xml" {
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.xml\"", filename))
+			_, err = msg.WriteXML(w)
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+			_, err = msg.WriteKVN(w)
+		}
+		if err != nil {
+			log.Printf("Failed to write OEM for satellite %s: %v", sat.NoardID, err)
+		}
+	}
+}
+
+// internationalDesignator returns the COSPAR ID from columns 10-17 of TLE line 1 in the
+// form 2008-041A, or "" when the TLE leaves it blank
+func internationalDesignator(line1 string) string {
+	if len(line1) < 17 {
+		return ""
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(line1[9:11]))
+	if err != nil {
+		return ""
+	}
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	return fmt.Sprintf("%d-%s%s", year, strings.TrimSpace(line1[11:14]), strings.TrimSpace(line1[14:17]))
+}
@@ -0,0 +1,696 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// maxPlanDuration limits the time window of a plan to keep server-side propagation bounded
+const maxPlanDuration = 31 * 24 * time.Hour
+
+// planSatellite groups the sensors of one satellite in a plan with its propagator
+type planSatellite struct {
+	Satellite  models.Satellite
+	Propagator *orbit.Propagator
+	Sensors    []planner.Sensor
+}
+
+// GetPlans returns all saved plans. With ?min_lon=&max_lon=&min_lat=&max_lat= only the
+// plans whose area overlaps that box are returned.
+func GetPlans(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		area, msg := parseAreaFilter(r.URL.Query())
+		if msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plans, err := st.Plans.List(r.Context(), store.PlanFilter{Area: area})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query plans: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Plans retrieved successfully",
+			Data:    plans,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetPlanById returns a single plan by ID
+func GetPlanById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Plan retrieved successfully",
+			Data:    plan,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddPlan saves a new plan for the authenticated user
+func AddPlan(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var plan models.Plan
+		if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validatePlan(&plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if err := validatePlanSensors(r.Context(), st, &plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan.UserID, _ = strconv.Atoi(r.Header.Get("X-User-ID"))
+		plan.CreatedAt = time.Now().Unix()
+
+		if err := st.Plans.Create(r.Context(), &plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert plan: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Plan added successfully",
+			Data:    plan,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdatePlan updates an existing plan and replaces its sensor selection
+func UpdatePlan(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+
+		var plan models.Plan
+		if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validatePlan(&plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if err := validatePlanSensors(r.Context(), st, &plan); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan.ID, _ = strconv.Atoi(vars["id"])
+		err := st.Plans.Update(r.Context(), plan)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update plan: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Plan updated successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeletePlan deletes a plan with its sensor and group selection and saved footprints
+func DeletePlan(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Plans.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete plan: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Plan deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetPlanStrips computes the strips of a saved plan with the server-side propagator
+func GetPlanStrips(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		strips, err := computePlanStrips(r.Context(), st, &plan, filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Computed %d strip(s)", len(strips)),
+			Data:    strips,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// validatePlan checks the target area, time window and sensor selection of a plan
+func validatePlan(plan *models.Plan) error {
+	if plan.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !planArea(plan).Valid() {
+		return fmt.Errorf("invalid target area: min_lon/max_lon/min_lat/max_lat must describe a non-empty box")
+	}
+	if plan.EndTime <= plan.StartTime {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	if time.Duration(plan.EndTime-plan.StartTime)*time.Second > maxPlanDuration {
+		return fmt.Errorf("plan time window must not exceed %d days", int(maxPlanDuration.Hours()/24))
+	}
+	if len(plan.Sensors) == 0 && len(plan.Groups) == 0 {
+		return fmt.Errorf("at least one sensor or group is required")
+	}
+	return nil
+}
+
+// validatePlanSensors checks that the sensors and modes of a plan exist and that each
+// optical sensor's side angle is within the field of regard of the sensor, or of its
+// mode. SAR sensors use only the sign of the side angle.
+func validatePlanSensors(ctx context.Context, st *store.Store, plan *models.Plan) error {
+	for _, ps := range plan.Sensors {
+		s, err := st.Sensors.Get(ctx, ps.SensorID)
+		if err == store.ErrNotFound {
+			return fmt.Errorf("sensor %d does not exist", ps.SensorID)
+		} else if err != nil {
+			return err
+		}
+		if ps.ModeID > 0 {
+			mode, err := st.Modes.Get(ctx, ps.ModeID)
+			if err == store.ErrNotFound || (err == nil && mode.SensorID != s.ID) {
+				return fmt.Errorf("mode %d is not a mode of sensor %d", ps.ModeID, s.ID)
+			} else if err != nil {
+				return err
+			}
+			applyMode(&s, mode)
+		}
+		if s.SensorType != planner.SAR && (ps.SideAngle < -s.LeftSideAngle || ps.SideAngle > s.RightSideAngle) {
+			return fmt.Errorf("side_angle %g of sensor %d is outside its field of regard (left_side_angle %g, right_side_angle %g)",
+				ps.SideAngle, s.ID, s.LeftSideAngle, s.RightSideAngle)
+		}
+	}
+	return nil
+}
+
+// parseAreaFilter reads an optional min_lon/max_lon/min_lat/max_lat box from a query. It
+// returns nil when none is given, or a message for the client if the box is incomplete
+// or invalid.
+func parseAreaFilter(query url.Values) (*planner.TargetArea, string) {
+	var area planner.TargetArea
+	given := 0
+	for _, p := range []struct {
+		name  string
+		value *float64
+	}{
+		{"min_lon", &area.West},
+		{"max_lon", &area.East},
+		{"min_lat", &area.South},
+		{"max_lat", &area.North},
+	} {
+		s := query.Get(p.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, p.name + " must be a number"
+		}
+		*p.value = v
+		given++
+	}
+	if given == 0 {
+		return nil, ""
+	}
+	if given < 4 || !area.Valid() {
+		return nil, "min_lon/max_lon/min_lat/max_lat must describe a non-empty box"
+	}
+	return &area, ""
+}
+
+// planArea returns the target area of a plan
+func planArea(plan *models.Plan) planner.TargetArea {
+	return planner.TargetArea{West: plan.MinLon, East: plan.MaxLon, North: plan.MaxLat, South: plan.MinLat}
+}
+
+// loadPlanSatellites resolves the plan's sensors, in their selected modes, and the sensors
+// of every satellite in its groups, and groups them by satellite with a propagator for the
+// plan's time window. A sensor selected both directly and through a group is planned once,
+// with its direct selection, and not at all if its selected mode has been deleted.
+// Satellites that are not active are left out unless the plan includes them, and so are
+// optical sensors whose side angle is outside their field of regard.
+func loadPlanSatellites(ctx context.Context, st *store.Store, plan *models.Plan) ([]planSatellite, error) {
+	groups := []planSatellite{}
+	index := map[string]int{}
+	selected := map[int]bool{}
+
+	add := func(s models.Sensor, sideAngle float64) error {
+		selected[s.ID] = true
+		if !withinLimits(planner.Sensor{Sensor: s, SideAngle: sideAngle}) {
+			// group side angles are not checked against members added after the plan was saved
+			log.Printf("Side angle %g of sensor %d in plan %d is outside its field of regard, skipping", sideAngle, s.ID, plan.ID)
+			return nil
+		}
+		i, ok := index[s.SatNoardID]
+		if !ok && !plan.IncludeInactive {
+			active, err := satelliteActive(ctx, st, s.SatNoardID)
+			if err != nil {
+				return err
+			}
+			if !active {
+				log.Printf("Satellite %s of plan %d is not active, skipping", s.SatNoardID, plan.ID)
+				index[s.SatNoardID] = -1
+				return nil
+			}
+		}
+		if i < 0 {
+			return nil
+		}
+		if !ok {
+			group, err := loadSatellite(ctx, st, s.SatNoardID, time.Unix(plan.StartTime, 0).UTC(), time.Unix(plan.EndTime, 0).UTC())
+			if err != nil {
+				return fmt.Errorf("satellite %s of sensor %d: %v", s.SatNoardID, s.ID, err)
+			}
+			groups = append(groups, *group)
+			i = len(groups) - 1
+			index[s.SatNoardID] = i
+		}
+
+		groups[i].Sensors = append(groups[i].Sensors, planner.Sensor{Sensor: s, SideAngle: sideAngle})
+		return nil
+	}
+
+	for _, ps := range plan.Sensors {
+		s, err := st.Sensors.Get(ctx, ps.SensorID)
+		if err == store.ErrNotFound {
+			log.Printf("Sensor %d of plan %d no longer exists, skipping", ps.SensorID, plan.ID)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if ps.ModeID > 0 {
+			mode, err := st.Modes.Get(ctx, ps.ModeID)
+			if err == store.ErrNotFound || (err == nil && mode.SensorID != s.ID) {
+				// still selected, so that its groups do not plan it in its default mode
+				log.Printf("Mode %d of sensor %d in plan %d no longer exists, skipping", ps.ModeID, s.ID, plan.ID)
+				selected[s.ID] = true
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			applyMode(&s, mode)
+		}
+		if err := add(s, ps.SideAngle); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pg := range plan.Groups {
+		sensors, err := st.Groups.Sensors(ctx, pg.GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %v", pg.GroupID, err)
+		}
+		if len(sensors) == 0 {
+			log.Printf("Group %d of plan %d has no sensors, skipping", pg.GroupID, plan.ID)
+		}
+		for _, s := range sensors {
+			if selected[s.ID] {
+				continue
+			}
+			if err := add(s, pg.SideAngle); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// satelliteActive reports whether a satellite's mission status is active. Unknown
+// satellites count as active so that the caller reports them.
+func satelliteActive(ctx context.Context, st *store.Store, noradID string) (bool, error) {
+	sat, err := st.Satellites.GetByNoradID(ctx, noradID)
+	if err == store.ErrNotFound {
+		return true, nil
+	}
+	return sat.Status == models.SatelliteActive, err
+}
+
+// loadSatellite loads a satellite with a propagator initialized from its latest TLE. A
+// stored ephemeris covering start to end is attached to the propagator and preferred
+// over the TLE; with such an ephemeris the satellite needs no TLE at all.
+func loadSatellite(ctx context.Context, st *store.Store, noradID string, start, end time.Time) (*planSatellite, error) {
+	sat, err := st.Satellites.GetByNoradID(ctx, noradID)
+	if err != nil {
+		return nil, err
+	}
+
+	eph, err := st.Ephemerides.Covering(ctx, sat.NoardID, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("satellite %s: %v", sat.Name, err)
+	}
+
+	tle, err := st.TLEs.Latest(ctx, sat.ID)
+	if err == store.ErrNotFound {
+		if eph != nil {
+			prop, err := orbit.NewEphemerisPropagator(sat.NoardID, eph)
+			if err != nil {
+				return nil, fmt.Errorf("satellite %s: %v", sat.Name, err)
+			}
+			return &planSatellite{Satellite: sat, Propagator: prop}, nil
+		}
+		return nil, fmt.Errorf("no TLE available for satellite %s", sat.Name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	prop, err := orbit.NewPropagatorFromTLE(tle.Line1, tle.Line2)
+	if err != nil {
+		return nil, fmt.Errorf("satellite %s: %v", sat.Name, err)
+	}
+	prop.Ephemeris = eph
+	return &planSatellite{Satellite: sat, Propagator: prop}, nil
+}
+
+// parseStripFilter reads the strip constraints from the query parameters
+// min_sun_elevation, max_sun_elevation, exclude_glint, glint_angle, max_gsd,
+// min_incidence, max_incidence, orbit_direction, look_side, exclude_eclipse and
+// exclude_violations
+func parseStripFilter(r *http.Request) (planner.StripFilter, error) {
+	query := r.URL.Query()
+	var filter planner.StripFilter
+
+	for _, p := range []struct {
+		name  string
+		value **float64
+	}{
+		{"min_sun_elevation", &filter.MinSunElevation},
+		{"max_sun_elevation", &filter.MaxSunElevation},
+	} {
+		s := query.Get(p.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < -90 || v > 90 {
+			return filter, fmt.Errorf("%s must be a number between -90 and 90", p.name)
+		}
+		*p.value = &v
+	}
+
+	if s := query.Get("exclude_glint"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("exclude_glint must be true or false")
+		}
+		filter.ExcludeGlint = v
+	}
+	if s := query.Get("glint_angle"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 || v > 90 {
+			return filter, fmt.Errorf("glint_angle must be a number between 0 and 90")
+		}
+		filter.GlintAngle = v
+	}
+	if s := query.Get("max_gsd"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 {
+			return filter, fmt.Errorf("max_gsd must be a positive number of metres")
+		}
+		filter.MaxGSD = &v
+	}
+	for _, p := range []struct {
+		name  string
+		value **float64
+	}{
+		{"min_incidence", &filter.MinIncidence},
+		{"max_incidence", &filter.MaxIncidence},
+	} {
+		s := query.Get(p.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 90 {
+			return filter, fmt.Errorf("%s must be a number between 0 and 90", p.name)
+		}
+		*p.value = &v
+	}
+	switch filter.OrbitDirection = query.Get("orbit_direction"); filter.OrbitDirection {
+	case "", planner.Ascending, planner.Descending:
+	default:
+		return filter, fmt.Errorf("orbit_direction must be ascending or descending")
+	}
+	switch filter.LookSide = query.Get("look_side"); filter.LookSide {
+	case "", planner.LookLeft, planner.LookRight:
+	default:
+		return filter, fmt.Errorf("look_side must be left or right")
+	}
+	if s := query.Get("exclude_eclipse"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("exclude_eclipse must be true or false")
+		}
+		filter.ExcludeEclipse = v
+	}
+	if s := query.Get("exclude_violations"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("exclude_violations must be true or false")
+		}
+		filter.ExcludeViolations = v
+	}
+	return filter, nil
+}
+
+// computePlanStrips runs SensorInRegion for every satellite of the plan and keeps the
+// strips that satisfy the filter
+func computePlanStrips(ctx context.Context, st *store.Store, plan *models.Plan, filter planner.StripFilter) ([]models.Strip, error) {
+	sats, err := loadPlanSatellites(ctx, st, plan)
+	if err != nil {
+		return nil, err
+	}
+	strips, err := satelliteStrips(sats, plan, filter)
+	if err != nil {
+		return nil, err
+	}
+	planner.FlagDutyCycles(strips, dutyCycles(sats))
+	return filter.Apply(strips), nil
+}
+
+// dutyCycles returns the duty-cycle limits of the sensors of the satellites that have any
+func dutyCycles(sats []planSatellite) map[int]planner.DutyCycle {
+	cycles := map[int]planner.DutyCycle{}
+	for _, sat := range sats {
+		for _, s := range sat.Sensors {
+			if _, ok := cycles[s.ID]; ok {
+				continue
+			}
+			if d := planner.DutyCycleOf(s.Sensor, sat.Propagator); d.Limited() {
+				cycles[s.ID] = d
+			}
+		}
+	}
+	return cycles
+}
+
+// satelliteStrips computes the strips of already loaded plan satellites
+func satelliteStrips(sats []planSatellite, plan *models.Plan, filter planner.StripFilter) ([]models.Strip, error) {
+	start := time.Unix(plan.StartTime, 0).UTC()
+	end := time.Unix(plan.EndTime, 0).UTC()
+	area := planArea(plan)
+
+	strips := []models.Strip{}
+	for _, sat := range sats {
+		satStrips, err := planner.SensorInRegion(sat.Propagator, sat.Satellite.Name, sat.Sensors, start, end, area)
+		if err != nil {
+			return nil, fmt.Errorf("satellite %s: %v", sat.Satellite.Name, err)
+		}
+		strips = append(strips, satStrips...)
+	}
+
+	strips = filter.Apply(strips)
+	sort.SliceStable(strips, func(i, j int) bool {
+		return strips[i].StartTimestamp < strips[j].StartTimestamp
+	})
+	return strips, nil
+}
@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// requestTransitions maps each lifecycle action to the states it may be taken from
+var requestTransitions = map[string]store.RequestTransition{
+	"submit":   {From: []string{models.RequestDraft}, To: models.RequestSubmitted},
+	"approve":  {From: []string{models.RequestSubmitted}, To: models.RequestApproved},
+	"schedule": {From: []string{models.RequestApproved}, To: models.RequestScheduled},
+	"acquire":  {From: []string{models.RequestScheduled}, To: models.RequestAcquired},
+	"fail":     {From: []string{models.RequestApproved, models.RequestScheduled}, To: models.RequestFailed},
+}
+
+// GetTaskingRequests returns all tasking requests, optionally filtered by ?status= and by
+// a ?min_lon=&max_lon=&min_lat=&max_lat= box their area overlaps
+func GetTaskingRequests(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		area, msg := parseAreaFilter(query)
+		if msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		requests, err := st.Requests.List(r.Context(), store.TaskingRequestFilter{Status: query.Get("status"), Area: area})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query tasking requests: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking requests retrieved successfully",
+			Data:    requests,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetTaskingRequestById returns a single tasking request by ID
+func GetTaskingRequestById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		t, err := st.Requests.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Tasking request not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request retrieved successfully",
+			Data:    t,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddTaskingRequest creates a draft tasking request for the authenticated user
+func AddTaskingRequest(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var t models.TaskingRequest
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if t.Priority == 0 {
+			t.Priority = 5
+		}
+		if err := validateTaskingRequest(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		t.UserID, _ = strconv.Atoi(r.Header.Get("X-User-ID"))
+		t.Status = models.RequestDraft
+		t.CreatedAt = time.Now().Unix()
+		t.UpdatedAt = t.CreatedAt
+		if t.SensorIDs == nil {
+			t.SensorIDs = []int{}
+		}
+
+		if err := st.Requests.Create(r.Context(), &t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert tasking request: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request added successfully",
+			Data:    t,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateTaskingRequest edits a draft tasking request and records the changed fields
+func UpdateTaskingRequest(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var t models.TaskingRequest
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if t.Priority == 0 {
+			t.Priority = 5
+		}
+		if err := validateTaskingRequest(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if t.SensorIDs == nil {
+			t.SensorIDs = []int{}
+		}
+
+		old, err := st.Requests.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Tasking request not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if old.Status != models.RequestDraft {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("Only draft requests can be edited; this request is %s", old.Status),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		t.ID, t.UserID, t.Status, t.CreatedAt = old.ID, old.UserID, old.Status, old.CreatedAt
+		t.UpdatedAt = time.Now().Unix()
+		changes := diffTaskingRequests(&old, &t)
+
+		userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+		err = st.Requests.Update(r.Context(), t, userID, changes)
+		if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update tasking request: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request updated successfully",
+			Data:    t,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteTaskingRequest deletes a draft tasking request, keeping its audit trail
+func DeleteTaskingRequest(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+		err := st.Requests.Delete(r.Context(), id, userID)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Tasking request not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete tasking request: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// TransitionTaskingRequest moves a tasking request through its lifecycle with the given
+// action (submit, approve, schedule, acquire or fail) and records who did it
+func TransitionTaskingRequest(st *store.Store, action string) http.HandlerFunc {
+	transition := requestTransitions[action]
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var req models.TransitionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Invalid request body: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+		rejected, err := st.Requests.Transition(r.Context(), action, transition, userID, map[int]string{id: req.Note})
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if from, ok := rejected[id]; ok && from == "" {
+			response := models.Response{
+				Success: false,
+				Message: "Tasking request not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if ok {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("Cannot %s a request that is %s", action, from),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request " + transition.To,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetTaskingRequestHistory returns the audit trail of a tasking request, oldest first
+func GetTaskingRequestHistory(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		events, err := st.Requests.Events(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Tasking request not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query tasking request history: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Tasking request history retrieved successfully",
+			Data:    events,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// validateTaskingRequest checks the area, time window and constraints of a tasking request
+func validateTaskingRequest(t *models.TaskingRequest) error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !(t.MinLon < t.MaxLon && t.MinLat < t.MaxLat && t.MinLon >= -180 && t.MaxLon <= 180 && t.MinLat >= -90 && t.MaxLat <= 90) {
+		return fmt.Errorf("invalid target area: min_lon/max_lon/min_lat/max_lat must describe a non-empty box")
+	}
+	if t.EndTime <= t.StartTime {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	if t.Priority < 1 || t.Priority > 10 {
+		return fmt.Errorf("priority must be between 1 (highest) and 10 (lowest)")
+	}
+	if t.MaxResolution < 0 {
+		return fmt.Errorf("max_resolution must not be negative")
+	}
+	if t.MaxCloudCover != nil && (*t.MaxCloudCover < 0 || *t.MaxCloudCover > 100) {
+		return fmt.Errorf("max_cloud_cover must be a percentage")
+	}
+	for _, e := range []*float64{t.MinSunElevation, t.MaxSunElevation} {
+		if e != nil && (*e < -90 || *e > 90) {
+			return fmt.Errorf("sun elevation limits must be between -90 and 90")
+		}
+	}
+	if t.MinSunElevation != nil && t.MaxSunElevation != nil && *t.MinSunElevation > *t.MaxSunElevation {
+		return fmt.Errorf("min_sun_elevation must not exceed max_sun_elevation")
+	}
+	return nil
+}
+
+// diffTaskingRequests returns the editable fields that differ between two versions of a
+// request as field -> [old, new], keyed by their JSON names
+func diffTaskingRequests(old, updated *models.TaskingRequest) map[string][2]interface{} {
+	changes := map[string][2]interface{}{}
+	oldV, newV := reflect.ValueOf(*old), reflect.ValueOf(*updated)
+	typ := oldV.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Tag.Get("json")
+		switch name {
+		case "id", "user_id", "status", "created_at", "updated_at":
+			continue
+		}
+		a, b := oldV.Field(i).Interface(), newV.Field(i).Interface()
+		if !reflect.DeepEqual(a, b) {
+			changes[name] = [2]interface{}{a, b}
+		}
+	}
+	return changes
+}
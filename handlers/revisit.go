@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/geojson"
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// GetPlanRevisit computes revisit and coverage statistics of a saved plan over its target
+// area. Query parameters: "grid_size" (cells per axis), "merge" (seconds within which
+// accesses to a cell count as one visit) and "format=geojson" to get the grid as GeoJSON.
+func GetPlanRevisit(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		query := r.URL.Query()
+		opts := planner.RevisitOptions{GridSize: planner.DefaultGridSize, MergeGap: planner.DefaultMergeGap}
+		if s := query.Get("grid_size"); s != "" {
+			size, err := strconv.Atoi(s)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "grid_size must be an integer",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			opts.GridSize = size
+		}
+		if s := query.Get("merge"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "merge must be an integer number of seconds",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			opts.MergeGap = time.Duration(seconds) * time.Second
+		}
+		format := query.Get("format")
+		if format != "" && format != "json" && format != "geojson" {
+			response := models.Response{
+				Success: false,
+				Message: "format must be \"json\" or \"geojson\"",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		strips, err := computePlanStrips(r.Context(), st, &plan, filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		start := time.Unix(plan.StartTime, 0).UTC()
+		end := time.Unix(plan.EndTime, 0).UTC()
+		analysis, err := planner.Revisit(strips, planArea(&plan), start, end, opts)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if format == "geojson" {
+			w.Header().Set("Content-Type", geojson.ContentType)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"plan-%d-revisit.geojson\"", plan.ID))
+			json.NewEncoder(w).Encode(revisitGrid(analysis))
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Revisit statistics computed from %d strip(s)", len(strips)),
+			Data:    analysis,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// revisitGrid converts the analysis cells to a GeoJSON feature collection of cell polygons
+func revisitGrid(analysis models.RevisitAnalysis) geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	halfW, halfH := analysis.CellWidth/2, analysis.CellHeight/2
+	for _, c := range analysis.Cells {
+		ring := [][]float64{
+			{c.Lon - halfW, c.Lat - halfH},
+			{c.Lon + halfW, c.Lat - halfH},
+			{c.Lon + halfW, c.Lat + halfH},
+			{c.Lon - halfW, c.Lat + halfH},
+			{c.Lon - halfW, c.Lat - halfH},
+		}
+		fc.Features = append(fc.Features, geojson.NewFeature(geojson.Polygon(ring), map[string]interface{}{
+			"row":          c.Row,
+			"col":          c.Col,
+			"accesses":     c.Accesses,
+			"first_access": c.FirstAccess,
+			"min_gap":      c.MinGap,
+			"mean_gap":     c.MeanGap,
+			"max_gap":      c.MaxGap,
+		}))
+	}
+	return fc
+}
@@ -1,22 +1,104 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"satplan/models"
+	"satplan/satcat"
+	"satplan/store"
 
 	"github.com/gorilla/mux"
 )
 
-// GetSatellites returns all satellites
-func GetSatellites(db *sql.DB) http.HandlerFunc {
+// validateSatellite checks that the agility and storage attributes of a satellite are not
+// negative and that its catalogue metadata is well formed. An empty status defaults to
+// active and the orbit class is upper-cased.
+func validateSatellite(sat *models.Satellite) error {
+	if sat.MaxRollRate < 0 || sat.MaxPitchRate < 0 || sat.RollAcceleration < 0 ||
+		sat.PitchAcceleration < 0 || sat.SettleTime < 0 {
+		return fmt.Errorf("agility attributes must not be negative")
+	}
+	if sat.RecorderCapacity < 0 || sat.DownlinkRate < 0 {
+		return fmt.Errorf("recorder_capacity and downlink_rate must not be negative")
+	}
+	return validateSatelliteMetadata(sat)
+}
+
+// validateSatelliteMetadata checks the status, orbit class and launch date of a satellite
+func validateSatelliteMetadata(sat *models.Satellite) error {
+	if sat.Status == "" {
+		sat.Status = models.SatelliteActive
+	}
+	switch sat.Status {
+	case models.SatelliteActive, models.SatelliteDegraded, models.SatelliteRetired:
+	default:
+		return fmt.Errorf("status must be %s, %s or %s", models.SatelliteActive, models.SatelliteDegraded, models.SatelliteRetired)
+	}
+	sat.OrbitClass = strings.ToUpper(sat.OrbitClass)
+	switch sat.OrbitClass {
+	case "", models.OrbitLEO, models.OrbitMEO, models.OrbitGEO, models.OrbitHEO:
+	default:
+		return fmt.Errorf("orbit_class must be %s, %s, %s or %s", models.OrbitLEO, models.OrbitMEO, models.OrbitGEO, models.OrbitHEO)
+	}
+	if sat.LaunchDate != "" {
+		if _, err := time.Parse("2006-01-02", sat.LaunchDate); err != nil {
+			return fmt.Errorf("launch_date must be a date in the form YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+// GetSatellites returns all satellites. Query parameters "status", "operator", "country"
+// and "orbit_class" (each comma separated for several values), "launched_after" and
+// "launched_before" (YYYY-MM-DD, inclusive) and "q" (a substring of the name, NORAD ID or
+// international designator) narrow the list.
+func GetSatellites(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		rows, err := db.Query("SELECT id, noard_id, name, hex_color FROM satellite ORDER BY name")
+		query := r.URL.Query()
+		list := func(param string) []string {
+			if v := query.Get(param); v != "" {
+				return strings.Split(v, ",")
+			}
+			return nil
+		}
+		filter := store.SatelliteFilter{
+			Status:         list("status"),
+			Operator:       list("operator"),
+			Country:        list("country"),
+			OrbitClass:     list("orbit_class"),
+			LaunchedAfter:  query.Get("launched_after"),
+			LaunchedBefore: query.Get("launched_before"),
+			Query:          query.Get("q"),
+		}
+		for _, bound := range []struct{ param, value string }{
+			{"launched_after", filter.LaunchedAfter},
+			{"launched_before", filter.LaunchedBefore},
+		} {
+			if bound.value == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", bound.value); err != nil {
+				response := models.Response{
+					Success: false,
+					Message: bound.param + " must be a date in the form YYYY-MM-DD",
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		satellites, err := st.Satellites.List(r.Context(), filter)
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -26,16 +108,18 @@ func GetSatellites(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
 
-		satellites := []models.Satellite{}
-		for rows.Next() {
-			var s models.Satellite
-			if err := rows.Scan(&s.ID, &s.NoardID, &s.Name, &s.HexColor); err != nil {
-				log.Printf("Error scanning satellite: %v", err)
-				continue
+		// flag decaying orbits from one query over the trend span; satellites without a
+		// TLE have no indicators
+		histories, err := loadRecentTLEHistories(r.Context(), st)
+		if err != nil {
+			log.Printf("Error loading TLE histories: %v", err)
+		}
+		for i := range satellites {
+			history := histories[satellites[i].NoardID]
+			if decay, err := satelliteDecay(history); err == nil {
+				satellites[i].Decay = decay
 			}
-			satellites = append(satellites, s)
 		}
 
 		response := models.Response{
@@ -49,18 +133,15 @@ func GetSatellites(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSatelliteByID returns a single satellite by ID
-func GetSatelliteByID(db *sql.DB) http.HandlerFunc {
+func GetSatelliteByID(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
-		var s models.Satellite
-		err := db.QueryRow("SELECT id, noard_id, name, hex_color FROM satellite WHERE id = ?", id).
-			Scan(&s.ID, &s.NoardID, &s.Name, &s.HexColor)
-
-		if err == sql.ErrNoRows {
+		s, err := st.Satellites.Get(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "Satellite not found",
@@ -89,12 +170,12 @@ func GetSatelliteByID(db *sql.DB) http.HandlerFunc {
 }
 
 // GetAllSatellites returns all satellites (alias for GetSatellites)
-func GetAllSatellites(db *sql.DB) http.HandlerFunc {
-	return GetSatellites(db)
+func GetAllSatellites(st *store.Store) http.HandlerFunc {
+	return GetSatellites(st)
 }
 
 // AddSatellite adds a new satellite
-func AddSatellite(db *sql.DB) http.HandlerFunc {
+func AddSatellite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -120,9 +201,25 @@ func AddSatellite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		result, err := db.Exec("INSERT INTO satellite (noard_id, name, hex_color) VALUES (?, ?, ?)",
-			sat.NoardID, sat.Name, sat.HexColor)
-		if err != nil {
+		if err := validateSatellite(&sat); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := st.Satellites.Create(r.Context(), &sat); errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to insert satellite: " + err.Error(),
@@ -132,9 +229,6 @@ func AddSatellite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		id, _ := result.LastInsertId()
-		sat.ID = int(id)
-
 		response := models.Response{
 			Success: true,
 			Message: "Satellite added successfully",
@@ -147,17 +241,17 @@ func AddSatellite(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSatelliteById returns a single satellite by ID (alias for GetSatelliteByID)
-func GetSatelliteById(db *sql.DB) http.HandlerFunc {
-	return GetSatelliteByID(db)
+func GetSatelliteById(st *store.Store) http.HandlerFunc {
+	return GetSatelliteByID(st)
 }
 
 // UpdateSatellite updates an existing satellite
-func UpdateSatellite(db *sql.DB) http.HandlerFunc {
+func UpdateSatellite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
 		var sat models.Satellite
 		if err := json.NewDecoder(r.Body).Decode(&sat); err != nil {
@@ -170,22 +264,45 @@ func UpdateSatellite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if satellite exists
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM satellite WHERE id = ?)", id).Scan(&exists)
-		if err != nil || !exists {
+		if err := validateSatellite(&sat); err != nil {
 			response := models.Response{
 				Success: false,
-				Message: "Satellite not found",
+				Message: err.Error(),
 			}
-			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
 
-		_, err = db.Exec("UPDATE satellite SET noard_id = ?, name = ?, hex_color = ? WHERE id = ?",
-			sat.NoardID, sat.Name, sat.HexColor, id)
-		if err != nil {
+		if sat.NoardID == "" {
+			response := models.Response{
+				Success: false,
+				Message: "noard_id is required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sat.ID = id
+		err := st.Satellites.Update(r.Context(), sat)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to update satellite: " + err.Error(),
@@ -204,18 +321,18 @@ func UpdateSatellite(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// DeleteSatellite deletes a satellite by ID
-func DeleteSatellite(db *sql.DB) http.HandlerFunc {
+// DeleteSatellite deletes a satellite by ID together with its TLEs, ephemerides, group
+// memberships, conjunctions and footprints. A satellite that still has sensors is not
+// deleted.
+func DeleteSatellite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
-		// Check if satellite exists
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM satellite WHERE id = ?)", id).Scan(&exists)
-		if err != nil || !exists {
+		err := st.Satellites.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "Satellite not found",
@@ -223,10 +340,15 @@ func DeleteSatellite(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(response)
 			return
-		}
-
-		_, err = db.Exec("DELETE FROM satellite WHERE id = ?", id)
-		if err != nil {
+		} else if errors.Is(err, store.ErrConflict) {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to delete satellite: " + err.Error(),
@@ -246,12 +368,11 @@ func DeleteSatellite(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSatelliteTree returns a hierarchical tree structure of satellites and sensors
-func GetSatelliteTree(db *sql.DB) http.HandlerFunc {
+func GetSatelliteTree(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		// Query all satellites
-		satRows, err := db.Query("SELECT id, noard_id, name, hex_color FROM satellite ORDER BY name")
+		satelliteNodes, err := loadSatelliteNodes(r.Context(), st)
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -261,83 +382,6 @@ func GetSatelliteTree(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer satRows.Close()
-
-		// Build satellite nodes
-		satelliteNodes := []models.TreeNode{}
-		for satRows.Next() {
-			var sat models.Satellite
-			if err := satRows.Scan(&sat.ID, &sat.NoardID, &sat.Name, &sat.HexColor); err != nil {
-				log.Printf("Error scanning satellite: %v", err)
-				continue
-			}
-
-			// Query latest TLE for this satellite
-			var tle1, tle2 string
-			err := db.QueryRow(`
-				SELECT line1, line2 FROM tle
-				WHERE sat_noard_id = ?
-				ORDER BY time DESC LIMIT 1
-			`, sat.NoardID).Scan(&tle1, &tle2)
-			if err != nil && err != sql.ErrNoRows {
-				log.Printf("Error querying TLE for satellite %s: %v", sat.NoardID, err)
-			}
-
-			// Query sensors for this satellite using noard_id
-			sensorRows, err := db.Query(`
-				SELECT s.id, s.sat_noard_id, s.sat_name, s.name, s.resolution, s.init_angle, 
-				       s.left_side_angle, s.observe_angle, s.hex_color
-				FROM sensor s
-				WHERE s.sat_noard_id = ?
-				ORDER BY s.name
-			`, sat.NoardID)
-			if err != nil {
-				log.Printf("Error querying sensors for satellite %s: %v", sat.NoardID, err)
-				continue
-			}
-
-			// Build sensor nodes
-			sensorNodes := []models.TreeNode{}
-			for sensorRows.Next() {
-				var sensorID int
-				var satNoardID, satName, sensorName, hexColor string
-				var resolution, initAngle, leftSideAngle, observeAngle float64
-				if err := sensorRows.Scan(&sensorID, &satNoardID, &satName, &sensorName,
-					&resolution, &initAngle, &leftSideAngle, &observeAngle, &hexColor); err != nil {
-					log.Printf("Error scanning sensor: %v", err)
-					continue
-				}
-
-				sensorNode := models.TreeNode{
-					ID:            sensorID,
-					Type:          "sensor",
-					Name:          sensorName,
-					HexColor:      hexColor,
-					SatNoardID:    satNoardID,
-					SatName:       satName,
-					Resolution:    resolution,
-					InitAngle:     initAngle,
-					LeftSideAngle: leftSideAngle,
-					CurSideAngle:  leftSideAngle,
-					ObserveAngle:  observeAngle,
-				}
-				sensorNodes = append(sensorNodes, sensorNode)
-			}
-			sensorRows.Close()
-
-			// Create satellite node with sensors as children
-			satNode := models.TreeNode{
-				ID:         sat.ID,
-				Type:       "satellite",
-				Name:       sat.Name,
-				HexColor:   sat.HexColor,
-				SatNoradID: sat.NoardID,
-				TLE1:       tle1,
-				TLE2:       tle2,
-				Children:   sensorNodes,
-			}
-			satelliteNodes = append(satelliteNodes, satNode)
-		}
 
 		// Create root node
 		rootNode := models.TreeNode{
@@ -356,3 +400,137 @@ func GetSatelliteTree(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// loadSatelliteNodes returns a tree node for every satellite, ordered by name, with its
+// latest TLE and its sensors as children
+func loadSatelliteNodes(ctx context.Context, st *store.Store) ([]models.TreeNode, error) {
+	satellites, err := st.Satellites.List(ctx, store.SatelliteFilter{})
+	if err != nil {
+		return nil, err
+	}
+	sensors, err := st.Sensors.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build sensor nodes by satellite
+	sensorNodes := map[int][]models.TreeNode{}
+	for _, s := range sensors {
+		sensorNodes[s.SatID] = append(sensorNodes[s.SatID], models.TreeNode{
+			ID:            s.ID,
+			Type:          "sensor",
+			Name:          s.Name,
+			HexColor:      s.HexColor,
+			SatNoardID:    s.SatNoardID,
+			SatName:       s.SatName,
+			Resolution:    s.Resolution,
+			InitAngle:     s.InitAngle,
+			LeftSideAngle: s.LeftSideAngle,
+			CurSideAngle:  s.LeftSideAngle,
+			ObserveAngle:  s.ObserveAngle,
+		})
+	}
+
+	// Build satellite nodes
+	satelliteNodes := []models.TreeNode{}
+	for _, sat := range satellites {
+		tle, err := st.TLEs.Latest(ctx, sat.ID)
+		if err != nil && err != store.ErrNotFound {
+			log.Printf("Error querying TLE for satellite %s: %v", sat.NoardID, err)
+		}
+
+		children := sensorNodes[sat.ID]
+		if children == nil {
+			children = []models.TreeNode{}
+		}
+		satNode := models.TreeNode{
+			ID:         sat.ID,
+			Type:       "satellite",
+			Name:       sat.Name,
+			HexColor:   sat.HexColor,
+			SatNoradID: sat.NoardID,
+			TLE1:       tle.Line1,
+			TLE2:       tle.Line2,
+			Children:   children,
+		}
+		satelliteNodes = append(satelliteNodes, satNode)
+	}
+	return satelliteNodes, nil
+}
+
+// ImportSatellites reads catalogue metadata from a SATCAT-style CSV file sent as the request
+// body and stores it on the satellites with matching NORAD IDs. Fields that are empty in
+// the file leave the stored value unchanged. With query parameter "create=true",
+// catalogue entries without a satellite are added as new satellites; otherwise they are
+// reported as not found. The import is all or nothing.
+func ImportSatellites(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		create := r.URL.Query().Get("create") == "true"
+
+		entries, err := satcat.Parse(r.Body)
+		if err == nil && len(entries) == 0 {
+			err = fmt.Errorf("no catalogue entries found")
+		}
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid catalogue: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sats := make([]models.Satellite, 0, len(entries))
+		for _, e := range entries {
+			sat := models.Satellite{
+				NoardID:        e.NoradID,
+				Name:           e.Name,
+				IntlDesignator: e.IntlDesignator,
+				Operator:       e.Operator,
+				Country:        e.Country,
+				LaunchDate:     e.LaunchDate,
+				Status:         e.Status,
+				OrbitClass:     e.OrbitClass,
+				Notes:          e.Notes,
+			}
+			if err := validateSatelliteMetadata(&sat); err != nil {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Invalid catalogue: NORAD ID %s: %v", e.NoradID, err),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			// an empty status leaves the stored one unchanged
+			sat.Status = e.Status
+			sats = append(sats, sat)
+		}
+
+		result, err := st.Satellites.Import(r.Context(), sats, create)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to import catalogue: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		message := fmt.Sprintf("Updated %d and created %d satellite(s)", result.Updated, result.Created)
+		if len(result.NotFound) > 0 {
+			message += fmt.Sprintf(" (%d not in the database)", len(result.NotFound))
+		}
+		response := models.Response{
+			Success: true,
+			Message: message,
+			Data:    result,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
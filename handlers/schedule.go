@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+)
+
+// SchedulePlans builds a slew-feasible acquisition timeline per satellite from the
+// candidate strips of several plans. Plans are listed in priority order; each gets at most
+// one acquisition and the ones that cannot be fitted are reported with the reason.
+func SchedulePlans(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req models.ScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if len(req.PlanIDs) == 0 {
+			response := models.Response{
+				Success: false,
+				Message: "plan_ids is required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		opts := planner.ScheduleOptions{Agility: map[string]planner.Agility{}, DutyCycles: map[int]planner.DutyCycle{}}
+		satellites := map[string]*planSatellite{}
+		requests := []planner.TaskRequest{}
+		for _, planID := range req.PlanIDs {
+			plan, err := st.Plans.Get(r.Context(), planID)
+			if err == store.ErrNotFound {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Plan %d not found", planID),
+				}
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(response)
+				return
+			} else if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Database error: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			sats, err := loadPlanSatellites(r.Context(), st, &plan)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to load plan satellites: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			task := planner.TaskRequest{ID: plan.ID, Name: plan.Name}
+			for i := range sats {
+				opts.Agility[sats[i].Satellite.NoardID] = planner.AgilityOf(sats[i].Satellite)
+				satellites[sats[i].Satellite.NoardID] = &sats[i]
+				sats[i].Sensors, task.Rejected = sensorsWithinLimits(sats[i].Sensors, task.Rejected)
+			}
+
+			strips, err := satelliteStrips(sats, &plan, filter)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to compute strips: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			for id, d := range dutyCycles(sats) {
+				if _, ok := opts.DutyCycles[id]; !ok {
+					opts.DutyCycles[id] = d
+				}
+			}
+			task.Candidates = strips
+			requests = append(requests, task)
+		}
+
+		if err := storageOptions(r.Context(), st, &opts, satellites, requests); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute downlink contacts: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		result := planner.Schedule(requests, opts)
+		data := scheduleResponse(result, satellites)
+
+		scheduled := 0
+		for _, s := range data.Satellites {
+			scheduled += len(s.Acquisitions)
+		}
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Scheduled %d of %d plan(s)", scheduled, len(requests)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// withinLimits reports whether the side angle of a sensor is within its left/right side
+// angle limits. The side angle of a SAR sensor only chooses its look side.
+func withinLimits(s planner.Sensor) bool {
+	return s.IsSAR() || !((s.SideAngle < 0 && -s.SideAngle > s.LeftSideAngle) || (s.SideAngle > 0 && s.SideAngle > s.RightSideAngle))
+}
+
+// sensorsWithinLimits drops the sensors whose side angle exceeds their left/right side
+// angle limits and records why in reason
+func sensorsWithinLimits(sensors []planner.Sensor, reason string) ([]planner.Sensor, string) {
+	kept := []planner.Sensor{}
+	for _, s := range sensors {
+		if !withinLimits(s) {
+			if reason == "" {
+				reason = fmt.Sprintf("side angle %.1f° of %s %s exceeds its limits (left %.1f°, right %.1f°)",
+					s.SideAngle, s.SatName, s.Name, s.LeftSideAngle, s.RightSideAngle)
+			}
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept, reason
+}
+
+// scheduleResponse converts a planner schedule to the API representation
+func scheduleResponse(result planner.ScheduleResult, satellites map[string]*planSatellite) models.ScheduleResponse {
+	data := models.ScheduleResponse{
+		Satellites:  []models.SatelliteSchedule{},
+		Unscheduled: []models.UnscheduledPlan{},
+	}
+	for noradID, timeline := range result.Timelines {
+		sat := models.SatelliteSchedule{SatNoardID: noradID, SatName: satellites[noradID].Satellite.Name}
+		for _, a := range timeline {
+			sat.Acquisitions = append(sat.Acquisitions, models.ScheduledAcquisition{
+				PlanID:       a.Request,
+				PlanName:     a.Name,
+				SlewTime:     a.SlewTime,
+				DataVolume:   a.Volume / 1000,
+				RecorderFill: a.RecorderFill / 1000,
+				Strip:        a.Strip,
+			})
+		}
+		if downlinks, ok := result.Downlinks[noradID]; ok {
+			sat.Downlinks = downlinkModels(downlinks)
+		}
+		data.Satellites = append(data.Satellites, sat)
+	}
+	sort.Slice(data.Satellites, func(i, j int) bool {
+		return data.Satellites[i].SatName < data.Satellites[j].SatName
+	})
+	for _, u := range result.Unscheduled {
+		data.Unscheduled = append(data.Unscheduled, models.UnscheduledPlan{
+			PlanID:     u.Request,
+			PlanName:   u.Name,
+			Candidates: u.Candidates,
+			Reason:     u.Reason,
+		})
+	}
+	return data
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"satplan/models"
+	"satplan/planner"
+)
+
+func TestSensorsWithinLimits(t *testing.T) {
+	optical := models.Sensor{Name: "CAM", SensorType: planner.Optical, LeftSideAngle: 20, RightSideAngle: 10}
+	sar := models.Sensor{Name: "SAR", SensorType: planner.SAR, LookSide: planner.LookBoth}
+
+	for _, c := range []struct {
+		name   string
+		sensor planner.Sensor
+		kept   bool
+	}{
+		{"optical at nadir", planner.Sensor{Sensor: optical}, true},
+		{"optical within the left limit", planner.Sensor{Sensor: optical, SideAngle: -20}, true},
+		{"optical past the left limit", planner.Sensor{Sensor: optical, SideAngle: -21}, false},
+		{"optical within the right limit", planner.Sensor{Sensor: optical, SideAngle: 10}, true},
+		{"optical past the right limit", planner.Sensor{Sensor: optical, SideAngle: 11}, false},
+		// a SAR sensor has no side angle limits; its side angle only picks the look side
+		{"SAR looking right", planner.Sensor{Sensor: sar, SideAngle: 1}, true},
+		{"SAR looking left", planner.Sensor{Sensor: sar, SideAngle: -1}, true},
+	} {
+		kept, reason := sensorsWithinLimits([]planner.Sensor{c.sensor}, "")
+		if got := len(kept) == 1; got != c.kept {
+			t.Errorf("%s: kept = %v (%q), want %v", c.name, got, reason, c.kept)
+		}
+	}
+}
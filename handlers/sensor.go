@@ -1,26 +1,24 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
 
 	"github.com/gorilla/mux"
 )
 
 // GetSensors returns all sensors
-func GetSensors(db *sql.DB) http.HandlerFunc {
+func GetSensors(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		rows, err := db.Query(`
-			SELECT id, sat_noard_id, sat_name, name, resolution, width, 
-			       right_side_angle, left_side_angle, observe_angle, hex_color, init_angle 
-			FROM sensor ORDER BY sat_name, name
-		`)
+		sensors, err := st.Sensors.List(r.Context())
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -30,19 +28,6 @@ func GetSensors(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		sensors := []models.Sensor{}
-		for rows.Next() {
-			var s models.Sensor
-			if err := rows.Scan(&s.ID, &s.SatNoardID, &s.SatName, &s.Name, &s.Resolution,
-				&s.Width, &s.RightSideAngle, &s.LeftSideAngle, &s.ObserveAngle,
-				&s.HexColor, &s.InitAngle); err != nil {
-				log.Printf("Error scanning sensor: %v", err)
-				continue
-			}
-			sensors = append(sensors, s)
-		}
 
 		response := models.Response{
 			Success: true,
@@ -55,23 +40,15 @@ func GetSensors(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSensorByID returns a single sensor by ID
-func GetSensorByID(db *sql.DB) http.HandlerFunc {
+func GetSensorByID(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
-
-		var s models.Sensor
-		err := db.QueryRow(`
-			SELECT id, sat_noard_id, sat_name, name, resolution, width, 
-			       right_side_angle, left_side_angle, observe_angle, hex_color, init_angle 
-			FROM sensor WHERE id = ?
-		`, id).Scan(&s.ID, &s.SatNoardID, &s.SatName, &s.Name, &s.Resolution,
-			&s.Width, &s.RightSideAngle, &s.LeftSideAngle, &s.ObserveAngle,
-			&s.HexColor, &s.InitAngle)
-
-		if err == sql.ErrNoRows {
+		id, _ := strconv.Atoi(vars["id"])
+
+		s, err := st.Sensors.Get(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "Sensor not found",
@@ -100,12 +77,12 @@ func GetSensorByID(db *sql.DB) http.HandlerFunc {
 }
 
 // GetAllSensors returns all sensors (alias for GetSensors)
-func GetAllSensors(db *sql.DB) http.HandlerFunc {
-	return GetSensors(db)
+func GetAllSensors(st *store.Store) http.HandlerFunc {
+	return GetSensors(st)
 }
 
 // AddSensor adds a new sensor
-func AddSensor(db *sql.DB) http.HandlerFunc {
+func AddSensor(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -121,23 +98,46 @@ func AddSensor(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Validate required fields
-		if sensor.SatNoardID == "" || sensor.Name == "" {
+		if (sensor.SatID == 0 && sensor.SatNoardID == "") || sensor.Name == "" {
 			response := models.Response{
 				Success: false,
-				Message: "sat_noard_id and name are required",
+				Message: "sat_id or sat_noard_id and name are required",
 			}
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
 
-		result, err := db.Exec(`INSERT INTO sensor (sat_noard_id, sat_name, name, resolution, width, 
-			right_side_angle, left_side_angle, observe_angle, hex_color, init_angle) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			sensor.SatNoardID, sensor.SatName, sensor.Name, sensor.Resolution, sensor.Width,
-			sensor.RightSideAngle, sensor.LeftSideAngle, sensor.ObserveAngle,
-			sensor.HexColor, sensor.InitAngle)
-		if err != nil {
+		if sensor.MaxOnTime < 0 || sensor.MaxAcquisitions < 0 || sensor.MinGap < 0 || sensor.DataRate < 0 ||
+			sensor.MaxPitchAngle < 0 {
+			response := models.Response{
+				Success: false,
+				Message: "data_rate, max_on_time, max_acquisitions, min_gap and max_pitch_angle must not be negative",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validateSensorType(&sensor); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := st.Sensors.Create(r.Context(), &sensor); err == store.ErrSatelliteNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to insert sensor: " + err.Error(),
@@ -147,9 +147,6 @@ func AddSensor(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		id, _ := result.LastInsertId()
-		sensor.ID = int(id)
-
 		response := models.Response{
 			Success: true,
 			Message: "Sensor added successfully",
@@ -162,7 +159,7 @@ func AddSensor(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSensorBySatId returns all sensors for a specific satellite
-func GetSensorBySatId(db *sql.DB) http.HandlerFunc {
+func GetSensorBySatId(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -177,11 +174,7 @@ func GetSensorBySatId(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		rows, err := db.Query(`
-			SELECT id, sat_noard_id, sat_name, name, resolution, width, 
-			       right_side_angle, left_side_angle, observe_angle, hex_color, init_angle 
-			FROM sensor WHERE sat_noard_id = ? ORDER BY name
-		`, satID)
+		sensors, err := st.Sensors.ListBySatellite(r.Context(), satID)
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -191,19 +184,6 @@ func GetSensorBySatId(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		sensors := []models.Sensor{}
-		for rows.Next() {
-			var s models.Sensor
-			if err := rows.Scan(&s.ID, &s.SatNoardID, &s.SatName, &s.Name, &s.Resolution,
-				&s.Width, &s.RightSideAngle, &s.LeftSideAngle, &s.ObserveAngle,
-				&s.HexColor, &s.InitAngle); err != nil {
-				log.Printf("Error scanning sensor: %v", err)
-				continue
-			}
-			sensors = append(sensors, s)
-		}
 
 		response := models.Response{
 			Success: true,
@@ -216,17 +196,17 @@ func GetSensorBySatId(db *sql.DB) http.HandlerFunc {
 }
 
 // GetSensorById returns a single sensor by ID (alias for GetSensorByID)
-func GetSensorById(db *sql.DB) http.HandlerFunc {
-	return GetSensorByID(db)
+func GetSensorById(st *store.Store) http.HandlerFunc {
+	return GetSensorByID(st)
 }
 
 // UpdateSensor updates an existing sensor
-func UpdateSensor(db *sql.DB) http.HandlerFunc {
+func UpdateSensor(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
 		var sensor models.Sensor
 		if err := json.NewDecoder(r.Body).Decode(&sensor); err != nil {
@@ -239,26 +219,46 @@ func UpdateSensor(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if sensor exists
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sensor WHERE id = ?)", id).Scan(&exists)
-		if err != nil || !exists {
+		if sensor.MaxOnTime < 0 || sensor.MaxAcquisitions < 0 || sensor.MinGap < 0 || sensor.DataRate < 0 ||
+			sensor.MaxPitchAngle < 0 {
 			response := models.Response{
 				Success: false,
-				Message: "Sensor not found",
+				Message: "data_rate, max_on_time, max_acquisitions, min_gap and max_pitch_angle must not be negative",
 			}
-			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
 
-		_, err = db.Exec(`UPDATE sensor SET sat_noard_id = ?, sat_name = ?, name = ?, resolution = ?, 
-			width = ?, right_side_angle = ?, left_side_angle = ?, observe_angle = ?, 
-			hex_color = ?, init_angle = ? WHERE id = ?`,
-			sensor.SatNoardID, sensor.SatName, sensor.Name, sensor.Resolution, sensor.Width,
-			sensor.RightSideAngle, sensor.LeftSideAngle, sensor.ObserveAngle,
-			sensor.HexColor, sensor.InitAngle, id)
-		if err != nil {
+		if err := validateSensorType(&sensor); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sensor.ID = id
+		err := st.Sensors.Update(r.Context(), &sensor)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Sensor not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err == store.ErrSatelliteNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to update sensor: " + err.Error(),
@@ -277,18 +277,16 @@ func UpdateSensor(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// DeleteSensor deletes a sensor by ID
-func DeleteSensor(db *sql.DB) http.HandlerFunc {
+// DeleteSensor deletes a sensor by ID together with its modes
+func DeleteSensor(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
-		// Check if sensor exists
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sensor WHERE id = ?)", id).Scan(&exists)
-		if err != nil || !exists {
+		err := st.Sensors.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "Sensor not found",
@@ -296,10 +294,7 @@ func DeleteSensor(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(response)
 			return
-		}
-
-		_, err = db.Exec("DELETE FROM sensor WHERE id = ?", id)
-		if err != nil {
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to delete sensor: " + err.Error(),
@@ -317,3 +312,35 @@ func DeleteSensor(db *sql.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// validateSensorType checks the SAR geometry and orbit direction of a sensor, defaulting
+// the type to optical and a SAR look side to right
+func validateSensorType(s *models.Sensor) error {
+	switch s.OrbitDirection {
+	case "", planner.Ascending, planner.Descending:
+	default:
+		return fmt.Errorf("orbit_direction must be ascending, descending or empty")
+	}
+	switch s.SensorType {
+	case "":
+		s.SensorType = planner.Optical
+		return nil
+	case planner.Optical:
+		return nil
+	case planner.SAR:
+	default:
+		return fmt.Errorf("sensor_type must be optical or sar")
+	}
+
+	if s.NearIncidence <= 0 || s.FarIncidence >= 90 || s.NearIncidence >= s.FarIncidence {
+		return fmt.Errorf("a SAR sensor needs 0 < near_incidence < far_incidence < 90")
+	}
+	switch s.LookSide {
+	case "":
+		s.LookSide = planner.LookRight
+	case planner.LookRight, planner.LookLeft, planner.LookBoth:
+	default:
+		return fmt.Errorf("look_side must be right, left or both")
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// downlinkHorizon is how long after the last candidate strip contacts are still searched
+// for, so that data recorded late in a window can be downlinked
+const downlinkHorizon = 24 * time.Hour
+
+// maxContactDuration bounds the time span of a contact window search
+const maxContactDuration = 7 * 24 * time.Hour
+
+// validateStation checks the location and elevation mask of a ground station
+func validateStation(st models.GroundStation) string {
+	if st.Name == "" {
+		return "name is required"
+	}
+	if st.Lat < -90 || st.Lat > 90 || st.Lon < -180 || st.Lon > 180 {
+		return "lat must be within [-90, 90] and lon within [-180, 180]"
+	}
+	if st.MinElevation < 0 || st.MinElevation >= 90 {
+		return "min_elevation must be within [0, 90)"
+	}
+	return ""
+}
+
+// GetGroundStations returns all ground stations
+func GetGroundStations(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		stations, err := st.Stations.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query ground stations: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ground stations retrieved successfully",
+			Data:    stations,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetGroundStationById returns a single ground station by ID
+func GetGroundStationById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		station, err := st.Stations.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Ground station not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ground station retrieved successfully",
+			Data:    station,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddGroundStation adds a new ground station
+func AddGroundStation(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var station models.GroundStation
+		if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if msg := validateStation(station); msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := st.Stations.Create(r.Context(), &station); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert ground station: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ground station added successfully",
+			Data:    station,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// UpdateGroundStation updates an existing ground station
+func UpdateGroundStation(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+
+		var station models.GroundStation
+		if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if msg := validateStation(station); msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		station.ID, _ = strconv.Atoi(vars["id"])
+		err := st.Stations.Update(r.Context(), station)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Ground station not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update ground station: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ground station updated successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeleteGroundStation deletes a ground station by ID
+func DeleteGroundStation(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Stations.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Ground station not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete ground station: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Ground station deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetSatelliteContacts returns the contact windows of a satellite with all ground stations.
+// Query parameters "start" and "end" are unix timestamps; the default is the next 24 hours.
+func GetSatelliteContacts(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		start, end, err := parseTimeRange(r, maxContactDuration)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		satellite, err := st.Satellites.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Satellite not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sat, err := loadSatellite(r.Context(), st, satellite.NoardID, start, end)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to load satellite: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		stations, err := st.Stations.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query ground stations: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		contacts, err := planner.ContactWindows(sat.Propagator, plannerStations(stations), start, end)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute contacts: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := []models.Contact{}
+		for _, c := range contacts {
+			data = append(data, contactModel(c))
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d contact(s) with %d ground station(s)", len(data), len(stations)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// parseTimeRange reads the start and end query parameters (unix seconds), defaulting to
+// the next 24 hours, and checks that the range is no longer than maxDuration
+func parseTimeRange(r *http.Request, maxDuration time.Duration) (time.Time, time.Time, error) {
+	start := time.Now().UTC()
+	end := start.Add(24 * time.Hour)
+	query := r.URL.Query()
+	for _, p := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"start", &start},
+		{"end", &end},
+	} {
+		s := query.Get(p.name)
+		if s == "" {
+			continue
+		}
+		ts, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return start, end, fmt.Errorf("%s must be a unix timestamp", p.name)
+		}
+		*p.value = time.Unix(ts, 0).UTC()
+	}
+	if !end.After(start) || end.Sub(start) > maxDuration {
+		return start, end, fmt.Errorf("end must be after start and at most %d days later", int(maxDuration.Hours()/24))
+	}
+	return start, end, nil
+}
+
+// plannerStations converts ground stations to the planner representation
+func plannerStations(stations []models.GroundStation) []planner.Station {
+	out := make([]planner.Station, len(stations))
+	for i, st := range stations {
+		out[i] = planner.Station{
+			ID:           st.ID,
+			Name:         st.Name,
+			Location:     orbit.Geodetic{Lat: st.Lat, Lon: st.Lon, Alt: st.Alt / 1000},
+			MinElevation: st.MinElevation,
+		}
+	}
+	return out
+}
+
+// contactModel converts a planner contact to the API representation
+func contactModel(c planner.Contact) models.Contact {
+	return models.Contact{
+		StationID:    c.StationID,
+		StationName:  c.Station,
+		Start:        c.Start,
+		End:          c.End,
+		MaxElevation: c.MaxElevation,
+	}
+}
+
+// downlinkModels converts planner downlinks to the API representation in Gbit, leaving
+// out the contacts that carried no data
+func downlinkModels(downlinks []planner.Downlink) []models.Downlink {
+	out := []models.Downlink{}
+	for _, d := range downlinks {
+		if d.Volume <= 0 {
+			continue
+		}
+		out = append(out, models.Downlink{
+			Contact:      contactModel(d.Contact),
+			Volume:       d.Volume / 1000,
+			RecorderFill: d.RecorderFill / 1000,
+		})
+	}
+	return out
+}
+
+// storageOptions fills opts.Storage for the satellites with a recorder
+// capacity, searching contacts from the first candidate strip until downlinkHorizon
+// after the last one
+func storageOptions(ctx context.Context, st *store.Store, opts *planner.ScheduleOptions, satellites map[string]*planSatellite, tasks []planner.TaskRequest) error {
+	opts.Storage = map[string]planner.Storage{}
+
+	var first, last int64
+	for _, t := range tasks {
+		for _, c := range t.Candidates {
+			if first == 0 || c.StartTimestamp < first {
+				first = c.StartTimestamp
+			}
+			if c.StopTimestamp > last {
+				last = c.StopTimestamp
+			}
+		}
+	}
+	if first == 0 {
+		return nil
+	}
+
+	var stations []planner.Station
+	for noradID, sat := range satellites {
+		if sat.Satellite.RecorderCapacity <= 0 {
+			continue
+		}
+		if stations == nil {
+			all, err := st.Stations.List(ctx)
+			if err != nil {
+				return err
+			}
+			stations = plannerStations(all)
+		}
+
+		start := time.Unix(first, 0).UTC()
+		end := time.Unix(last, 0).UTC().Add(downlinkHorizon)
+		contacts, err := planner.ContactWindows(sat.Propagator, stations, start, end)
+		if err != nil {
+			return fmt.Errorf("satellite %s: %v", sat.Satellite.Name, err)
+		}
+		opts.Storage[noradID] = planner.Storage{
+			Capacity:     sat.Satellite.RecorderCapacity * 1000,
+			DownlinkRate: sat.Satellite.DownlinkRate,
+			Contacts:     contacts,
+		}
+	}
+	return nil
+}
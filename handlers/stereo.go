@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"satplan/models"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// Default stereo geometry when neither a convergence nor a base-to-height range is given
+const (
+	defaultMinConvergence = 15.0
+	defaultMaxConvergence = 40.0
+	defaultStereoLimit    = 20
+)
+
+// GetPlanStereo searches the plan's satellites for stereo pairs or triplets over the
+// centre of its target area. Every sensor of the plan is tried at all side angles it can
+// reach, not only the plan's; the illumination query parameters of the strip endpoints
+// apply.
+func GetPlanStereo(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		var req models.StereoRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		opts, msg := stereoOptions(req)
+		if msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		filter, err := parseStripFilter(r)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		plan, err := st.Plans.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Plan not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sats, err := loadPlanSatellites(r.Context(), st, &plan)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to load plan satellites: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// try every optical sensor across its whole side angle range
+		bySat := map[string]*planSatellite{}
+		for i := range sats {
+			seen := map[int]bool{}
+			sensors := []planner.Sensor{}
+			for _, s := range sats[i].Sensors {
+				if seen[s.ID] || s.IsSAR() {
+					continue
+				}
+				seen[s.ID] = true
+				for _, angle := range planner.SideAngles(s.Sensor) {
+					sensors = append(sensors, planner.Sensor{Sensor: s.Sensor, SideAngle: angle})
+				}
+			}
+			sats[i].Sensors = sensors
+			bySat[sats[i].Satellite.NoardID] = &sats[i]
+			opts.Agility[sats[i].Satellite.NoardID] = planner.AgilityOf(sats[i].Satellite)
+		}
+
+		strips, err := satelliteStrips(sats, &plan, filter)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to compute strips: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		target := [2]float64{(plan.MinLon + plan.MaxLon) / 2, (plan.MinLat + plan.MaxLat) / 2}
+		looks := []planner.Look{}
+		for pass, strip := range strips {
+			sat := bySat[strip.SatNoardID]
+			var sensor planner.Sensor
+			for _, s := range sat.Sensors {
+				if s.ID == strip.SensorID && s.SideAngle == strip.SideAngle {
+					sensor = s
+				}
+			}
+			stripLooks, err := planner.StereoLooks(sat.Propagator, strip, sensor, target, opts.AlongTrack)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Failed to compute looks: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			for i := range stripLooks {
+				stripLooks[i].Pass = pass
+			}
+			looks = append(looks, stripLooks...)
+		}
+
+		window := time.Duration(plan.EndTime-plan.StartTime) * time.Second
+		candidates, err := planner.Stereo(looks, target, window, opts)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		data := models.StereoResponse{Target: target, Looks: len(looks), Candidates: []models.StereoCandidate{}}
+		for _, c := range candidates {
+			candidate := models.StereoCandidate{
+				Type:        c.Type,
+				Convergence: c.Convergence,
+				BH:          c.BH,
+				TimeSpan:    c.TimeSpan,
+				MaxOffNadir: c.MaxOffNadir,
+				Score:       c.Score,
+			}
+			for _, l := range c.Looks {
+				candidate.Looks = append(candidate.Looks, models.StereoLook{
+					SatNoardID:    l.Strip.SatNoardID,
+					SatName:       l.Strip.SatName,
+					SensorID:      l.Strip.SensorID,
+					SensorName:    l.Strip.SensorName,
+					Time:          l.Time,
+					SideAngle:     l.Strip.SideAngle,
+					PitchAngle:    l.Pitch,
+					OffNadirAngle: l.OffNadir,
+					ViewAzimuth:   l.Azimuth,
+					ViewElevation: l.Elevation,
+				})
+			}
+			data.Candidates = append(data.Candidates, candidate)
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d stereo candidate(s) from %d look(s)", len(data.Candidates), len(looks)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// stereoOptions validates a stereo request and fills in the defaults
+func stereoOptions(req models.StereoRequest) (planner.StereoOptions, string) {
+	opts := planner.StereoOptions{
+		Images:         req.Images,
+		MinConvergence: req.MinConvergence,
+		MaxConvergence: req.MaxConvergence,
+		MinBH:          req.MinBH,
+		MaxBH:          req.MaxBH,
+		MaxTimeSpan:    time.Duration(req.MaxTimeSpan) * time.Second,
+		Limit:          req.Limit,
+		Agility:        map[string]planner.Agility{},
+	}
+	if opts.Images == 0 {
+		opts.Images = 2
+	}
+	if opts.Images != 2 && opts.Images != 3 {
+		return opts, "images must be 2 or 3"
+	}
+	switch req.Mode {
+	case "", "both":
+		opts.AlongTrack, opts.CrossPass = true, true
+	case planner.AlongTrack:
+		opts.AlongTrack = true
+	case planner.CrossPass:
+		opts.CrossPass = true
+	default:
+		return opts, "mode must be \"along_track\", \"cross_pass\" or \"both\""
+	}
+	if opts.MinConvergence < 0 || opts.MaxConvergence < 0 || opts.MinBH < 0 || opts.MaxBH < 0 || req.MaxTimeSpan < 0 || opts.Limit < 0 {
+		return opts, "stereo constraints must not be negative"
+	}
+	if (opts.MaxConvergence > 0 && opts.MinConvergence > opts.MaxConvergence) || (opts.MaxBH > 0 && opts.MinBH > opts.MaxBH) {
+		return opts, "minimum must not exceed maximum"
+	}
+	if opts.MinConvergence == 0 && opts.MaxConvergence == 0 && opts.MinBH == 0 && opts.MaxBH == 0 {
+		opts.MinConvergence, opts.MaxConvergence = defaultMinConvergence, defaultMaxConvergence
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultStereoLimit
+	}
+	return opts, ""
+}
@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+	"satplan/store"
+
+	"github.com/gorilla/mux"
+)
+
+// validateTarget checks the name and location of a point target
+func validateTarget(t models.PointTarget) string {
+	if t.Name == "" {
+		return "name is required"
+	}
+	if t.Lat < -90 || t.Lat > 90 || t.Lon < -180 || t.Lon > 180 {
+		return "lat must be within [-90, 90] and lon within [-180, 180]"
+	}
+	return ""
+}
+
+// GetPointTargets returns all point targets
+func GetPointTargets(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		targets, err := st.Targets.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query point targets: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Point targets retrieved successfully",
+			Data:    targets,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetPointTargetById returns a single point target by ID
+func GetPointTargetById(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		t, err := st.Targets.Get(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Point target not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Database error: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Point target retrieved successfully",
+			Data:    t,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// AddPointTarget adds a new point target
+func AddPointTarget(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var t models.PointTarget
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if msg := validateTarget(t); msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		targets := []models.PointTarget{t}
+		if err := st.Targets.Create(r.Context(), targets); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert point target: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Point target added successfully",
+			Data:    targets[0],
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// ImportPointTargets adds the point targets of a CSV document. The first row is a header
+// naming the columns "name", "lat", "lon" and optionally "alt" (metres), in any order.
+// Nothing is imported if any row is invalid.
+func ImportPointTargets(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		targets, err := parseTargetCSV(r.Body)
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid CSV: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := st.Targets.Create(r.Context(), targets); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to insert point target: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Imported %d point target(s)", len(targets)),
+			Data:    targets,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// parseTargetCSV reads point targets from CSV with a header row
+func parseTargetCSV(body io.Reader) ([]models.PointTarget, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "lat", "lon"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header has no %q column", required)
+		}
+	}
+
+	targets := []models.PointTarget{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) (float64, error) {
+			i, ok := columns[name]
+			if !ok || strings.TrimSpace(record[i]) == "" {
+				return 0, nil
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: %s must be a number", line, name)
+			}
+			return v, nil
+		}
+
+		t := models.PointTarget{Name: strings.TrimSpace(record[columns["name"]])}
+		if t.Lat, err = field("lat"); err != nil {
+			return nil, err
+		}
+		if t.Lon, err = field("lon"); err != nil {
+			return nil, err
+		}
+		if t.Alt, err = field("alt"); err != nil {
+			return nil, err
+		}
+		if msg := validateTarget(t); msg != "" {
+			return nil, fmt.Errorf("line %d: %s", line, msg)
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	return targets, nil
+}
+
+// UpdatePointTarget updates an existing point target
+func UpdatePointTarget(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+
+		var t models.PointTarget
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if msg := validateTarget(t); msg != "" {
+			response := models.Response{
+				Success: false,
+				Message: msg,
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		t.ID, _ = strconv.Atoi(vars["id"])
+		err := st.Targets.Update(r.Context(), t)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Point target not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to update point target: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Point target updated successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DeletePointTarget deletes a point target by ID
+func DeletePointTarget(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		vars := mux.Vars(r)
+		id, _ := strconv.Atoi(vars["id"])
+
+		err := st.Targets.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
+			response := models.Response{
+				Success: false,
+				Message: "Point target not found",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response)
+			return
+		} else if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to delete point target: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response := models.Response{
+			Success: true,
+			Message: "Point target deleted successfully",
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetAccessWindows computes, per satellite and sensor, the windows in which point targets
+// can be imaged within the sensors' side angle limits
+func GetAccessWindows(st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req models.AccessRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Invalid request body: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if req.EndTime <= req.StartTime || time.Duration(req.EndTime-req.StartTime)*time.Second > maxPlanDuration {
+			response := models.Response{
+				Success: false,
+				Message: fmt.Sprintf("end_time must be after start_time and at most %d days later", int(maxPlanDuration.Hours()/24)),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if req.MaxOffNadir < 0 || req.MaxOffNadir >= 90 || req.MinElevation < 0 || req.MinElevation >= 90 {
+			response := models.Response{
+				Success: false,
+				Message: "max_off_nadir and min_elevation must be within [0, 90)",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		targets := req.Targets
+		for _, t := range targets {
+			if msg := validateTarget(t); msg != "" {
+				response := models.Response{
+					Success: false,
+					Message: "Invalid target: " + msg,
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+		for _, id := range req.TargetIDs {
+			t, err := st.Targets.Get(r.Context(), id)
+			if err == store.ErrNotFound {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Point target %d not found", id),
+				}
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(response)
+				return
+			} else if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Database error: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			targets = append(targets, t)
+		}
+		if len(targets) == 0 {
+			response := models.Response{
+				Success: false,
+				Message: "targets or target_ids is required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		sensors, err := st.Sensors.List(r.Context())
+		if err != nil {
+			response := models.Response{
+				Success: false,
+				Message: "Failed to query sensors: " + err.Error(),
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if len(req.SensorIDs) > 0 {
+			wanted := map[int]bool{}
+			for _, id := range req.SensorIDs {
+				wanted[id] = true
+			}
+			selected := []models.Sensor{}
+			for _, s := range sensors {
+				if wanted[s.ID] {
+					selected = append(selected, s)
+				}
+			}
+			sensors = selected
+		}
+
+		points := make([]planner.PointTarget, len(targets))
+		for i, t := range targets {
+			points[i] = planner.PointTarget{ID: t.ID, Name: t.Name, Location: orbit.Geodetic{Lat: t.Lat, Lon: t.Lon, Alt: t.Alt / 1000}}
+		}
+		limits := planner.AccessLimits{MaxOffNadir: req.MaxOffNadir, MinElevation: req.MinElevation}
+		start := time.Unix(req.StartTime, 0).UTC()
+		end := time.Unix(req.EndTime, 0).UTC()
+
+		// group the sensors by satellite so that each orbit is propagated once
+		order := []string{}
+		bySat := map[string][]models.Sensor{}
+		for _, s := range sensors {
+			if _, ok := bySat[s.SatNoardID]; !ok {
+				order = append(order, s.SatNoardID)
+			}
+			bySat[s.SatNoardID] = append(bySat[s.SatNoardID], s)
+		}
+
+		data := models.AccessResponse{Targets: targets, Sensors: []models.SensorAccess{}}
+		for _, noradID := range order {
+			sat, err := loadSatellite(r.Context(), st, noradID, start, end)
+			if err != nil {
+				log.Printf("Skipping satellite %s for access windows: %v", noradID, err)
+				continue
+			}
+			accesses, err := planner.AccessWindows(sat.Propagator, bySat[noradID], points, start, end, limits)
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: fmt.Sprintf("Failed to compute access windows of %s: %v", sat.Satellite.Name, err),
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			for _, s := range bySat[noradID] {
+				access := models.SensorAccess{
+					SatNoardID: noradID,
+					SatName:    sat.Satellite.Name,
+					SensorID:   s.ID,
+					SensorName: s.Name,
+					Windows:    []models.AccessWindow{},
+				}
+				for _, a := range accesses {
+					if a.SensorID != s.ID {
+						continue
+					}
+					access.Windows = append(access.Windows, models.AccessWindow{
+						TargetID:         targets[a.Target].ID,
+						TargetName:       targets[a.Target].Name,
+						Start:            a.Start,
+						End:              a.End,
+						Roll:             a.Roll,
+						SideAngle:        a.SideAngle,
+						MinOffNadirAngle: a.OffNadir,
+						MinOffNadirTime:  a.Time,
+						Elevation:        a.Elevation,
+						GSDCrossTrack:    a.GSDCross,
+						GSDAlongTrack:    a.GSDAlong,
+						SunElevation:     a.SunElevation,
+					})
+				}
+				data.Sensors = append(data.Sensors, access)
+			}
+		}
+
+		windows := 0
+		for _, s := range data.Sensors {
+			windows += len(s.Windows)
+		}
+		response := models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Found %d access window(s) to %d target(s)", windows, len(targets)),
+			Data:    data,
+		}
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
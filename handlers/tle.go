@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"bufio"
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,16 +13,17 @@ import (
 	"time"
 
 	"satplan/models"
+	"satplan/store"
 
 	"github.com/gorilla/mux"
 )
 
 // GetTLEs returns recent TLE data
-func GetTLEs(db *sql.DB) http.HandlerFunc {
+func GetTLEs(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		rows, err := db.Query("SELECT id, sat_noard_id, time, line1, line2 FROM tle ORDER BY time DESC LIMIT 100")
+		tles, err := st.TLEs.List(r.Context(), 100)
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -32,17 +33,6 @@ func GetTLEs(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		tles := []models.TLE{}
-		for rows.Next() {
-			var t models.TLE
-			if err := rows.Scan(&t.ID, &t.SatNoardID, &t.Time, &t.Line1, &t.Line2); err != nil {
-				log.Printf("Error scanning TLE: %v", err)
-				continue
-			}
-			tles = append(tles, t)
-		}
 
 		response := models.Response{
 			Success: true,
@@ -55,14 +45,14 @@ func GetTLEs(db *sql.DB) http.HandlerFunc {
 }
 
 // GetTLEBySatellite returns TLE data for a specific satellite
-func GetTLEBySatellite(db *sql.DB) http.HandlerFunc {
+func GetTLEBySatellite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
 		noradID := vars["norad_id"]
 
-		rows, err := db.Query("SELECT id, sat_noard_id, time, line1, line2 FROM tle WHERE sat_noard_id = ? ORDER BY time DESC", noradID)
+		tles, err := st.TLEs.ListBySatellite(r.Context(), noradID)
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -72,17 +62,6 @@ func GetTLEBySatellite(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		tles := []models.TLE{}
-		for rows.Next() {
-			var t models.TLE
-			if err := rows.Scan(&t.ID, &t.SatNoardID, &t.Time, &t.Line1, &t.Line2); err != nil {
-				log.Printf("Error scanning TLE: %v", err)
-				continue
-			}
-			tles = append(tles, t)
-		}
 
 		response := models.Response{
 			Success: true,
@@ -96,7 +75,7 @@ func GetTLEBySatellite(db *sql.DB) http.HandlerFunc {
 
 // UpdateTles updates TLE data (batch update from external source)
 // Only updates TLEs for satellites that exist in the satellite table
-func UpdateTles(db *sql.DB) http.HandlerFunc {
+func UpdateTles(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -121,56 +100,25 @@ func UpdateTles(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// Begin transaction
-		tx, err := db.Begin()
+		// Insert TLEs only for satellites that exist in the satellite table
+		result, err := st.TLEs.Add(r.Context(), tles)
 		if err != nil {
 			response := models.Response{
 				Success: false,
-				Message: "Failed to begin transaction: " + err.Error(),
+				Message: "Failed to store TLE data: " + err.Error(),
 			}
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer tx.Rollback()
-
-		// Insert TLEs only for satellites that exist in the satellite table
-		inserted := 0
-		skipped := 0
-		notFound := []string{}
-
-		for _, tle := range tles {
-			// Check if satellite exists in satellite table
-			var exists bool
-			err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM satellite WHERE noard_id = ?)", tle.SatNoardID).Scan(&exists)
-			if err != nil {
-				log.Printf("Failed to check satellite existence for %s: %v", tle.SatNoardID, err)
-				skipped++
-				continue
-			}
-
-			if !exists {
-				log.Printf("Satellite with NORAD ID %s not found in satellite table, skipping", tle.SatNoardID)
-				notFound = append(notFound, tle.SatNoardID)
-				skipped++
-				continue
-			}
-
-			// Insert TLE for existing satellite
-			_, err = tx.Exec("INSERT INTO tle (sat_noard_id, time, line1, line2) VALUES (?, ?, ?, ?)",
-				tle.SatNoardID, tle.Time, tle.Line1, tle.Line2)
-			if err != nil {
-				log.Printf("Failed to insert TLE for satellite %s: %v", tle.SatNoardID, err)
-				skipped++
-				continue
-			}
-			inserted++
+		for _, noradID := range result.NotFound {
+			log.Printf("Satellite with NORAD ID %s not found in satellite table, skipping", noradID)
 		}
 
-		if inserted == 0 {
-			message := fmt.Sprintf("Failed to insert any TLE records. All %d records were skipped.", skipped)
-			if len(notFound) > 0 {
-				message += fmt.Sprintf(" Satellites not found: %v", notFound)
+		if result.Inserted == 0 {
+			message := fmt.Sprintf("Failed to insert any TLE records. All %d records were skipped.", result.Skipped)
+			if len(result.NotFound) > 0 {
+				message += fmt.Sprintf(" Satellites not found: %v", result.NotFound)
 			}
 			response := models.Response{
 				Success: false,
@@ -181,28 +129,18 @@ func UpdateTles(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := tx.Commit(); err != nil {
-			response := models.Response{
-				Success: false,
-				Message: "Failed to commit transaction: " + err.Error(),
-			}
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		message := fmt.Sprintf("Successfully updated %d TLE record(s)", inserted)
-		if skipped > 0 {
-			message += fmt.Sprintf(" (%d skipped)", skipped)
+		message := fmt.Sprintf("Successfully updated %d TLE record(s)", result.Inserted)
+		if result.Skipped > 0 {
+			message += fmt.Sprintf(" (%d skipped)", result.Skipped)
 		}
 
 		responseData := map[string]interface{}{
-			"inserted": inserted,
-			"skipped":  skipped,
+			"inserted": result.Inserted,
+			"skipped":  result.Skipped,
 			"total":    len(tles),
 		}
-		if len(notFound) > 0 {
-			responseData["not_found"] = notFound
+		if len(result.NotFound) > 0 {
+			responseData["not_found"] = result.NotFound
 		}
 
 		response := models.Response{
@@ -216,17 +154,15 @@ func UpdateTles(db *sql.DB) http.HandlerFunc {
 }
 
 // DeleteTLE deletes a TLE record by ID
-func DeleteTLE(db *sql.DB) http.HandlerFunc {
+func DeleteTLE(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
-		// Check if TLE exists
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM tle WHERE id = ?)", id).Scan(&exists)
-		if err != nil || !exists {
+		err := st.TLEs.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "TLE not found",
@@ -234,10 +170,7 @@ func DeleteTLE(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(response)
 			return
-		}
-
-		_, err = db.Exec("DELETE FROM tle WHERE id = ?", id)
-		if err != nil {
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to delete TLE: " + err.Error(),
@@ -257,11 +190,11 @@ func DeleteTLE(db *sql.DB) http.HandlerFunc {
 }
 
 // GetTLESites returns all TLE data sources
-func GetTLESites(db *sql.DB) http.HandlerFunc {
+func GetTLESites(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		rows, err := db.Query("SELECT id, site, url, description FROM tle_site")
+		sites, err := st.TLESites.List(r.Context())
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -271,17 +204,6 @@ func GetTLESites(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		sites := []models.TLESite{}
-		for rows.Next() {
-			var site models.TLESite
-			if err := rows.Scan(&site.ID, &site.Site, &site.URL, &site.Description); err != nil {
-				log.Printf("Error scanning TLE site: %v", err)
-				continue
-			}
-			sites = append(sites, site)
-		}
 
 		response := models.Response{
 			Success: true,
@@ -293,12 +215,47 @@ func GetTLESites(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// AutoUpdateTLEs fetches TLE data from all sites in tle_site table and updates the database
-func AutoUpdateTLEs(db *sql.DB) http.HandlerFunc {
+// AutoUpdateTLEs fetches TLE data from all sites in tle_site table and updates the database.
+// Query parameter "group_id" restricts the update to the satellites of a group.
+func AutoUpdateTLEs(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		result, err := performTLEUpdateCore(db)
+		var only map[string]bool
+		if v := r.URL.Query().Get("group_id"); v != "" {
+			groupID, err := strconv.Atoi(v)
+			if err == nil {
+				_, err = st.Groups.Get(r.Context(), groupID)
+			}
+			if err == store.ErrNotFound {
+				response := models.Response{
+					Success: false,
+					Message: "Group not found",
+				}
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			var noradIDs []string
+			if err == nil {
+				noradIDs, err = st.Groups.Satellites(r.Context(), groupID)
+			}
+			if err != nil {
+				response := models.Response{
+					Success: false,
+					Message: "Invalid group_id: " + err.Error(),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			only = map[string]bool{}
+			for _, id := range noradIDs {
+				only[id] = true
+			}
+		}
+
+		result, err := performTLEUpdateCore(r.Context(), st, only)
 
 		// Handle errors
 		if err != nil {
@@ -360,8 +317,27 @@ func AutoUpdateTLEs(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// namedTLE is a TLE with the object name given on its title line
+type namedTLE struct {
+	Name string
+	models.TLE
+}
+
 // fetchTLEFromURL fetches and parses TLE data from a URL
 func fetchTLEFromURL(url string) ([]models.TLE, error) {
+	named, err := fetchNamedTLEs(url)
+	if err != nil {
+		return nil, err
+	}
+	tles := make([]models.TLE, 0, len(named))
+	for _, t := range named {
+		tles = append(tles, t.TLE)
+	}
+	return tles, nil
+}
+
+// fetchNamedTLEs fetches and parses three-line TLE data from a URL, keeping the names
+func fetchNamedTLEs(url string) ([]namedTLE, error) {
 	// Fetch the TLE data from URL
 	resp, err := http.Get(url)
 	if err != nil {
@@ -374,10 +350,10 @@ func fetchTLEFromURL(url string) ([]models.TLE, error) {
 	}
 
 	// Parse TLE data
-	tles := []models.TLE{}
+	tles := []namedTLE{}
 	scanner := bufio.NewScanner(resp.Body)
 
-	var line1 string
+	var name, line1 string
 	lineCount := 0
 
 	for scanner.Scan() {
@@ -389,7 +365,8 @@ func fetchTLEFromURL(url string) ([]models.TLE, error) {
 		}
 
 		if lineCount == 0 {
-			// First line is the satellite name (just skip it, we use NORAD ID)
+			// First line is the satellite name
+			name = strings.TrimPrefix(line, "0 ")
 			lineCount++
 		} else if lineCount == 1 {
 			// Second line is TLE line 1
@@ -412,7 +389,7 @@ func fetchTLEFromURL(url string) ([]models.TLE, error) {
 						Line1:      line1,
 						Line2:      line,
 					}
-					tles = append(tles, tle)
+					tles = append(tles, namedTLE{Name: name, TLE: tle})
 				}
 				lineCount = 0
 			} else {
@@ -456,29 +433,19 @@ type TLEUpdateResult struct {
 }
 
 // performTLEUpdateCore is the core reusable function for TLE updates
-// It fetches TLE data from configured sites and updates the database
-func performTLEUpdateCore(db *sql.DB) (*TLEUpdateResult, error) {
+// It fetches TLE data from configured sites and updates the database; when only is not
+// nil, TLEs of satellites outside it are ignored
+func performTLEUpdateCore(ctx context.Context, st *store.Store, only map[string]bool) (*TLEUpdateResult, error) {
 	result := &TLEUpdateResult{
 		FailedSites: []string{},
 		NotFound:    []string{},
 	}
 
 	// Get all TLE sites
-	rows, err := db.Query("SELECT id, site, url, description FROM tle_site")
+	sites, err := st.TLESites.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query TLE sites: %v", err)
 	}
-	defer rows.Close()
-
-	sites := []models.TLESite{}
-	for rows.Next() {
-		var site models.TLESite
-		if err := rows.Scan(&site.ID, &site.Site, &site.URL, &site.Description); err != nil {
-			log.Printf("Error scanning TLE site: %v", err)
-			continue
-		}
-		sites = append(sites, site)
-	}
 
 	if len(sites) == 0 {
 		return nil, fmt.Errorf("no TLE sites configured")
@@ -504,52 +471,32 @@ func performTLEUpdateCore(db *sql.DB) (*TLEUpdateResult, error) {
 		return result, fmt.Errorf("no TLE data fetched from any site")
 	}
 
-	// Begin transaction to insert TLEs
-	tx, err := db.Begin()
-	if err != nil {
-		return result, fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
-
-	// Insert TLEs only for satellites that exist in the satellite table
-	for _, tle := range allTLEs {
-		// Check if satellite exists in satellite table
-		var exists bool
-		err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM satellite WHERE noard_id = ?)", tle.SatNoardID).Scan(&exists)
-		if err != nil {
-			log.Printf("Failed to check satellite existence for %s: %v", tle.SatNoardID, err)
-			result.Skipped++
-			continue
-		}
-
-		if !exists {
-			result.NotFound = append(result.NotFound, tle.SatNoardID)
-			result.Skipped++
-			continue
-		}
-
-		// Insert TLE for existing satellite
-		_, err = tx.Exec("INSERT INTO tle (sat_noard_id, time, line1, line2) VALUES (?, ?, ?, ?)",
-			tle.SatNoardID, tle.Time, tle.Line1, tle.Line2)
-		if err != nil {
-			log.Printf("Failed to insert TLE for satellite %s: %v", tle.SatNoardID, err)
-			result.Skipped++
-			continue
+	if only != nil {
+		selected := []models.TLE{}
+		for _, tle := range allTLEs {
+			if only[tle.SatNoardID] {
+				selected = append(selected, tle)
+			}
 		}
-		result.Inserted++
+		allTLEs = selected
 	}
 
-	if err := tx.Commit(); err != nil {
-		return result, fmt.Errorf("failed to commit transaction: %v", err)
+	// Insert TLEs only for satellites that exist in the satellite table
+	added, err := st.TLEs.Add(ctx, allTLEs)
+	if err != nil {
+		return result, fmt.Errorf("failed to store TLE data: %v", err)
 	}
+	result.Inserted = added.Inserted
+	result.Skipped = added.Skipped
+	result.NotFound = added.NotFound
 
 	return result, nil
 }
 
 // PerformAutoUpdateTLEs performs automatic TLE update without HTTP context
 // This is used for initial database setup and scheduled updates
-func PerformAutoUpdateTLEs(db *sql.DB) error {
-	result, err := performTLEUpdateCore(db)
+func PerformAutoUpdateTLEs(st *store.Store) error {
+	result, err := performTLEUpdateCore(context.Background(), st, nil)
 	if err != nil {
 		return err
 	}
@@ -561,18 +508,15 @@ func PerformAutoUpdateTLEs(db *sql.DB) error {
 }
 
 // GetTLESiteById returns a specific TLE site by ID
-func GetTLESiteById(db *sql.DB) http.HandlerFunc {
+func GetTLESiteById(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
-
-		var site models.TLESite
-		err := db.QueryRow("SELECT id, site, url, description FROM tle_site WHERE id = ?", id).
-			Scan(&site.ID, &site.Site, &site.URL, &site.Description)
+		id, _ := strconv.Atoi(vars["id"])
 
-		if err == sql.ErrNoRows {
+		site, err := st.TLESites.Get(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "TLE site not found",
@@ -603,7 +547,7 @@ func GetTLESiteById(db *sql.DB) http.HandlerFunc {
 }
 
 // AddTLESite adds a new TLE site
-func AddTLESite(db *sql.DB) http.HandlerFunc {
+func AddTLESite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -629,10 +573,7 @@ func AddTLESite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		result, err := db.Exec("INSERT INTO tle_site (site, url, description) VALUES (?, ?, ?)",
-			site.Site, site.URL, site.Description)
-
-		if err != nil {
+		if err := st.TLESites.Create(r.Context(), &site); err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to add TLE site: " + err.Error(),
@@ -642,9 +583,6 @@ func AddTLESite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		id, _ := result.LastInsertId()
-		site.ID = int(id)
-
 		response := models.Response{
 			Success: true,
 			Message: "TLE site added successfully",
@@ -657,12 +595,12 @@ func AddTLESite(db *sql.DB) http.HandlerFunc {
 }
 
 // UpdateTLESite updates an existing TLE site
-func UpdateTLESite(db *sql.DB) http.HandlerFunc {
+func UpdateTLESite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
 		var site models.TLESite
 		if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
@@ -686,26 +624,22 @@ func UpdateTLESite(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		result, err := db.Exec("UPDATE tle_site SET site = ?, url = ?, description = ? WHERE id = ?",
-			site.Site, site.URL, site.Description, id)
-
-		if err != nil {
+		site.ID = id
+		err := st.TLESites.Update(r.Context(), site)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
-				Message: "Failed to update TLE site: " + err.Error(),
+				Message: "TLE site not found",
 			}
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(response)
 			return
-		}
-
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected == 0 {
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
-				Message: "TLE site not found",
+				Message: "Failed to update TLE site: " + err.Error(),
 			}
-			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
@@ -721,32 +655,28 @@ func UpdateTLESite(db *sql.DB) http.HandlerFunc {
 }
 
 // DeleteTLESite deletes a TLE site
-func DeleteTLESite(db *sql.DB) http.HandlerFunc {
+func DeleteTLESite(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		vars := mux.Vars(r)
-		id := vars["id"]
+		id, _ := strconv.Atoi(vars["id"])
 
-		result, err := db.Exec("DELETE FROM tle_site WHERE id = ?", id)
-
-		if err != nil {
+		err := st.TLESites.Delete(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
-				Message: "Failed to delete TLE site: " + err.Error(),
+				Message: "TLE site not found",
 			}
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(response)
 			return
-		}
-
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected == 0 {
+		} else if err != nil {
 			response := models.Response{
 				Success: false,
-				Message: "TLE site not found",
+				Message: "Failed to delete TLE site: " + err.Error(),
 			}
-			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
@@ -1,21 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 
 	"satplan/auth"
 	"satplan/models"
+	"satplan/store"
 )
 
 // GetAllUsers returns all users
-func GetAllUsers(db *sql.DB) http.HandlerFunc {
+func GetAllUsers(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		rows, err := db.Query("SELECT id, user_name, email FROM sys_user ORDER BY user_name")
+		users, err := st.Users.List(r.Context())
 		if err != nil {
 			response := models.Response{
 				Success: false,
@@ -25,18 +26,6 @@ func GetAllUsers(db *sql.DB) http.HandlerFunc {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-		defer rows.Close()
-
-		users := []models.User{}
-		for rows.Next() {
-			var u models.User
-			if err := rows.Scan(&u.ID, &u.Username, &u.Email); err != nil {
-				log.Printf("Error scanning user: %v", err)
-				continue
-			}
-			users = append(users, u)
-		}
-
 		response := models.Response{
 			Success: true,
 			Message: "Users retrieved successfully",
@@ -48,7 +37,7 @@ func GetAllUsers(db *sql.DB) http.HandlerFunc {
 }
 
 // GetUserInfo returns the current authenticated user's information
-func GetUserInfo(db *sql.DB) http.HandlerFunc {
+func GetUserInfo(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -64,11 +53,9 @@ func GetUserInfo(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var user models.User
-		err := db.QueryRow("SELECT id, user_name, email FROM sys_user WHERE id = ?", userID).
-			Scan(&user.ID, &user.Username, &user.Email)
-
-		if err == sql.ErrNoRows {
+		id, _ := strconv.Atoi(userID)
+		user, err := st.Users.Get(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "User not found",
@@ -97,7 +84,7 @@ func GetUserInfo(db *sql.DB) http.HandlerFunc {
 }
 
 // UpdateUserPassword handles password change requests
-func UpdateUserPassword(db *sql.DB) http.HandlerFunc {
+func UpdateUserPassword(st *store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -148,11 +135,9 @@ func UpdateUserPassword(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Get current password hash from database
-		var currentPasswordHash string
-		err := db.QueryRow("SELECT password FROM sys_user WHERE id = ?", userID).
-			Scan(&currentPasswordHash)
-
-		if err == sql.ErrNoRows {
+		id, _ := strconv.Atoi(userID)
+		currentPasswordHash, err := st.Users.PasswordHash(r.Context(), id)
+		if err == store.ErrNotFound {
 			response := models.Response{
 				Success: false,
 				Message: "User not found",
@@ -194,8 +179,7 @@ func UpdateUserPassword(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Update password in database
-		_, err = db.Exec("UPDATE sys_user SET password = ? WHERE id = ?", hashedPassword, userID)
-		if err != nil {
+		if err := st.Users.SetPasswordHash(r.Context(), id, hashedPassword); err != nil {
 			response := models.Response{
 				Success: false,
 				Message: "Failed to update password: " + err.Error(),
@@ -39,3 +39,11 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- printf "%s-jwt" (include "satplan.fullname" .) -}}
 {{- end -}}
 {{- end -}}
+
+{{- define "satplan.databaseSecretName" -}}
+{{- if .Values.database.existingSecret -}}
+{{- .Values.database.existingSecret -}}
+{{- else -}}
+{{- printf "%s-database" (include "satplan.fullname" .) -}}
+{{- end -}}
+{{- end -}}
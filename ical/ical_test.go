@@ -0,0 +1,50 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestFolding checks that content lines are folded at 75 octets (RFC 5545 3.1) without
+// splitting a UTF-8 sequence, and that unfolding restores them
+func TestFolding(t *testing.T) {
+	summary := strings.Repeat("a", 70) + strings.Repeat("é", 40) + ", done"
+	c := Calendar{Name: "Plan", Events: []Event{{
+		UID:     "strip-1@satplan",
+		Start:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		End:     time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC),
+		Summary: summary,
+	}}}
+
+	var b strings.Builder
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Fatal("lines must end with CRLF")
+	}
+
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 sequence: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded == 0 {
+		t.Error("the summary was not folded")
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "\r\nSUMMARY:"+strings.Repeat("a", 70)+strings.Repeat("é", 40)+`\, done`+"\r\n") {
+		t.Errorf("unfolded summary not found in\n%s", unfolded)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"satplan/auth"
 	"satplan/database"
 	"satplan/handlers"
+	"satplan/store"
 
 	"github.com/gorilla/mux"
 )
@@ -24,16 +25,25 @@ func main() {
 	// Initialize database
 	var err error
 	dbPath := getEnvOrDefault("DB_PATH", dataFile)
-	db, isNewDB, err := database.InitDB(dbPath)
+	databaseURL := os.Getenv("DATABASE_URL")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(databaseURL, dbPath, os.Args[2:]))
+	}
+	autoMigrate := getEnvOrDefault("AUTO_MIGRATE", "true") != "false"
+	db, isNewDB, err := database.InitDB(databaseURL, dbPath, autoMigrate)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer db.Close()
+	st := store.NewSQLite(db)
+	if databaseURL != "" {
+		st = store.NewPostgres(db)
+	}
 
 	// If this is a new database, automatically fetch TLE data
 	if isNewDB {
 		log.Println("New database detected, fetching initial TLE data...")
-		if err := handlers.PerformAutoUpdateTLEs(db); err != nil {
+		if err := handlers.PerformAutoUpdateTLEs(st); err != nil {
 			log.Printf("Warning: Failed to fetch initial TLE data: %v", err)
 			log.Println("You can manually update TLE data later via the admin panel")
 		} else {
@@ -48,45 +58,135 @@ func main() {
 	api := r.PathPrefix("/api/v1").Subrouter()
 
 	// Public routes (no authentication required)
-	api.HandleFunc("/health", handlers.HealthCheck(db)).Methods("GET")
-	api.HandleFunc("/login", auth.LoginHandler(db)).Methods("POST")
-	api.HandleFunc("/sat/tree", handlers.GetSatelliteTree(db)).Methods("GET")
-	api.HandleFunc("/tle/auto-update", handlers.AutoUpdateTLEs(db)).Methods("POST")
+	api.HandleFunc("/health", handlers.HealthCheck(st)).Methods("GET")
+	api.HandleFunc("/login", auth.LoginHandler(st)).Methods("POST")
+	api.HandleFunc("/sat/tree", handlers.GetSatelliteTree(st)).Methods("GET")
+	api.HandleFunc("/group/tree", handlers.GetGroupTree(st)).Methods("GET")
+	api.HandleFunc("/sat/positions", handlers.GetSatellitePositions(st)).Methods("GET")
+	api.HandleFunc("/tle/auto-update", handlers.AutoUpdateTLEs(st)).Methods("POST")
+
+	// Calendar feed routes (authenticated by a per-user feed token, since calendar clients cannot send a JWT)
+	feeds := api.PathPrefix("/feed").Subrouter()
+	feeds.Use(auth.FeedTokenMiddleware(st))
+	feeds.HandleFunc("/plan/{id:[0-9]+}.ics", handlers.GetPlanCalendar(st)).Methods("GET")
 
 	// Protected routes (authentication required)
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(auth.Middleware)
 
 	// Satellite routes
-	protected.HandleFunc("/sat/all", handlers.GetAllSatellites(db)).Methods("GET")
-	protected.HandleFunc("/sat/add", handlers.AddSatellite(db)).Methods("POST")
-	protected.HandleFunc("/sat/{id}", handlers.GetSatelliteById(db)).Methods("GET")
-	protected.HandleFunc("/sat/update/{id}", handlers.UpdateSatellite(db)).Methods("PUT")
-	protected.HandleFunc("/sat/{id}", handlers.DeleteSatellite(db)).Methods("DELETE")
+	protected.HandleFunc("/sat/all", handlers.GetAllSatellites(st)).Methods("GET")
+	protected.HandleFunc("/sat/add", handlers.AddSatellite(st)).Methods("POST")
+	protected.HandleFunc("/sat/import", handlers.ImportSatellites(st)).Methods("POST")
+	protected.HandleFunc("/sat/{id}", handlers.GetSatelliteById(st)).Methods("GET")
+	protected.HandleFunc("/sat/update/{id}", handlers.UpdateSatellite(st)).Methods("PUT")
+	protected.HandleFunc("/sat/{id}", handlers.DeleteSatellite(st)).Methods("DELETE")
+	protected.HandleFunc("/sat/{id}/contacts", handlers.GetSatelliteContacts(st)).Methods("GET")
+	protected.HandleFunc("/sat/{id}/decay", handlers.GetSatelliteDecay(st)).Methods("GET")
+	protected.HandleFunc("/sat/{id}/oem", handlers.GetSatelliteOEM(st)).Methods("GET")
+	protected.HandleFunc("/eclipse", handlers.GetEclipses(st)).Methods("GET")
+
+	// Satellite group routes
+	protected.HandleFunc("/group/all", handlers.GetSatelliteGroups(st)).Methods("GET")
+	protected.HandleFunc("/group/add", handlers.AddSatelliteGroup(st)).Methods("POST")
+	protected.HandleFunc("/group/{id}", handlers.GetSatelliteGroupById(st)).Methods("GET")
+	protected.HandleFunc("/group/update/{id}", handlers.UpdateSatelliteGroup(st)).Methods("PUT")
+	protected.HandleFunc("/group/{id}", handlers.DeleteSatelliteGroup(st)).Methods("DELETE")
+	protected.HandleFunc("/group/{id}/members", handlers.AddGroupMembers(st)).Methods("POST")
+	protected.HandleFunc("/group/{id}/members/{norad_id}", handlers.DeleteGroupMember(st)).Methods("DELETE")
 
 	// TLE routes
-	protected.HandleFunc("/tle/all", handlers.GetTLEs(db)).Methods("GET")
-	protected.HandleFunc("/tle/sat/{norad_id}", handlers.GetTLEBySatellite(db)).Methods("GET")
-	protected.HandleFunc("/tle/{id}", handlers.DeleteTLE(db)).Methods("DELETE")
-	protected.HandleFunc("/sat/tle/update", handlers.UpdateTles(db)).Methods("POST")
-	protected.HandleFunc("/tle/sites", handlers.GetTLESites(db)).Methods("GET")
-	protected.HandleFunc("/tle/sites/add", handlers.AddTLESite(db)).Methods("POST")
-	protected.HandleFunc("/tle/sites/{id}", handlers.GetTLESiteById(db)).Methods("GET")
-	protected.HandleFunc("/tle/sites/update/{id}", handlers.UpdateTLESite(db)).Methods("PUT")
-	protected.HandleFunc("/tle/sites/{id}", handlers.DeleteTLESite(db)).Methods("DELETE")
+	protected.HandleFunc("/tle/all", handlers.GetTLEs(st)).Methods("GET")
+	protected.HandleFunc("/tle/sat/{norad_id}", handlers.GetTLEBySatellite(st)).Methods("GET")
+	protected.HandleFunc("/tle/{id}", handlers.DeleteTLE(st)).Methods("DELETE")
+	protected.HandleFunc("/sat/tle/update", handlers.UpdateTles(st)).Methods("POST")
+	protected.HandleFunc("/tle/sites", handlers.GetTLESites(st)).Methods("GET")
+	protected.HandleFunc("/tle/sites/add", handlers.AddTLESite(st)).Methods("POST")
+	protected.HandleFunc("/tle/sites/{id}", handlers.GetTLESiteById(st)).Methods("GET")
+	protected.HandleFunc("/tle/sites/update/{id}", handlers.UpdateTLESite(st)).Methods("PUT")
+	protected.HandleFunc("/tle/sites/{id}", handlers.DeleteTLESite(st)).Methods("DELETE")
+
+	// Ephemeris routes
+	protected.HandleFunc("/ephemeris/all", handlers.GetEphemerides(st)).Methods("GET")
+	protected.HandleFunc("/ephemeris/import", handlers.ImportEphemeris(st)).Methods("POST")
+	protected.HandleFunc("/ephemeris/{id}", handlers.GetEphemerisById(st)).Methods("GET")
+	protected.HandleFunc("/ephemeris/{id}", handlers.DeleteEphemeris(st)).Methods("DELETE")
 
 	// Sensor routes
-	protected.HandleFunc("/sen/all", handlers.GetAllSensors(db)).Methods("GET")
-	protected.HandleFunc("/sen/add", handlers.AddSensor(db)).Methods("POST")
-	protected.HandleFunc("/sen/bysat", handlers.GetSensorBySatId(db)).Methods("GET")
-	protected.HandleFunc("/sen/{id}", handlers.GetSensorById(db)).Methods("GET")
-	protected.HandleFunc("/sen/update/{id}", handlers.UpdateSensor(db)).Methods("PUT")
-	protected.HandleFunc("/sen/{id}", handlers.DeleteSensor(db)).Methods("DELETE")
+	protected.HandleFunc("/sen/all", handlers.GetAllSensors(st)).Methods("GET")
+	protected.HandleFunc("/sen/add", handlers.AddSensor(st)).Methods("POST")
+	protected.HandleFunc("/sen/bysat", handlers.GetSensorBySatId(st)).Methods("GET")
+	protected.HandleFunc("/sen/search", handlers.SearchSensors(st)).Methods("GET")
+	protected.HandleFunc("/sen/{id}", handlers.GetSensorById(st)).Methods("GET")
+	protected.HandleFunc("/sen/update/{id}", handlers.UpdateSensor(st)).Methods("PUT")
+	protected.HandleFunc("/sen/{id}", handlers.DeleteSensor(st)).Methods("DELETE")
+	protected.HandleFunc("/sen/{id}/modes", handlers.GetSensorModes(st)).Methods("GET")
+	protected.HandleFunc("/sen/modes/add", handlers.AddSensorMode(st)).Methods("POST")
+	protected.HandleFunc("/sen/modes/{id}", handlers.GetSensorModeById(st)).Methods("GET")
+	protected.HandleFunc("/sen/modes/update/{id}", handlers.UpdateSensorMode(st)).Methods("PUT")
+	protected.HandleFunc("/sen/modes/{id}", handlers.DeleteSensorMode(st)).Methods("DELETE")
+
+	// Plan routes
+	protected.HandleFunc("/plan/all", handlers.GetPlans(st)).Methods("GET")
+	protected.HandleFunc("/plan/add", handlers.AddPlan(st)).Methods("POST")
+	protected.HandleFunc("/plan/{id}", handlers.GetPlanById(st)).Methods("GET")
+	protected.HandleFunc("/plan/update/{id}", handlers.UpdatePlan(st)).Methods("PUT")
+	protected.HandleFunc("/plan/{id}", handlers.DeletePlan(st)).Methods("DELETE")
+	protected.HandleFunc("/plan/{id}/strips", handlers.GetPlanStrips(st)).Methods("GET")
+	protected.HandleFunc("/plan/{id}/czml", handlers.GetPlanCZML(st)).Methods("GET")
+	protected.HandleFunc("/plan/{id}/autoselect", handlers.AutoSelectPlanStrips(st)).Methods("POST")
+	protected.HandleFunc("/plan/{id}/revisit", handlers.GetPlanRevisit(st)).Methods("GET")
+	protected.HandleFunc("/plan/{id}/stereo", handlers.GetPlanStereo(st)).Methods("POST")
+	protected.HandleFunc("/plan/{id}/footprints", handlers.SavePlanFootprints(st)).Methods("POST")
+	protected.HandleFunc("/footprint/search", handlers.SearchFootprints(st)).Methods("GET")
+
+	// Tasking request routes
+	protected.HandleFunc("/request/all", handlers.GetTaskingRequests(st)).Methods("GET")
+	protected.HandleFunc("/request/add", handlers.AddTaskingRequest(st)).Methods("POST")
+	protected.HandleFunc("/request/deconflict", handlers.DeconflictRequests(st)).Methods("POST")
+	protected.HandleFunc("/request/{id}", handlers.GetTaskingRequestById(st)).Methods("GET")
+	protected.HandleFunc("/request/update/{id}", handlers.UpdateTaskingRequest(st)).Methods("PUT")
+	protected.HandleFunc("/request/{id}", handlers.DeleteTaskingRequest(st)).Methods("DELETE")
+	protected.HandleFunc("/request/{id}/history", handlers.GetTaskingRequestHistory(st)).Methods("GET")
+	protected.HandleFunc("/request/{id}/submit", handlers.TransitionTaskingRequest(st, "submit")).Methods("POST")
+	protected.HandleFunc("/request/{id}/approve", handlers.TransitionTaskingRequest(st, "approve")).Methods("POST")
+	protected.HandleFunc("/request/{id}/schedule", handlers.TransitionTaskingRequest(st, "schedule")).Methods("POST")
+	protected.HandleFunc("/request/{id}/acquire", handlers.TransitionTaskingRequest(st, "acquire")).Methods("POST")
+	protected.HandleFunc("/request/{id}/fail", handlers.TransitionTaskingRequest(st, "fail")).Methods("POST")
+
+	// Ground station routes
+	protected.HandleFunc("/station/all", handlers.GetGroundStations(st)).Methods("GET")
+	protected.HandleFunc("/station/add", handlers.AddGroundStation(st)).Methods("POST")
+	protected.HandleFunc("/station/{id}", handlers.GetGroundStationById(st)).Methods("GET")
+	protected.HandleFunc("/station/update/{id}", handlers.UpdateGroundStation(st)).Methods("PUT")
+	protected.HandleFunc("/station/{id}", handlers.DeleteGroundStation(st)).Methods("DELETE")
+
+	// Point target routes
+	protected.HandleFunc("/target/all", handlers.GetPointTargets(st)).Methods("GET")
+	protected.HandleFunc("/target/add", handlers.AddPointTarget(st)).Methods("POST")
+	protected.HandleFunc("/target/import", handlers.ImportPointTargets(st)).Methods("POST")
+	protected.HandleFunc("/target/{id}", handlers.GetPointTargetById(st)).Methods("GET")
+	protected.HandleFunc("/target/update/{id}", handlers.UpdatePointTarget(st)).Methods("PUT")
+	protected.HandleFunc("/target/{id}", handlers.DeletePointTarget(st)).Methods("DELETE")
+	protected.HandleFunc("/access", handlers.GetAccessWindows(st)).Methods("POST")
+
+	// Conjunction routes
+	protected.HandleFunc("/conjunction/screen", handlers.ScreenConjunctions(st)).Methods("POST")
+	protected.HandleFunc("/conjunction/runs", handlers.GetScreeningRuns(st)).Methods("GET")
+	protected.HandleFunc("/conjunction/runs/{id}", handlers.GetScreeningRunById(st)).Methods("GET")
+	protected.HandleFunc("/conjunction/runs/{id}", handlers.DeleteScreeningRun(st)).Methods("DELETE")
+	protected.HandleFunc("/conjunction/all", handlers.GetConjunctions(st)).Methods("GET")
+
+	// Scheduling routes
+	protected.HandleFunc("/schedule", handlers.SchedulePlans(st)).Methods("POST")
 
 	// User routes
-	protected.HandleFunc("/user/all", handlers.GetAllUsers(db)).Methods("GET")
-	protected.HandleFunc("/user/me", handlers.GetUserInfo(db)).Methods("GET")
-	protected.HandleFunc("/user/password", handlers.UpdateUserPassword(db)).Methods("PUT")
+	protected.HandleFunc("/user/all", handlers.GetAllUsers(st)).Methods("GET")
+	protected.HandleFunc("/user/me", handlers.GetUserInfo(st)).Methods("GET")
+	protected.HandleFunc("/user/password", handlers.UpdateUserPassword(st)).Methods("PUT")
+	protected.HandleFunc("/user/feed-tokens", handlers.GetFeedTokens(st)).Methods("GET")
+	protected.HandleFunc("/user/feed-tokens/add", handlers.AddFeedToken(st)).Methods("POST")
+	protected.HandleFunc("/user/feed-tokens/{id}", handlers.DeleteFeedToken(st)).Methods("DELETE")
 
 	// Admin page route
 	r.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"satplan/database"
+)
+
+const migrateUsage = `usage: satplan migrate <command>
+
+commands:
+  status     show the schema version and every migration
+  up         apply all pending migrations
+  down [n]   revert the last n migrations (default 1)
+  to <v>     migrate up or down to version v
+  seed       load the initial data into an empty database`
+
+// runMigrate runs the migrate subcommand against the PostgreSQL database at databaseURL,
+// or the SQLite database at dbPath when it is empty, and returns the process exit code
+func runMigrate(databaseURL, dbPath string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := database.Connect(databaseURL, dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to open database:", err)
+		return 1
+	}
+	defer db.Close()
+	if args[0] != "status" {
+		unlock, err := database.LockSchema(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer unlock()
+	}
+
+	// A database created from init.sql gets its version recorded first, so that down
+	// and to count from it
+	if args[0] != "status" {
+		if err := database.Baseline(db); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	version, err := database.Version(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read schema version:", err)
+		return 1
+	}
+	latest, err := database.LatestVersion(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	target := -1
+	switch {
+	case args[0] == "status" && len(args) == 1:
+		status, err := database.Status(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		name := dbPath
+		if u, err := url.Parse(databaseURL); err == nil && databaseURL != "" {
+			name = u.Redacted()
+		}
+		fmt.Printf("%s: schema version %d of %d\n", name, version, latest)
+		for _, s := range status {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = "applied " + s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %04d %-30s %s\n", s.Version, s.Name, applied)
+		}
+		return 0
+	case args[0] == "up" && len(args) == 1:
+		target = latest
+	case args[0] == "down" && len(args) <= 2:
+		n := 1
+		if len(args) == 2 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "down needs a positive number of migrations")
+				return 2
+			}
+		}
+		target = max(version-n, 0)
+	case args[0] == "to" && len(args) == 2:
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, "to needs a version number")
+			return 2
+		}
+	case args[0] == "seed" && len(args) == 1:
+		if version != latest {
+			fmt.Fprintf(os.Stderr, "The seed data needs schema version %d, run migrate up first\n", latest)
+			return 1
+		}
+		if err := database.Seed(db); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load seed data:", err)
+			return 1
+		}
+		fmt.Println("Seed data loaded")
+		return 0
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err := database.Migrate(db, target); err != nil {
+		fmt.Fprintln(os.Stderr, "Migration failed:", err)
+		return 1
+	}
+	if version, err = database.Version(db); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Schema version %d of %d\n", version, latest)
+	return 0
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -11,14 +13,55 @@ type Satellite struct {
 	NoardID  string `json:"noard_id"`
 	Name     string `json:"name"`
 	HexColor string `json:"hex_color"`
+	// Agility; zero means unknown and re-pointing is then treated as instantaneous
+	MaxRollRate       float64 `json:"max_roll_rate"`      // deg/s
+	MaxPitchRate      float64 `json:"max_pitch_rate"`     // deg/s
+	RollAcceleration  float64 `json:"roll_acceleration"`  // deg/s², zero for rate-limited slews
+	PitchAcceleration float64 `json:"pitch_acceleration"` // deg/s²
+	SettleTime        float64 `json:"settle_time"`        // seconds after each slew
+	// Storage; a zero capacity means the recorder is not modelled
+	RecorderCapacity float64 `json:"recorder_capacity"` // Gbit
+	DownlinkRate     float64 `json:"downlink_rate"`     // Mbit/s
+	// Catalogue metadata
+	IntlDesignator string `json:"intl_designator"` // COSPAR ID, e.g. 2008-041B
+	Operator       string `json:"operator"`
+	Country        string `json:"country"`
+	LaunchDate     string `json:"launch_date"` // YYYY-MM-DD
+	Status         string `json:"status"`      // active, degraded or retired; only active satellites are planned by default
+	OrbitClass     string `json:"orbit_class"` // LEO, MEO, GEO or HEO
+	Notes          string `json:"notes"`
+	// Decay indicators from the TLE history; only filled in satellite lists
+	Decay *SatelliteDecay `json:"decay,omitempty"`
+}
+
+// Satellite mission statuses
+const (
+	SatelliteActive   = "active"
+	SatelliteDegraded = "degraded"
+	SatelliteRetired  = "retired"
+)
+
+// Orbit classes
+const (
+	OrbitLEO = "LEO"
+	OrbitMEO = "MEO"
+	OrbitGEO = "GEO"
+	OrbitHEO = "HEO"
+)
+
+// SatelliteImportResult reports the outcome of a catalogue import
+type SatelliteImportResult struct {
+	Updated  int      `json:"updated"`
+	Created  int      `json:"created"`
+	NotFound []string `json:"not_found"` // NORAD IDs skipped because the satellite does not exist
 }
 
 // Sensor represents a satellite sensor
 type Sensor struct {
 	ID             int     `json:"id"`
 	SatID          int     `json:"sat_id"`
-	SatNoardID     string  `json:"sat_noard_id"`
-	SatName        string  `json:"sat_name"`
+	SatNoardID     string  `json:"sat_noard_id"` // read from the satellite
+	SatName        string  `json:"sat_name"`     // read from the satellite
 	Name           string  `json:"name"`
 	Resolution     float64 `json:"resolution"`
 	Width          float64 `json:"width"`
@@ -27,15 +70,68 @@ type Sensor struct {
 	ObserveAngle   float64 `json:"observe_angle"`
 	HexColor       string  `json:"hex_color"`
 	InitAngle      float64 `json:"init_angle"`
+	DataRate       float64 `json:"data_rate"` // Mbit/s, 0 to derive it from resolution and width
+	// Duty-cycle limits; zero means unlimited
+	MaxOnTime       float64 `json:"max_on_time"`      // seconds of acquisition per orbit
+	MaxAcquisitions int     `json:"max_acquisitions"` // acquisitions per orbit
+	MinGap          float64 `json:"min_gap"`          // seconds between acquisitions
+	EclipseAware    bool    `json:"eclipse_aware"`    // scale max_on_time by the orbit's sunlit fraction
+	MaxPitchAngle   float64 `json:"max_pitch_angle"`  // degrees fore/aft, 0 if the sensor cannot look along track
+	// SensorType is optical or sar. A SAR sensor images between its near and far incidence
+	// angles (degrees) on its look side (right, left or both) instead of using the optical
+	// side and observe angles.
+	SensorType     string  `json:"sensor_type"`
+	NearIncidence  float64 `json:"near_incidence"`
+	FarIncidence   float64 `json:"far_incidence"`
+	LookSide       string  `json:"look_side"`
+	OrbitDirection string  `json:"orbit_direction"` // ascending or descending to image on those passes only, "" for both
+}
+
+// SensorMode is an imaging mode of a sensor with its own resolution, swath and side-angle
+// limits, which replace the sensor's when a plan selects the mode
+type SensorMode struct {
+	ID             int     `json:"id"`
+	SensorID       int     `json:"sensor_id"`
+	Name           string  `json:"name"`
+	Type           string  `json:"type"` // pan, multispectral, hyperspectral, thermal, sar_stripmap, sar_spotlight or sar_scansar
+	Resolution     float64 `json:"resolution"`
+	Width          float64 `json:"width"` // km, 0 to keep the sensor's
+	RightSideAngle float64 `json:"right_side_angle"`
+	LeftSideAngle  float64 `json:"left_side_angle"`
+	ObserveAngle   float64 `json:"observe_angle"`
+	DataRate       float64 `json:"data_rate"`      // Mbit/s, 0 to keep the sensor's
+	NearIncidence  float64 `json:"near_incidence"` // degrees, for SAR modes
+	FarIncidence   float64 `json:"far_incidence"`
+	Bands          []Band  `json:"bands"`
+}
+
+// Band is a spectral band of a sensor mode
+type Band struct {
+	ID               int     `json:"id"`
+	ModeID           int     `json:"mode_id"`
+	Name             string  `json:"name"`
+	CenterWavelength float64 `json:"center_wavelength"` // nm
+	Bandwidth        float64 `json:"bandwidth"`         // nm
+	GSD              float64 `json:"gsd"`               // metres, 0 for the mode's resolution
+}
+
+// SensorMatch is a sensor mode meeting a capability search, with the bands that met it
+type SensorMatch struct {
+	Sensor Sensor      `json:"sensor"`
+	Mode   *SensorMode `json:"mode,omitempty"` // nil when the sensor has no modes
+	Bands  []Band      `json:"bands,omitempty"`
+	GSD    float64     `json:"gsd"` // the coarsest GSD among the matched bands, or the resolution
 }
 
 // TreeNode represents a node in the satellite tree
 type TreeNode struct {
 	ID       int        `json:"id"`
-	Type     string     `json:"type"` // "root", "satellite", "sensor"
+	Type     string     `json:"type"` // "root", "group", "satellite", "sensor"
 	Name     string     `json:"name"`
 	HexColor string     `json:"hex_color,omitempty"`
 	Children []TreeNode `json:"children,omitempty"`
+	// Group-specific fields
+	Kind string `json:"kind,omitempty"`
 	// Satellite-specific fields
 	SatNoradID string `json:"sat_norad_id,omitempty"`
 	TLE1       string `json:"tle1,omitempty"`
@@ -56,9 +152,33 @@ type SatelliteWithSensors struct {
 	Sensors []Sensor `json:"sensors"`
 }
 
+// Satellite group kinds
+const (
+	GroupConstellation = "constellation"
+	GroupOperator      = "operator"
+	GroupMission       = "mission"
+)
+
+// SatelliteGroup is a named set of satellites such as a constellation, the fleet of an
+// operator or a mission. Groups nest: a group also contains the satellites of its subgroups.
+type SatelliteGroup struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	ParentID    int      `json:"parent_id"` // 0 for a top-level group
+	Description string   `json:"description"`
+	SatNoardIDs []string `json:"sat_noard_ids"` // direct members
+}
+
+// GroupMembersRequest adds satellites to a group
+type GroupMembersRequest struct {
+	SatNoardIDs []string `json:"sat_noard_ids"`
+}
+
 // TLE represents Two-Line Element orbital data
 type TLE struct {
 	ID         int    `json:"id"`
+	SatID      int    `json:"sat_id"`
 	SatNoardID string `json:"sat_noard_id"`
 	Time       int64  `json:"time"`
 	Line1      string `json:"line1"`
@@ -73,6 +193,77 @@ type TLESite struct {
 	Description string `json:"description"`
 }
 
+// Plan is a saved planning request: a target area, a time window and the sensors or
+// satellite groups to use
+type Plan struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Name      string       `json:"name"`
+	MinLon    float64      `json:"min_lon"`
+	MaxLon    float64      `json:"max_lon"`
+	MinLat    float64      `json:"min_lat"`
+	MaxLat    float64      `json:"max_lat"`
+	StartTime int64        `json:"start_time"`
+	EndTime   int64        `json:"end_time"`
+	CreatedAt int64        `json:"created_at"`
+	Sensors   []PlanSensor `json:"sensors"`
+	Groups    []PlanGroup  `json:"groups"`
+	// IncludeInactive also plans the sensors of degraded and retired satellites
+	IncludeInactive bool `json:"include_inactive"`
+}
+
+// PlanSensor is a sensor selected for a plan with its commanded side angle
+type PlanSensor struct {
+	SensorID  int     `json:"sensor_id"`
+	SideAngle float64 `json:"side_angle"`
+	ModeID    int     `json:"mode_id,omitempty"` // imaging mode to plan with, 0 for the sensor's own geometry
+}
+
+// PlanGroup selects every sensor of a satellite group for a plan, all at the same side
+// angle. Membership is resolved when the plan is computed.
+type PlanGroup struct {
+	GroupID   int     `json:"group_id"`
+	SideAngle float64 `json:"side_angle"`
+}
+
+// Strip is the ground area swept by a sensor over a target area during one pass
+type Strip struct {
+	SatNoardID      string      `json:"sat_noard_id"`
+	SatName         string      `json:"sat_name"`
+	SensorID        int         `json:"sensor_id"`
+	SensorName      string      `json:"sensor_name"`
+	HexColor        string      `json:"hex_color"`
+	Resolution      float64     `json:"resolution"`
+	SideAngle       float64     `json:"side_angle"`
+	OffNadirAngle   float64     `json:"off_nadir_angle"`
+	StartTimestamp  int64       `json:"start_timestamp"`
+	StopTimestamp   int64       `json:"stop_timestamp"`
+	SunElevation    float64     `json:"sun_elevation"` // at the strip's centre time and location
+	SunAzimuth      float64     `json:"sun_azimuth"`
+	GlintAngle      float64     `json:"glint_angle"`     // angle between the view and the specular reflection of the sun
+	GSDCrossTrack   float64     `json:"gsd_cross_track"` // metres at the boresight
+	GSDAlongTrack   float64     `json:"gsd_along_track"`
+	IncidenceNear   float64     `json:"incidence_near"` // degrees at the swath edges
+	IncidenceFar    float64     `json:"incidence_far"`
+	LookSide        string      `json:"look_side,omitempty"`
+	OrbitDirection  string      `json:"orbit_direction"`
+	SatIllumination float64     `json:"sat_illumination"`    // visible fraction of the sun from the satellite, 0 in eclipse
+	DataRate        float64     `json:"data_rate"`           // Mbit/s recorded by the sensor in its mode
+	Violation       string      `json:"violation,omitempty"` // duty-cycle limit broken together with earlier strips
+	Coordinates     [][]float64 `json:"coordinates"`
+}
+
+// Footprint is the stored ground area of a strip computed for a plan
+type Footprint struct {
+	ID             int         `json:"id"`
+	PlanID         int         `json:"plan_id"`
+	SensorID       int         `json:"sensor_id"`
+	SatNoardID     string      `json:"sat_noard_id"`
+	StartTimestamp int64       `json:"start_timestamp"`
+	StopTimestamp  int64       `json:"stop_timestamp"`
+	Coordinates    [][]float64 `json:"coordinates"` // closed [lon, lat] ring
+}
+
 // User represents a system user
 type User struct {
 	ID       int    `json:"id"`
@@ -81,6 +272,482 @@ type User struct {
 	Email    string `json:"email"`
 }
 
+// AutoSelectRequest configures the server-side greedy strip selection of a plan
+type AutoSelectRequest struct {
+	Objective string             `json:"objective"` // "max-coverage", "min-time" or "min-strips"
+	Method    string             `json:"method"`    // "grid" (default) or "exact"
+	GridSize  int                `json:"grid_size"`
+	Weights   map[string]float64 `json:"weights"` // per-satellite cost weights keyed by NORAD ID
+}
+
+// AutoSelectResponse contains the strips chosen by the auto-select and their coverage
+type AutoSelectResponse struct {
+	Objective     string  `json:"objective"`
+	Method        string  `json:"method"`
+	GridSize      int     `json:"grid_size,omitempty"`
+	Candidates    int     `json:"candidates"`
+	Coverage      float64 `json:"coverage"` // percent of the target area
+	TotalDuration int64   `json:"total_duration"`
+	Strips        []Strip `json:"strips"`
+}
+
+// PointTarget is a point on the ground to be imaged, such as a port or a dam
+type PointTarget struct {
+	ID   int     `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Alt  float64 `json:"alt"` // metres
+}
+
+// AccessRequest asks for the access windows of point targets. Targets may be given inline,
+// by ID, or both.
+type AccessRequest struct {
+	Targets      []PointTarget `json:"targets"`
+	TargetIDs    []int         `json:"target_ids"`
+	StartTime    int64         `json:"start_time"`
+	EndTime      int64         `json:"end_time"`
+	SensorIDs    []int         `json:"sensor_ids"`    // empty for all sensors
+	MaxOffNadir  float64       `json:"max_off_nadir"` // degrees, 0 for the side angle limits only
+	MinElevation float64       `json:"min_elevation"` // degrees of the satellite above the target's horizon
+}
+
+// AccessWindow is a window during which a sensor can image a point target
+type AccessWindow struct {
+	TargetID         int     `json:"target_id"`
+	TargetName       string  `json:"target_name"`
+	Start            int64   `json:"start"`
+	End              int64   `json:"end"`
+	Roll             float64 `json:"roll"`       // roll to the target, degrees, positive to the right
+	SideAngle        float64 `json:"side_angle"` // side angle to command
+	MinOffNadirAngle float64 `json:"min_off_nadir_angle"`
+	MinOffNadirTime  int64   `json:"min_off_nadir_time"`
+	Elevation        float64 `json:"elevation"` // satellite elevation seen from the target
+	GSDCrossTrack    float64 `json:"gsd_cross_track"`
+	GSDAlongTrack    float64 `json:"gsd_along_track"`
+	SunElevation     float64 `json:"sun_elevation"`
+}
+
+// SensorAccess lists the access windows of one sensor
+type SensorAccess struct {
+	SatNoardID string         `json:"sat_noard_id"`
+	SatName    string         `json:"sat_name"`
+	SensorID   int            `json:"sensor_id"`
+	SensorName string         `json:"sensor_name"`
+	Windows    []AccessWindow `json:"windows"`
+}
+
+// AccessResponse contains the access windows per satellite and sensor
+type AccessResponse struct {
+	Targets []PointTarget  `json:"targets"`
+	Sensors []SensorAccess `json:"sensors"`
+}
+
+// StereoRequest configures the stereo search over a plan's target area
+type StereoRequest struct {
+	Images         int     `json:"images"`          // 2 for pairs (default), 3 for triplets
+	Mode           string  `json:"mode"`            // "along_track", "cross_pass" or "both" (default)
+	MinConvergence float64 `json:"min_convergence"` // degrees
+	MaxConvergence float64 `json:"max_convergence"` // degrees
+	MinBH          float64 `json:"min_bh"`          // base-to-height ratio
+	MaxBH          float64 `json:"max_bh"`
+	MaxTimeSpan    int64   `json:"max_time_span"` // seconds between the first and last look, 0 for any
+	Limit          int     `json:"limit"`         // number of candidates returned, default 20
+}
+
+// StereoLook is one acquisition of a stereo candidate
+type StereoLook struct {
+	SatNoardID    string  `json:"sat_noard_id"`
+	SatName       string  `json:"sat_name"`
+	SensorID      int     `json:"sensor_id"`
+	SensorName    string  `json:"sensor_name"`
+	Time          int64   `json:"time"`
+	SideAngle     float64 `json:"side_angle"`
+	PitchAngle    float64 `json:"pitch_angle"` // positive forward
+	OffNadirAngle float64 `json:"off_nadir_angle"`
+	ViewAzimuth   float64 `json:"view_azimuth"`   // azimuth of the satellite seen from the target
+	ViewElevation float64 `json:"view_elevation"` // elevation of the satellite seen from the target
+}
+
+// StereoCandidate is a ranked pair or triplet of looks at the target
+type StereoCandidate struct {
+	Type        string       `json:"type"` // "along_track" or "cross_pass"
+	Looks       []StereoLook `json:"looks"`
+	Convergence float64      `json:"convergence"` // degrees between the outermost looks
+	BH          float64      `json:"bh"`          // base-to-height ratio of the outermost looks
+	TimeSpan    int64        `json:"time_span"`   // seconds
+	MaxOffNadir float64      `json:"max_off_nadir"`
+	Score       float64      `json:"score"` // 0 to 1, higher is better
+}
+
+// StereoResponse lists the stereo candidates found for a plan
+type StereoResponse struct {
+	Target     [2]float64        `json:"target"` // [lon, lat] the looks are computed for
+	Looks      int               `json:"looks"`  // looks considered
+	Candidates []StereoCandidate `json:"candidates"`
+}
+
+// RevisitCell holds the revisit statistics of one cell of the analysis grid. Gaps are in
+// seconds and are null when the cell is imaged fewer than two times.
+type RevisitCell struct {
+	Row         int      `json:"row"`
+	Col         int      `json:"col"`
+	Lon         float64  `json:"lon"` // cell centre
+	Lat         float64  `json:"lat"`
+	Accesses    int      `json:"accesses"`
+	FirstAccess int64    `json:"first_access,omitempty"`
+	MinGap      *float64 `json:"min_gap"`
+	MeanGap     *float64 `json:"mean_gap"`
+	MaxGap      *float64 `json:"max_gap"`
+}
+
+// CoverageSample is the cumulative coverage of the target area at a point in time
+type CoverageSample struct {
+	Time     int64   `json:"time"`
+	Coverage float64 `json:"coverage"` // percent of grid cells imaged at least once
+}
+
+// RevisitAnalysis contains the revisit and coverage statistics of a plan over its target area
+type RevisitAnalysis struct {
+	StartTime          int64            `json:"start_time"`
+	EndTime            int64            `json:"end_time"`
+	GridSize           int              `json:"grid_size"`
+	CellWidth          float64          `json:"cell_width"`  // degrees of longitude
+	CellHeight         float64          `json:"cell_height"` // degrees of latitude
+	MergeGap           int64            `json:"merge_gap"`   // seconds
+	Strips             int              `json:"strips"`
+	Coverage           float64          `json:"coverage"`
+	TimeToFullCoverage *int64           `json:"time_to_full_coverage"` // seconds from start_time, null if never reached
+	MinGap             *float64         `json:"min_gap"`
+	MeanGap            *float64         `json:"mean_gap"`
+	MaxGap             *float64         `json:"max_gap"`
+	CoverageOverTime   []CoverageSample `json:"coverage_over_time"`
+	Cells              []RevisitCell    `json:"cells"`
+}
+
+// ScheduleRequest lists the plans whose target areas compete for acquisitions, in
+// priority order
+type ScheduleRequest struct {
+	PlanIDs []int `json:"plan_ids"`
+}
+
+// ScheduledAcquisition is a strip placed on a satellite timeline
+type ScheduledAcquisition struct {
+	PlanID       int     `json:"plan_id"`
+	PlanName     string  `json:"plan_name"`
+	SlewTime     float64 `json:"slew_time"`     // seconds to re-point from the previous acquisition
+	DataVolume   float64 `json:"data_volume"`   // Gbit recorded, 0 if storage is not modelled
+	RecorderFill float64 `json:"recorder_fill"` // Gbit on the recorder after the acquisition
+	Strip        Strip   `json:"strip"`
+}
+
+// SatelliteSchedule is the conflict-free acquisition timeline of one satellite
+type SatelliteSchedule struct {
+	SatNoardID   string                 `json:"sat_noard_id"`
+	SatName      string                 `json:"sat_name"`
+	Acquisitions []ScheduledAcquisition `json:"acquisitions"`
+	Downlinks    []Downlink             `json:"downlinks,omitempty"`
+}
+
+// UnscheduledPlan explains why a plan could not be fitted into the schedule
+type UnscheduledPlan struct {
+	PlanID     int    `json:"plan_id"`
+	PlanName   string `json:"plan_name"`
+	Candidates int    `json:"candidates"`
+	Reason     string `json:"reason"`
+}
+
+// ScheduleResponse contains the per-satellite timelines and the plans left out
+type ScheduleResponse struct {
+	Satellites  []SatelliteSchedule `json:"satellites"`
+	Unscheduled []UnscheduledPlan   `json:"unscheduled"`
+}
+
+// GroundStation is a receiving station for satellite downlinks
+type GroundStation struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+	Alt          float64 `json:"alt"`           // metres
+	MinElevation float64 `json:"min_elevation"` // degrees above the horizon
+}
+
+// Contact is a window during which a satellite is visible from a ground station
+type Contact struct {
+	StationID    int     `json:"station_id"`
+	StationName  string  `json:"station_name"`
+	Start        int64   `json:"start"`
+	End          int64   `json:"end"`
+	MaxElevation float64 `json:"max_elevation"`
+}
+
+// Eclipse is a passage of a satellite through the earth's shadow. The umbra times are
+// omitted when only the penumbra was crossed.
+type Eclipse struct {
+	PenumbraStart int64   `json:"penumbra_start"`
+	UmbraStart    int64   `json:"umbra_start,omitempty"`
+	UmbraEnd      int64   `json:"umbra_end,omitempty"`
+	PenumbraEnd   int64   `json:"penumbra_end"`
+	Duration      float64 `json:"duration"` // seconds in the shadow
+}
+
+// OrbitIllumination is the illumination of one orbit between ascending node crossings
+type OrbitIllumination struct {
+	Start          int64   `json:"start"`
+	End            int64   `json:"end"`
+	Umbra          float64 `json:"umbra"`    // seconds
+	Penumbra       float64 `json:"penumbra"` // seconds
+	SunlitFraction float64 `json:"sunlit_fraction"`
+}
+
+// SatelliteEclipses lists the eclipses and orbit illumination of one satellite
+type SatelliteEclipses struct {
+	SatID      int                 `json:"sat_id"`
+	SatNoardID string              `json:"sat_noard_id"`
+	SatName    string              `json:"sat_name"`
+	Error      string              `json:"error,omitempty"`
+	Eclipses   []Eclipse           `json:"eclipses"`
+	Orbits     []OrbitIllumination `json:"orbits"`
+}
+
+// Shadow states of a satellite
+const (
+	ShadowSunlit   = "sunlit"
+	ShadowPenumbra = "penumbra"
+	ShadowUmbra    = "umbra"
+)
+
+// SatellitePosition is the sub-satellite point of a satellite at one time and whether it
+// is in the earth's shadow
+type SatellitePosition struct {
+	SatNoardID   string  `json:"sat_noard_id"`
+	SatName      string  `json:"sat_name"`
+	HexColor     string  `json:"hex_color"`
+	Time         int64   `json:"time"`
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+	Alt          float64 `json:"alt"`          // km
+	Illumination float64 `json:"illumination"` // visible fraction of the sun, 0 in the umbra
+	Shadow       string  `json:"shadow"`       // sunlit, penumbra or umbra
+}
+
+// Decay warnings raised when a satellite crosses a decay threshold
+const (
+	DecayLowPerigee    = "low_perigee"
+	DecayFast          = "fast_decay"
+	DecayShortLifetime = "short_lifetime"
+)
+
+// SatelliteDecay holds the decay indicators of a satellite's orbit
+type SatelliteDecay struct {
+	Epoch          int64   `json:"epoch"`            // latest TLE epoch, unix seconds
+	MeanMotion     float64 `json:"mean_motion"`      // rev/day
+	MeanMotionRate float64 `json:"mean_motion_rate"` // rev/day²
+	Altitude       float64 `json:"altitude"`         // mean altitude, km
+	Perigee        float64 `json:"perigee"`          // km
+	Apogee         float64 `json:"apogee"`           // km
+	AltitudeRate   float64 `json:"altitude_rate"`    // km/day
+	BStar          float64 `json:"bstar"`
+	// Source is "history" when the rates were fitted over several TLEs, "ndot" when
+	// they come from the mean motion derivative of the latest one
+	Source   string   `json:"source"`
+	Samples  int      `json:"samples"`
+	Lifetime float64  `json:"lifetime_days,omitempty"` // rough estimate, omitted when not decaying
+	Warnings []string `json:"warnings"`
+	Flagged  bool     `json:"flagged"`
+}
+
+// DecaySample is the orbit described by one TLE of a satellite's history
+type DecaySample struct {
+	TLEID      int     `json:"tle_id"`
+	Epoch      int64   `json:"epoch"`
+	MeanMotion float64 `json:"mean_motion"`
+	Altitude   float64 `json:"altitude"`
+	NDot       float64 `json:"ndot"` // half the first derivative of mean motion, rev/day²
+	BStar      float64 `json:"bstar"`
+}
+
+// SatelliteDecayHistory is a satellite's decay indicators with the TLE history behind them
+type SatelliteDecayHistory struct {
+	SatID      int            `json:"sat_id"`
+	SatNoardID string         `json:"sat_noard_id"`
+	SatName    string         `json:"sat_name"`
+	Decay      SatelliteDecay `json:"decay"`
+	History    []DecaySample  `json:"history"`
+}
+
+// Conjunction screening run states
+const (
+	ScreeningRunning = "running"
+	ScreeningDone    = "done"
+	ScreeningFailed  = "failed"
+)
+
+// ScreeningRequest starts a close-approach screening
+type ScreeningRequest struct {
+	Days      float64 `json:"days"`      // default 3, at most 7
+	Threshold float64 `json:"threshold"` // miss distance in km, default 5
+	SiteID    int     `json:"site_id"`   // tle_site to screen against as well, 0 for our satellites only
+}
+
+// ScreeningRun is one run of the close-approach screening job
+type ScreeningRun struct {
+	ID           int     `json:"id"`
+	Status       string  `json:"status"`
+	StartedAt    int64   `json:"started_at"`
+	FinishedAt   int64   `json:"finished_at,omitempty"`
+	Start        int64   `json:"start"`
+	End          int64   `json:"end"`
+	Threshold    float64 `json:"threshold"`
+	SiteID       int     `json:"site_id,omitempty"`
+	Objects      int     `json:"objects"`
+	Pairs        int     `json:"pairs"`
+	Conjunctions int     `json:"conjunctions"`
+	Message      string  `json:"message,omitempty"`
+}
+
+// Conjunction is a close approach found by a screening run. The miss components are
+// those of the other object relative to our satellite in its radial, in-track and
+// cross-track frame.
+type Conjunction struct {
+	ID               int     `json:"id"`
+	RunID            int     `json:"run_id"`
+	SatNoardID       string  `json:"sat_noard_id"`
+	SatName          string  `json:"sat_name"`
+	OtherNoardID     string  `json:"other_noard_id"`
+	OtherName        string  `json:"other_name"`
+	TCA              int64   `json:"tca"`
+	MissDistance     float64 `json:"miss_distance"`     // km
+	RelativeVelocity float64 `json:"relative_velocity"` // km/s
+	Radial           float64 `json:"radial"`
+	InTrack          float64 `json:"in_track"`
+	CrossTrack       float64 `json:"cross_track"`
+}
+
+// Ephemeris is a stored precise ephemeris of a satellite. Its states are kept in TEME
+// whatever the frame of the file it was imported from.
+type Ephemeris struct {
+	ID            int    `json:"id"`
+	SatNoardID    string `json:"sat_noard_id"`
+	Format        string `json:"format"` // oem or sp3
+	ObjectName    string `json:"object_name"`
+	Frame         string `json:"frame"`       // frame of the imported file
+	TimeSystem    string `json:"time_system"` // time system of the imported file
+	StartTime     int64  `json:"start_time"`
+	EndTime       int64  `json:"end_time"`
+	Points        int    `json:"points"`
+	Interpolation string `json:"interpolation"` // lagrange or hermite
+	Degree        int    `json:"degree"`
+	HasVelocity   bool   `json:"has_velocity"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// Downlink is a contact window used to empty the recorder
+type Downlink struct {
+	Contact
+	Volume       float64 `json:"volume"`        // Gbit downlinked
+	RecorderFill float64 `json:"recorder_fill"` // Gbit left on the recorder after the contact
+}
+
+// Tasking request states
+const (
+	RequestDraft     = "draft"
+	RequestSubmitted = "submitted"
+	RequestApproved  = "approved"
+	RequestScheduled = "scheduled"
+	RequestAcquired  = "acquired"
+	RequestFailed    = "failed"
+)
+
+// TaskingRequest is a customer's request for imagery of a target area
+type TaskingRequest struct {
+	ID              int      `json:"id"`
+	UserID          int      `json:"user_id"` // requester
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	MinLon          float64  `json:"min_lon"`
+	MaxLon          float64  `json:"max_lon"`
+	MinLat          float64  `json:"min_lat"`
+	MaxLat          float64  `json:"max_lat"`
+	StartTime       int64    `json:"start_time"`
+	EndTime         int64    `json:"end_time"`       // also the deadline
+	MaxResolution   float64  `json:"max_resolution"` // metres, 0 for any resolution
+	SensorIDs       []int    `json:"sensor_ids"`     // allowed sensors, empty for any
+	Priority        int      `json:"priority"`       // 1 (highest) to 10 (lowest)
+	MaxCloudCover   *float64 `json:"max_cloud_cover"`
+	MinSunElevation *float64 `json:"min_sun_elevation"`
+	MaxSunElevation *float64 `json:"max_sun_elevation"`
+	Status          string   `json:"status"`
+	CreatedAt       int64    `json:"created_at"`
+	UpdatedAt       int64    `json:"updated_at"`
+}
+
+// TaskingRequestEvent is an entry of a tasking request's audit trail
+type TaskingRequestEvent struct {
+	ID         int             `json:"id"`
+	RequestID  int             `json:"request_id"`
+	UserID     int             `json:"user_id"`
+	Username   string          `json:"username"`
+	Action     string          `json:"action"`
+	FromStatus string          `json:"from_status"`
+	ToStatus   string          `json:"to_status"`
+	Changes    json.RawMessage `json:"changes,omitempty"` // field -> [old, new] for edits
+	Note       string          `json:"note"`
+	CreatedAt  int64           `json:"created_at"`
+}
+
+// TransitionRequest carries an optional note for a tasking request state change
+type TransitionRequest struct {
+	Note string `json:"note"`
+}
+
+// RequestAcquisition is a strip assigned to a tasking request
+type RequestAcquisition struct {
+	RequestID    int     `json:"request_id"`
+	RequestName  string  `json:"request_name"`
+	Priority     int     `json:"priority"`
+	SlewTime     float64 `json:"slew_time"`     // seconds to re-point from the previous acquisition
+	DataVolume   float64 `json:"data_volume"`   // Gbit recorded, 0 if storage is not modelled
+	RecorderFill float64 `json:"recorder_fill"` // Gbit on the recorder after the acquisition
+	Strip        Strip   `json:"strip"`
+}
+
+// RequestSchedule is the deconflicted acquisition timeline of one satellite
+type RequestSchedule struct {
+	SatNoardID   string               `json:"sat_noard_id"`
+	SatName      string               `json:"sat_name"`
+	Acquisitions []RequestAcquisition `json:"acquisitions"`
+	Downlinks    []Downlink           `json:"downlinks,omitempty"`
+}
+
+// UnsatisfiedRequest explains why a tasking request got no acquisition
+type UnsatisfiedRequest struct {
+	RequestID   int    `json:"request_id"`
+	RequestName string `json:"request_name"`
+	Priority    int    `json:"priority"`
+	Deadline    int64  `json:"deadline"`
+	Candidates  int    `json:"candidates"`
+	Reason      string `json:"reason"`
+}
+
+// DeconflictResponse contains the per-satellite schedule of the approved requests
+type DeconflictResponse struct {
+	Satellites  []RequestSchedule    `json:"satellites"`
+	Unsatisfied []UnsatisfiedRequest `json:"unsatisfied"`
+	Applied     bool                 `json:"applied"` // scheduled requests were moved to "scheduled"
+}
+
+// FeedToken is a revocable token that lets calendar clients read a user's feeds
+type FeedToken struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"user_id"`
+	Name       string `json:"name"`
+	Token      string `json:"token,omitempty"` // only returned once, on creation
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+}
+
 // LoginRequest contains login credentials
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -0,0 +1,200 @@
+// Package oem reads and writes CCSDS Orbit Ephemeris Messages (CCSDS 502.0-B-2) in the KVN and
+// XML encodings
+package oem
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"satplan/orbit"
+)
+
+// Version is the OEM format version written
+const Version = "2.0"
+
+// State is an ephemeris data line: position in km and velocity in km/s
+type State struct {
+	Epoch    time.Time
+	Position orbit.Vector
+	Velocity orbit.Vector
+}
+
+// Covariance is a position-velocity covariance matrix, stored as its lower triangle row
+// by row (km², km²/s and km²/s²)
+type Covariance struct {
+	Epoch   time.Time
+	Frame   string
+	Comment string
+	Lower   [21]float64
+}
+
+// Message is a single-segment OEM
+type Message struct {
+	Originator    string
+	Created       time.Time
+	ObjectName    string
+	ObjectID      string // international designator, e.g. 2008-041A
+	Frame         string // TEME, GCRF or ITRF
+	TimeSystem    string // UTC when empty
+	Start         time.Time
+	Stop          time.Time
+	Interpolation string // interpolation method recommended to users, "" for none
+	Degree        int
+	Comments      []string
+	States        []State
+	Covariances   []Covariance
+}
+
+// covarianceNames are the XML element names of the lower triangle entries
+var covarianceNames = [21]string{
+	"CX_X",
+	"CY_X", "CY_Y",
+	"CZ_X", "CZ_Y", "CZ_Z",
+	"CX_DOT_X", "CX_DOT_Y", "CX_DOT_Z", "CX_DOT_X_DOT",
+	"CY_DOT_X", "CY_DOT_Y", "CY_DOT_Z", "CY_DOT_X_DOT", "CY_DOT_Y_DOT",
+	"CZ_DOT_X", "CZ_DOT_Y", "CZ_DOT_Z", "CZ_DOT_X_DOT", "CZ_DOT_Y_DOT", "CZ_DOT_Z_DOT",
+}
+
+// WriteKVN writes the message in the keyword = value notation
+func (m Message) WriteKVN(w io.Writer) (int64, error) {
+	var b strings.Builder
+	keyword := func(k, v string) { fmt.Fprintf(&b, "%-20s = %s\n", k, v) }
+
+	keyword("CCSDS_OEM_VERS", Version)
+	keyword("CREATION_DATE", formatTime(m.Created))
+	keyword("ORIGINATOR", m.Originator)
+	b.WriteString("\n")
+
+	b.WriteString("META_START\n")
+	keyword("OBJECT_NAME", m.ObjectName)
+	keyword("OBJECT_ID", m.ObjectID)
+	keyword("CENTER_NAME", "EARTH")
+	keyword("REF_FRAME", m.Frame)
+	keyword("TIME_SYSTEM", m.timeSystem())
+	keyword("START_TIME", formatTime(m.Start))
+	keyword("STOP_TIME", formatTime(m.Stop))
+	if m.Interpolation != "" {
+		keyword("INTERPOLATION", m.Interpolation)
+		keyword("INTERPOLATION_DEGREE", fmt.Sprint(m.Degree))
+	}
+	b.WriteString("META_STOP\n\n")
+
+	for _, c := range m.Comments {
+		b.WriteString("COMMENT " + c + "\n")
+	}
+	for _, s := range m.States {
+		fmt.Fprintf(&b, "%s %s %s %s %s %s %s\n", formatTime(s.Epoch),
+			formatKm(s.Position.X), formatKm(s.Position.Y), formatKm(s.Position.Z),
+			formatKmS(s.Velocity.X), formatKmS(s.Velocity.Y), formatKmS(s.Velocity.Z))
+	}
+
+	if len(m.Covariances) > 0 {
+		b.WriteString("\nCOVARIANCE_START\n")
+		for _, c := range m.Covariances {
+			if c.Comment != "" {
+				b.WriteString("COMMENT " + c.Comment + "\n")
+			}
+			keyword("EPOCH", formatTime(c.Epoch))
+			keyword("COV_REF_FRAME", c.Frame)
+			k := 0
+			for row := 1; row <= 6; row++ {
+				values := make([]string, row)
+				for col := range values {
+					values[col] = formatCov(c.Lower[k])
+					k++
+				}
+				b.WriteString(strings.Join(values, " ") + "\n")
+			}
+		}
+		b.WriteString("COVARIANCE_STOP\n")
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// WriteXML writes the message in the NDM/XML encoding
+func (m Message) WriteXML(w io.Writer) (int64, error) {
+	var b strings.Builder
+	element := func(indent int, name, value string) {
+		b.WriteString(strings.Repeat("  ", indent) + "<" + name + ">")
+		xml.EscapeText(&b, []byte(value))
+		b.WriteString("</" + name + ">\n")
+	}
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<oem id="CCSDS_OEM_VERS" version="` + Version + `">` + "\n")
+	b.WriteString("  <header>\n")
+	element(2, "CREATION_DATE", formatTime(m.Created))
+	element(2, "ORIGINATOR", m.Originator)
+	b.WriteString("  </header>\n")
+	b.WriteString("  <body>\n    <segment>\n      <metadata>\n")
+	element(4, "OBJECT_NAME", m.ObjectName)
+	element(4, "OBJECT_ID", m.ObjectID)
+	element(4, "CENTER_NAME", "EARTH")
+	element(4, "REF_FRAME", m.Frame)
+	element(4, "TIME_SYSTEM", m.timeSystem())
+	element(4, "START_TIME", formatTime(m.Start))
+	element(4, "STOP_TIME", formatTime(m.Stop))
+	if m.Interpolation != "" {
+		element(4, "INTERPOLATION", m.Interpolation)
+		element(4, "INTERPOLATION_DEGREE", fmt.Sprint(m.Degree))
+	}
+	b.WriteString("      </metadata>\n      <data>\n")
+	for _, c := range m.Comments {
+		element(4, "COMMENT", c)
+	}
+	for _, s := range m.States {
+		b.WriteString("        <stateVector>\n")
+		element(5, "EPOCH", formatTime(s.Epoch))
+		element(5, "X", formatKm(s.Position.X))
+		element(5, "Y", formatKm(s.Position.Y))
+		element(5, "Z", formatKm(s.Position.Z))
+		element(5, "X_DOT", formatKmS(s.Velocity.X))
+		element(5, "Y_DOT", formatKmS(s.Velocity.Y))
+		element(5, "Z_DOT", formatKmS(s.Velocity.Z))
+		b.WriteString("        </stateVector>\n")
+	}
+	for _, c := range m.Covariances {
+		b.WriteString("        <covarianceMatrix>\n")
+		if c.Comment != "" {
+			element(5, "COMMENT", c.Comment)
+		}
+		element(5, "EPOCH", formatTime(c.Epoch))
+		element(5, "COV_REF_FRAME", c.Frame)
+		for k, name := range covarianceNames {
+			element(5, name, formatCov(c.Lower[k]))
+		}
+		b.WriteString("        </covarianceMatrix>\n")
+	}
+	b.WriteString("      </data>\n    </segment>\n  </body>\n</oem>\n")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (m Message) timeSystem() string {
+	if m.TimeSystem == "" {
+		return "UTC"
+	}
+	return m.TimeSystem
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000")
+}
+
+func formatKm(v float64) string {
+	return fmt.Sprintf("%.6f", v)
+}
+
+func formatKmS(v float64) string {
+	return fmt.Sprintf("%.9f", v)
+}
+
+func formatCov(v float64) string {
+	return fmt.Sprintf("%.7e", v)
+}
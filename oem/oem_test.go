@@ -0,0 +1,69 @@
+package oem
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"satplan/orbit"
+)
+
+// TestRoundTrip writes a message in each encoding and parses it back
+func TestRoundTrip(t *testing.T) {
+	epoch := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := Message{
+		Originator:    "SATPLAN",
+		Created:       epoch,
+		ObjectName:    "SAT A & B",
+		ObjectID:      "2008-041A",
+		Frame:         "TEME",
+		Start:         epoch,
+		Stop:          epoch.Add(time.Minute),
+		Interpolation: "LAGRANGE",
+		Degree:        7,
+		Comments:      []string{"propagated with SGP4"},
+		States: []State{
+			{Epoch: epoch, Position: orbit.Vector{X: 6678.137, Y: -12.5, Z: 0.000001}, Velocity: orbit.Vector{X: 0.000123456, Y: 7.612345678, Z: -1.5}},
+			{Epoch: epoch.Add(time.Minute), Position: orbit.Vector{X: 6650.25, Y: 456.75, Z: 100.125}, Velocity: orbit.Vector{X: -0.5, Y: 7.6, Z: 0.25}},
+		},
+		Covariances: []Covariance{{Epoch: epoch, Frame: "RTN", Lower: [21]float64{1e-3, 2e-4}}},
+	}
+
+	for name, write := range map[string]func(Message, io.Writer) (int64, error){
+		"KVN": Message.WriteKVN,
+		"XML": Message.WriteXML,
+	} {
+		var b bytes.Buffer
+		if _, err := write(m, &b); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := Parse(&b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%s: %d messages, want 1", name, len(got))
+		}
+		g := got[0]
+		if g.Originator != m.Originator || g.ObjectName != m.ObjectName || g.ObjectID != m.ObjectID ||
+			g.Frame != m.Frame || g.TimeSystem != "UTC" || !g.Start.Equal(m.Start) || !g.Stop.Equal(m.Stop) ||
+			g.Interpolation != m.Interpolation || g.Degree != m.Degree {
+			t.Errorf("%s: metadata %+v", name, g)
+		}
+		if len(g.States) != len(m.States) {
+			t.Fatalf("%s: %d states, want %d", name, len(g.States), len(m.States))
+		}
+		for i, s := range g.States {
+			want := m.States[i]
+			if !s.Epoch.Equal(want.Epoch) || !near(s.Position, want.Position, 1e-6) || !near(s.Velocity, want.Velocity, 1e-9) {
+				t.Errorf("%s: state %d = %+v, want %+v", name, i, s, want)
+			}
+		}
+	}
+}
+
+func near(a, b orbit.Vector, tolerance float64) bool {
+	return math.Abs(a.X-b.X) <= tolerance && math.Abs(a.Y-b.Y) <= tolerance && math.Abs(a.Z-b.Z) <= tolerance
+}
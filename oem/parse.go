@@ -0,0 +1,223 @@
+package oem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"satplan/orbit"
+)
+
+// Parse reads an OEM in either encoding and returns one message per segment. Epochs are
+// in each segment's time system; covariance blocks are skipped.
+func Parse(r io.Reader) ([]Message, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parseXML(data)
+	}
+	return parseKVN(data)
+}
+
+// parseKVN reads the keyword = value notation
+func parseKVN(data []byte) ([]Message, error) {
+	segments := []Message{}
+	var cur *Message
+	originator := ""
+	inMeta, inCovariance := false, false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "COMMENT") {
+			continue
+		}
+		switch line {
+		case "META_START":
+			segments = append(segments, Message{})
+			cur = &segments[len(segments)-1]
+			inMeta = true
+			continue
+		case "META_STOP":
+			inMeta = false
+			continue
+		case "COVARIANCE_START":
+			inCovariance = true
+			continue
+		case "COVARIANCE_STOP":
+			inCovariance = false
+			continue
+		}
+		if inCovariance {
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !inMeta {
+				// header keywords
+				if key == "ORIGINATOR" {
+					originator = value
+				}
+				continue
+			}
+			if err := setMetadata(cur, key, value); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			continue
+		}
+
+		if cur == nil || inMeta {
+			return nil, fmt.Errorf("line %d: ephemeris data outside a segment", lineNo)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("line %d: expected an epoch and six state components", lineNo)
+		}
+		epoch, err := parseTime(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		var v [6]float64
+		for i := range v {
+			if v[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+		}
+		cur.States = append(cur.States, State{
+			Epoch:    epoch,
+			Position: orbit.Vector{X: v[0], Y: v[1], Z: v[2]},
+			Velocity: orbit.Vector{X: v[3], Y: v[4], Z: v[5]},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no META_START found")
+	}
+	for i := range segments {
+		segments[i].Originator = originator
+	}
+	return segments, nil
+}
+
+// setMetadata stores a segment metadata keyword
+func setMetadata(m *Message, key, value string) error {
+	var err error
+	switch key {
+	case "OBJECT_NAME":
+		m.ObjectName = value
+	case "OBJECT_ID":
+		m.ObjectID = value
+	case "CENTER_NAME":
+		if !strings.EqualFold(value, "EARTH") {
+			return fmt.Errorf("center %q is not supported", value)
+		}
+	case "REF_FRAME":
+		m.Frame = value
+	case "TIME_SYSTEM":
+		m.TimeSystem = value
+	case "START_TIME":
+		m.Start, err = parseTime(value)
+	case "STOP_TIME":
+		m.Stop, err = parseTime(value)
+	case "INTERPOLATION":
+		m.Interpolation = value
+	case "INTERPOLATION_DEGREE":
+		m.Degree, err = strconv.Atoi(value)
+	}
+	return err
+}
+
+// xmlMessage is the part of the NDM/XML OEM schema that is read
+type xmlMessage struct {
+	Originator string `xml:"header>ORIGINATOR"`
+	Segments   []struct {
+		Metadata struct {
+			ObjectName    string `xml:"OBJECT_NAME"`
+			ObjectID      string `xml:"OBJECT_ID"`
+			CenterName    string `xml:"CENTER_NAME"`
+			RefFrame      string `xml:"REF_FRAME"`
+			TimeSystem    string `xml:"TIME_SYSTEM"`
+			StartTime     string `xml:"START_TIME"`
+			StopTime      string `xml:"STOP_TIME"`
+			Interpolation string `xml:"INTERPOLATION"`
+			Degree        string `xml:"INTERPOLATION_DEGREE"`
+		} `xml:"metadata"`
+		States []struct {
+			Epoch string  `xml:"EPOCH"`
+			X     float64 `xml:"X"`
+			Y     float64 `xml:"Y"`
+			Z     float64 `xml:"Z"`
+			XDot  float64 `xml:"X_DOT"`
+			YDot  float64 `xml:"Y_DOT"`
+			ZDot  float64 `xml:"Z_DOT"`
+		} `xml:"data>stateVector"`
+	} `xml:"body>segment"`
+}
+
+// parseXML reads the NDM/XML encoding
+func parseXML(data []byte) ([]Message, error) {
+	var doc xmlMessage
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Segments) == 0 {
+		return nil, fmt.Errorf("no segment found")
+	}
+
+	segments := []Message{}
+	for i, s := range doc.Segments {
+		m := Message{Originator: doc.Originator}
+		meta := s.Metadata
+		for _, kv := range [][2]string{
+			{"OBJECT_NAME", meta.ObjectName}, {"OBJECT_ID", meta.ObjectID}, {"CENTER_NAME", meta.CenterName},
+			{"REF_FRAME", meta.RefFrame}, {"TIME_SYSTEM", meta.TimeSystem},
+			{"START_TIME", meta.StartTime}, {"STOP_TIME", meta.StopTime},
+			{"INTERPOLATION", meta.Interpolation}, {"INTERPOLATION_DEGREE", meta.Degree},
+		} {
+			if kv[1] == "" {
+				continue
+			}
+			if err := setMetadata(&m, kv[0], strings.TrimSpace(kv[1])); err != nil {
+				return nil, fmt.Errorf("segment %d: %v", i+1, err)
+			}
+		}
+		for _, sv := range s.States {
+			epoch, err := parseTime(strings.TrimSpace(sv.Epoch))
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %v", i+1, err)
+			}
+			m.States = append(m.States, State{
+				Epoch:    epoch,
+				Position: orbit.Vector{X: sv.X, Y: sv.Y, Z: sv.Z},
+				Velocity: orbit.Vector{X: sv.XDot, Y: sv.YDot, Z: sv.ZDot},
+			})
+		}
+		segments = append(segments, m)
+	}
+	return segments, nil
+}
+
+// parseTime reads a CCSDS ASCII time in calendar (2024-01-31T12:00:00.000) or
+// day-of-year (2024-031T12:00:00.000) form, with an optional trailing Z
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-002T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
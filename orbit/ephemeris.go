@@ -0,0 +1,211 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ephemeris interpolation methods
+const (
+	Lagrange = "lagrange"
+	Hermite  = "hermite"
+)
+
+// EphemerisPoint is a tabulated TEME state: position in km and velocity in km/s
+type EphemerisPoint struct {
+	Time     time.Time
+	Position Vector
+	Velocity Vector
+}
+
+// Ephemeris is a table of states interpolated between its points. Lagrange interpolation
+// of degree n uses the n+1 points around the requested time; Hermite interpolation also
+// matches the tabulated velocities and uses (n+1)/2 points. Without tabulated velocities
+// they are derived from the Lagrange polynomial.
+type Ephemeris struct {
+	Points      []EphemerisPoint // sorted by time
+	Method      string
+	Degree      int
+	HasVelocity bool
+}
+
+// Start returns the time of the first point
+func (e *Ephemeris) Start() time.Time { return e.Points[0].Time }
+
+// End returns the time of the last point
+func (e *Ephemeris) End() time.Time { return e.Points[len(e.Points)-1].Time }
+
+// Covers reports whether t lies within the tabulated span
+func (e *Ephemeris) Covers(t time.Time) bool {
+	return len(e.Points) > 1 && !t.Before(e.Start()) && !t.After(e.End())
+}
+
+// Interpolate returns the TEME position and velocity at t
+func (e *Ephemeris) Interpolate(t time.Time) (Vector, Vector, error) {
+	if !e.Covers(t) {
+		return Vector{}, Vector{}, fmt.Errorf("%s is outside the ephemeris span", t.UTC().Format(time.RFC3339))
+	}
+
+	n := e.Degree + 1
+	hermite := e.Method == Hermite && e.HasVelocity
+	if hermite {
+		n = max((e.Degree+1)/2, 2)
+	}
+	n = min(max(n, 2), len(e.Points))
+	i := sort.Search(len(e.Points), func(i int) bool { return e.Points[i].Time.After(t) })
+	first := min(max(i-n/2, 0), len(e.Points)-n)
+	nodes := e.Points[first : first+n]
+
+	// node abscissae in seconds from the first node keep the products well scaled
+	xs := make([]float64, n)
+	for j, p := range nodes {
+		xs[j] = p.Time.Sub(nodes[0].Time).Seconds()
+	}
+	x := t.Sub(nodes[0].Time).Seconds()
+
+	var pos, vel Vector
+	for j, p := range nodes {
+		l, dl := lagrangeBasis(xs, j, x)
+		if !hermite {
+			pos = pos.Add(p.Position.Scale(l))
+			if e.HasVelocity {
+				vel = vel.Add(p.Velocity.Scale(l))
+			} else {
+				vel = vel.Add(p.Position.Scale(dl))
+			}
+			continue
+		}
+		// Hermite basis h = (1 - 2 l'(xj)(x - xj)) l², k = (x - xj) l² and their derivatives
+		_, dlj := lagrangeBasis(xs, j, xs[j])
+		u := x - xs[j]
+		h := (1 - 2*dlj*u) * l * l
+		dh := -2*dlj*l*l + (1-2*dlj*u)*2*l*dl
+		k := u * l * l
+		dk := l*l + u*2*l*dl
+		pos = pos.Add(p.Position.Scale(h)).Add(p.Velocity.Scale(k))
+		vel = vel.Add(p.Position.Scale(dh)).Add(p.Velocity.Scale(dk))
+	}
+	return pos, vel, nil
+}
+
+// lagrangeBasis returns the j-th Lagrange basis polynomial of the nodes xs and its
+// derivative at x
+func lagrangeBasis(xs []float64, j int, x float64) (float64, float64) {
+	l := 1.0
+	for m := range xs {
+		if m != j {
+			l *= (x - xs[m]) / (xs[j] - xs[m])
+		}
+	}
+	dl := 0.0
+	for k := range xs {
+		if k == j {
+			continue
+		}
+		term := 1 / (xs[j] - xs[k])
+		for m := range xs {
+			if m != j && m != k {
+				term *= (x - xs[m]) / (xs[j] - xs[m])
+			}
+		}
+		dl += term
+	}
+	return l, dl
+}
+
+// ToTEME converts a state given in the named reference frame to TEME. Celestial frames
+// (GCRF, ICRF, EME2000/J2000) are taken as GCRF and terrestrial ones (ITRF, IGS and
+// WGS84 realizations) as the earth-fixed frame of TEMEToECEF.
+func ToTEME(frame string, r, v Vector, t time.Time) (Vector, Vector, error) {
+	f := strings.ToUpper(strings.TrimSpace(frame))
+	switch {
+	case f == "TEME":
+		return r, v, nil
+	case f == "GCRF" || f == "ICRF" || f == "EME2000" || f == "J2000":
+		r, v = GCRFToTEME(r, v, t)
+		return r, v, nil
+	case strings.HasPrefix(f, "ITR") || strings.HasPrefix(f, "IGS") || strings.HasPrefix(f, "IGB") || strings.HasPrefix(f, "WGS"):
+		r, v = ECEFToTEME(r, v, t)
+		return r, v, nil
+	}
+	return r, v, fmt.Errorf("unsupported reference frame %q", frame)
+}
+
+// ToUTC converts a time read in the named time system to UTC. The offsets use the leap
+// second count in force since 2017.
+func ToUTC(t time.Time, system string) (time.Time, error) {
+	const leapSeconds = 37 * time.Second
+	switch strings.ToUpper(strings.TrimSpace(system)) {
+	case "UTC":
+		return t, nil
+	case "TAI":
+		return t.Add(-leapSeconds), nil
+	case "GPS":
+		return t.Add(-leapSeconds + 19*time.Second), nil
+	case "TT":
+		return t.Add(-leapSeconds - 32184*time.Millisecond), nil
+	}
+	return t, fmt.Errorf("unsupported time system %q", system)
+}
+
+// ElementsFromState returns the osculating elements of a TEME state, for orbits known
+// only from an ephemeris
+func ElementsFromState(noradID string, r, v Vector, t time.Time) *Elements {
+	const mu = muWGS72
+	h := r.Cross(v)
+	node := Vector{0, 0, 1}.Cross(h)
+	rn, vn := r.Norm(), v.Norm()
+	ev := r.Scale(vn*vn - mu/rn).Sub(v.Scale(r.Dot(v))).Scale(1 / mu)
+	ecc := ev.Norm()
+	a := 1 / (2/rn - vn*vn/mu)
+
+	angle := func(a, b Vector) float64 {
+		if a.Norm() == 0 || b.Norm() == 0 {
+			return 0
+		}
+		return math.Acos(math.Max(-1, math.Min(1, a.Dot(b)/(a.Norm()*b.Norm()))))
+	}
+	raan := math.Atan2(node.Y, node.X)
+	if raan < 0 {
+		raan += twoPi
+	}
+	argp := angle(node, ev)
+	if ev.Z < 0 {
+		argp = twoPi - argp
+	}
+	nu := angle(ev, r)
+	if r.Dot(v) < 0 {
+		nu = twoPi - nu
+	}
+	ea := 2 * math.Atan(math.Sqrt((1-ecc)/(1+ecc))*math.Tan(nu/2))
+	mean := math.Mod(ea-ecc*math.Sin(ea)+twoPi, twoPi)
+
+	return &Elements{
+		NoradID:      noradID,
+		Epoch:        t,
+		Inclination:  angle(Vector{0, 0, 1}, h) * rad2deg,
+		RAAN:         raan * rad2deg,
+		Eccentricity: ecc,
+		ArgPerigee:   argp * rad2deg,
+		MeanAnomaly:  mean * rad2deg,
+		MeanMotion:   math.Sqrt(mu/(a*a*a)) * 86400 / twoPi,
+	}
+}
+
+// NewEphemerisPropagator returns a propagator for a satellite known only from an
+// ephemeris. It cannot propagate outside the ephemeris span. Its elements are the
+// osculating elements at the middle of the span.
+func NewEphemerisPropagator(noradID string, eph *Ephemeris) (*Propagator, error) {
+	if len(eph.Points) < 2 {
+		return nil, fmt.Errorf("an ephemeris needs at least two points")
+	}
+	mid := eph.Points[len(eph.Points)/2].Time
+	r, v, err := eph.Interpolate(mid)
+	if err != nil {
+		return nil, err
+	}
+	return &Propagator{Elements: ElementsFromState(noradID, r, v, mid), Ephemeris: eph}, nil
+}
@@ -0,0 +1,177 @@
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+// WGS-84 ellipsoid used for geodetic conversions
+const (
+	RadiusWGS84     = 6378.137 // km
+	flatteningWGS84 = 1 / 298.257223563
+	// EarthRotationRate is the rotation rate of the earth in rad/s
+	EarthRotationRate = 7.292115146706979e-5
+	// MeanEarthRadius is the mean spherical earth radius in km used for footprint geometry
+	MeanEarthRadius = 6371.0
+)
+
+// Vector is a cartesian 3-vector
+type Vector struct {
+	X, Y, Z float64
+}
+
+// Add returns v + o
+func (v Vector) Add(o Vector) Vector { return Vector{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+
+// Sub returns v - o
+func (v Vector) Sub(o Vector) Vector { return Vector{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+
+// Scale returns v * s
+func (v Vector) Scale(s float64) Vector { return Vector{v.X * s, v.Y * s, v.Z * s} }
+
+// Dot returns the dot product of v and o
+func (v Vector) Dot(o Vector) float64 { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+// Cross returns the cross product of v and o
+func (v Vector) Cross(o Vector) Vector {
+	return Vector{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+// Norm returns the magnitude of v
+func (v Vector) Norm() float64 { return math.Sqrt(v.Dot(v)) }
+
+// Unit returns v normalized to unit length
+func (v Vector) Unit() Vector {
+	n := v.Norm()
+	if n == 0 {
+		return v
+	}
+	return v.Scale(1 / n)
+}
+
+// Geodetic is a position on the WGS-84 ellipsoid
+type Geodetic struct {
+	Lat float64 `json:"lat"` // degrees
+	Lon float64 `json:"lon"` // degrees
+	Alt float64 `json:"alt"` // km
+}
+
+// JulianDate returns the Julian date of t
+func JulianDate(t time.Time) float64 {
+	return float64(t.UnixNano())/1e9/86400 + 2440587.5
+}
+
+// GMST returns the Greenwich mean sidereal time in radians (IAU-82)
+func GMST(t time.Time) float64 {
+	tut1 := (JulianDate(t) - 2451545.0) / 36525.0
+	temp := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 +
+		(876600.0*3600+8640184.812866)*tut1 + 67310.54841
+	temp = math.Mod(temp*deg2rad/240.0, twoPi)
+	if temp < 0 {
+		temp += twoPi
+	}
+	return temp
+}
+
+// TEMEToECEF rotates a TEME state into the earth-fixed frame (polar motion neglected)
+func TEMEToECEF(r, v Vector, t time.Time) (Vector, Vector) {
+	g := GMST(t)
+	cg, sg := math.Cos(g), math.Sin(g)
+	rf := Vector{cg*r.X + sg*r.Y, -sg*r.X + cg*r.Y, r.Z}
+	vf := Vector{cg*v.X + sg*v.Y, -sg*v.X + cg*v.Y, v.Z}
+	// remove the velocity due to earth rotation
+	vf = vf.Sub(Vector{0, 0, EarthRotationRate}.Cross(rf))
+	return rf, vf
+}
+
+// ECEFToTEME rotates an earth-fixed state into the TEME frame
+func ECEFToTEME(r, v Vector, t time.Time) (Vector, Vector) {
+	vi := v.Add(Vector{0, 0, EarthRotationRate}.Cross(r))
+	g := GMST(t)
+	cg, sg := math.Cos(g), math.Sin(g)
+	ri := Vector{cg*r.X - sg*r.Y, sg*r.X + cg*r.Y, r.Z}
+	vi = Vector{cg*vi.X - sg*vi.Y, sg*vi.X + cg*vi.Y, vi.Z}
+	return ri, vi
+}
+
+// ECEFToGeodetic converts an earth-fixed position (km) to WGS-84 geodetic coordinates
+func ECEFToGeodetic(r Vector) Geodetic {
+	e2 := flatteningWGS84 * (2 - flatteningWGS84)
+	lon := math.Atan2(r.Y, r.X)
+	p := math.Hypot(r.X, r.Y)
+	lat := math.Atan2(r.Z, p*(1-e2))
+	var n float64
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n = RadiusWGS84 / math.Sqrt(1-e2*sinLat*sinLat)
+		next := math.Atan2(r.Z+n*e2*sinLat, p)
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+	sinLat := math.Sin(lat)
+	n = RadiusWGS84 / math.Sqrt(1-e2*sinLat*sinLat)
+	var alt float64
+	if math.Abs(math.Cos(lat)) > 1e-9 {
+		alt = p/math.Cos(lat) - n
+	} else {
+		alt = math.Abs(r.Z) - n*(1-e2)
+	}
+	return Geodetic{Lat: lat * rad2deg, Lon: lon * rad2deg, Alt: alt}
+}
+
+// GeodeticToECEF converts WGS-84 geodetic coordinates to an earth-fixed position (km)
+func GeodeticToECEF(g Geodetic) Vector {
+	e2 := flatteningWGS84 * (2 - flatteningWGS84)
+	lat, lon := g.Lat*deg2rad, g.Lon*deg2rad
+	sinLat := math.Sin(lat)
+	n := RadiusWGS84 / math.Sqrt(1-e2*sinLat*sinLat)
+	return Vector{
+		(n + g.Alt) * math.Cos(lat) * math.Cos(lon),
+		(n + g.Alt) * math.Cos(lat) * math.Sin(lon),
+		(n*(1-e2) + g.Alt) * sinLat,
+	}
+}
+
+// State is a propagated satellite state in the earth-fixed frame
+type State struct {
+	Time     time.Time
+	Position Vector // ECEF km
+	Velocity Vector // ECEF km/s
+	Geodetic Geodetic
+}
+
+// StateAt propagates to t and returns the earth-fixed state
+func (p *Propagator) StateAt(t time.Time) (State, error) {
+	r, v, err := p.Propagate(t)
+	if err != nil {
+		return State{}, err
+	}
+	rf, vf := TEMEToECEF(r, v, t)
+	return State{Time: t, Position: rf, Velocity: vf, Geodetic: ECEFToGeodetic(rf)}, nil
+}
+
+// Heading returns the ground track azimuth in degrees clockwise from north
+func (s State) Heading() float64 {
+	lat, lon := s.Geodetic.Lat*deg2rad, s.Geodetic.Lon*deg2rad
+	east := Vector{-math.Sin(lon), math.Cos(lon), 0}
+	north := Vector{-math.Sin(lat) * math.Cos(lon), -math.Sin(lat) * math.Sin(lon), math.Cos(lat)}
+	return math.Mod(math.Atan2(s.Velocity.Dot(east), s.Velocity.Dot(north))*rad2deg+360, 360)
+}
+
+// LookAngles returns the azimuth (degrees clockwise from north), elevation (degrees) and
+// range (km) of the earth-fixed position target seen from the observer
+func LookAngles(observer Geodetic, target Vector) (az, el, rng float64) {
+	lat, lon := observer.Lat*deg2rad, observer.Lon*deg2rad
+	rho := target.Sub(GeodeticToECEF(observer))
+	east := Vector{-math.Sin(lon), math.Cos(lon), 0}
+	north := Vector{-math.Sin(lat) * math.Cos(lon), -math.Sin(lat) * math.Sin(lon), math.Cos(lat)}
+	up := Vector{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+	e, n, u := rho.Dot(east), rho.Dot(north), rho.Dot(up)
+	rng = rho.Norm()
+	az = math.Mod(math.Atan2(e, n)*rad2deg+360, 360)
+	el = math.Asin(u/rng) * rad2deg
+	return az, el, rng
+}
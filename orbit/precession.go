@@ -0,0 +1,138 @@
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+// arcsec2rad converts arcseconds to radians
+const arcsec2rad = deg2rad / 3600
+
+// matrix is a 3x3 rotation matrix
+type matrix [3][3]float64
+
+// apply returns m·v
+func (m matrix) apply(v Vector) Vector {
+	return Vector{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// mul returns m·o
+func (m matrix) mul(o matrix) matrix {
+	var p matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				p[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+	return p
+}
+
+// transpose returns the inverse of a rotation matrix
+func (m matrix) transpose() matrix {
+	var p matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			p[i][j] = m[j][i]
+		}
+	}
+	return p
+}
+
+// rot1, rot2 and rot3 rotate the coordinate frame by a (radians) about the x, y and z axes
+func rot1(a float64) matrix {
+	c, s := math.Cos(a), math.Sin(a)
+	return matrix{{1, 0, 0}, {0, c, s}, {0, -s, c}}
+}
+
+func rot2(a float64) matrix {
+	c, s := math.Cos(a), math.Sin(a)
+	return matrix{{c, 0, -s}, {0, 1, 0}, {s, 0, c}}
+}
+
+func rot3(a float64) matrix {
+	c, s := math.Cos(a), math.Sin(a)
+	return matrix{{c, s, 0}, {-s, c, 0}, {0, 0, 1}}
+}
+
+// nutationTerms are the largest terms of the IAU 1980 nutation series: multipliers of
+// the fundamental arguments l, l', F, D and Ω, then the longitude coefficients A + B·T
+// and the obliquity coefficients C + D·T in units of 0.0001". The omitted terms are
+// below 0.005" each.
+var nutationTerms = [][9]float64{
+	{0, 0, 0, 0, 1, -171996, -174.2, 92025, 8.9},
+	{0, 0, 2, -2, 2, -13187, -1.6, 5736, -3.1},
+	{0, 0, 2, 0, 2, -2274, -0.2, 977, -0.5},
+	{0, 0, 0, 0, 2, 2062, 0.2, -895, 0.5},
+	{0, 1, 0, 0, 0, 1426, -3.4, 54, -0.1},
+	{1, 0, 0, 0, 0, 712, 0.1, -7, 0},
+	{0, 1, 2, -2, 2, -517, 1.2, 224, -0.6},
+	{0, 0, 2, 0, 1, -386, -0.4, 200, 0},
+	{1, 0, 2, 0, 2, -301, 0, 129, -0.1},
+	{0, -1, 2, -2, 2, 217, -0.5, -95, 0.3},
+	{1, 0, 0, -2, 0, -158, 0, -1, 0},
+	{0, 0, 2, -2, 1, 129, 0.1, -70, 0},
+	{-1, 0, 2, 0, 2, 123, 0, -53, 0},
+	{1, 0, 0, 0, 1, 63, 0.1, -33, 0},
+	{0, 0, 0, 2, 0, 63, 0, -2, 0},
+	{-1, 0, 2, 2, 2, -59, 0, 26, 0},
+	{-1, 0, 0, 0, 1, -58, -0.1, 32, 0},
+	{1, 0, 2, 0, 1, -51, 0, 27, 0},
+}
+
+// nutation returns the nutation in longitude and obliquity and the mean obliquity of the
+// ecliptic (radians) at T julian centuries from J2000
+func nutation(T float64) (dpsi, deps, meanEps float64) {
+	meanEps = (84381.448 - 46.8150*T - 0.00059*T*T + 0.001813*T*T*T) * arcsec2rad
+
+	// fundamental arguments in degrees, the rates including whole revolutions
+	l := 134.96298139 + (1325*360+198.8673981)*T + 0.0086972*T*T + 1.78e-5*T*T*T
+	lp := 357.52772333 + (99*360+359.0503400)*T - 0.0001603*T*T - 3.3e-6*T*T*T
+	F := 93.27191028 + (1342*360+82.0175381)*T - 0.0036825*T*T + 3.1e-6*T*T*T
+	D := 297.85036306 + (1236*360+307.1114800)*T - 0.0019142*T*T + 5.3e-6*T*T*T
+	omega := 125.04452222 - (5*360+134.1362608)*T + 0.0020708*T*T + 2.2e-6*T*T*T
+
+	for _, n := range nutationTerms {
+		arg := (n[0]*l + n[1]*lp + n[2]*F + n[3]*D + n[4]*omega) * deg2rad
+		dpsi += (n[5] + n[6]*T) * math.Sin(arg)
+		deps += (n[7] + n[8]*T) * math.Cos(arg)
+	}
+	return dpsi * 1e-4 * arcsec2rad, deps * 1e-4 * arcsec2rad, meanEps
+}
+
+// temeToGCRF returns the rotation from TEME to GCRF at t: the equation of the equinoxes
+// to true of date, IAU 1980 nutation to mean of date and IAU 1976 precession to J2000.
+// The frame bias between J2000 and GCRF (under 0.03") and the difference between UTC and
+// terrestrial time are neglected.
+func temeToGCRF(t time.Time) matrix {
+	T := (JulianDate(t) - 2451545.0) / 36525.0
+
+	zeta := (2306.2181*T + 0.30188*T*T + 0.017998*T*T*T) * arcsec2rad
+	theta := (2004.3109*T - 0.42665*T*T - 0.041833*T*T*T) * arcsec2rad
+	z := (2306.2181*T + 1.09468*T*T + 0.018203*T*T*T) * arcsec2rad
+	precession := rot3(zeta).mul(rot2(-theta)).mul(rot3(z))
+
+	dpsi, deps, meanEps := nutation(T)
+	nut := rot1(-meanEps).mul(rot3(dpsi)).mul(rot1(meanEps + deps))
+
+	equinoxes := rot3(-dpsi * math.Cos(meanEps))
+
+	return precession.mul(nut).mul(equinoxes)
+}
+
+// TEMEToGCRF rotates a TEME state into the geocentric celestial reference frame
+func TEMEToGCRF(r, v Vector, t time.Time) (Vector, Vector) {
+	m := temeToGCRF(t)
+	return m.apply(r), m.apply(v)
+}
+
+// GCRFToTEME rotates a GCRF state into the TEME frame
+func GCRFToTEME(r, v Vector, t time.Time) (Vector, Vector) {
+	m := temeToGCRF(t).transpose()
+	return m.apply(r), m.apply(v)
+}
@@ -0,0 +1,314 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// WGS-72 constants used by SGP4
+const (
+	muWGS72       = 398600.8       // km^3/s^2
+	radiusWGS72   = 6378.135       // km
+	j2WGS72       = 0.001082616    //
+	j3WGS72       = -0.00000253881 //
+	j4WGS72       = -0.00000165597 //
+	minutesPerDay = 1440.0
+	twoPi         = 2 * math.Pi
+	deg2rad       = math.Pi / 180
+	rad2deg       = 180 / math.Pi
+)
+
+var (
+	xke       = 60.0 / math.Sqrt(radiusWGS72*radiusWGS72*radiusWGS72/muWGS72)
+	j3oj2     = j3WGS72 / j2WGS72
+	vkmpersec = radiusWGS72 * xke / 60.0
+)
+
+// ErrDeepSpace is returned for element sets with a period of 225 minutes or more,
+// which require the SDP4 deep-space perturbations that are not implemented
+var ErrDeepSpace = fmt.Errorf("deep-space orbits (period >= 225 min) are not supported")
+
+// Propagator propagates a single element set with the near-earth SGP4 model. When an
+// ephemeris is attached, states within its span are interpolated from it instead.
+type Propagator struct {
+	Elements  *Elements
+	Ephemeris *Ephemeris
+
+	// sgp4 is false for propagators built from an ephemeris alone
+	sgp4 bool
+
+	// initialized constants
+	ecco, inclo, nodeo, argpo, mo, no, bstar   float64
+	isimp                                      bool
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4    float64
+	delmo, eta, argpdot, omgcof, sinmao        float64
+	t2cof, t3cof, t4cof, t5cof, x1mth2, x7thm1 float64
+	mdot, nodedot, xlcof, xmcof, nodecf        float64
+}
+
+// NewPropagator initializes SGP4 for the given elements
+func NewPropagator(el *Elements) (*Propagator, error) {
+	p := &Propagator{
+		Elements: el,
+		sgp4:     true,
+		ecco:     el.Eccentricity,
+		inclo:    el.Inclination * deg2rad,
+		nodeo:    el.RAAN * deg2rad,
+		argpo:    el.ArgPerigee * deg2rad,
+		mo:       el.MeanAnomaly * deg2rad,
+		bstar:    el.BStar,
+	}
+	noKozai := el.MeanMotion * twoPi / minutesPerDay
+
+	// Recover original mean motion and semi-major axis from the Kozai mean motion
+	const x2o3 = 2.0 / 3.0
+	eccsq := p.ecco * p.ecco
+	omeosq := 1 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(p.inclo)
+	cosio2 := cosio * cosio
+
+	ak := math.Pow(xke/noKozai, x2o3)
+	d1 := 0.75 * j2WGS72 * (3*cosio2 - 1) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1 - del*del - del*(1.0/3.0+134*del*del/81))
+	del = d1 / (adel * adel)
+	p.no = noKozai / (1 + del)
+
+	ao := math.Pow(xke/p.no, x2o3)
+	sinio := math.Sin(p.inclo)
+	po := ao * omeosq
+	con42 := 1 - 5*cosio2
+	p.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1 - p.ecco)
+
+	if twoPi/p.no >= 225 {
+		return nil, ErrDeepSpace
+	}
+	if omeosq < 0 || p.no <= 0 {
+		return nil, fmt.Errorf("invalid elements for satellite %s", el.NoradID)
+	}
+
+	ss := 78/radiusWGS72 + 1
+	qzms2t := math.Pow((120-78)/radiusWGS72, 4)
+	p.isimp = rp < 220/radiusWGS72+1
+
+	sfour := ss
+	qzms24 := qzms2t
+	perige := (rp - 1) * radiusWGS72
+	if perige < 156 {
+		sfour = perige - 78
+		if perige < 98 {
+			sfour = 20
+		}
+		qzms24 = math.Pow((120-sfour)/radiusWGS72, 4)
+		sfour = sfour/radiusWGS72 + 1
+	}
+
+	pinvsq := 1 / posq
+	tsi := 1 / (ao - sfour)
+	p.eta = ao * p.ecco * tsi
+	etasq := p.eta * p.eta
+	eeta := p.ecco * p.eta
+	psisq := math.Abs(1 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * p.no * (ao*(1+1.5*etasq+eeta*(4+etasq)) +
+		0.375*j2WGS72*tsi/psisq*p.con41*(8+3*etasq*(8+etasq)))
+	p.cc1 = p.bstar * cc2
+	cc3 := 0.0
+	if p.ecco > 1.0e-4 {
+		cc3 = -2 * coef * tsi * j3oj2 * p.no * sinio / p.ecco
+	}
+	p.x1mth2 = 1 - cosio2
+	p.cc4 = 2 * p.no * coef1 * ao * omeosq *
+		(p.eta*(2+0.5*etasq) + p.ecco*(0.5+2*etasq) -
+			j2WGS72*tsi/(ao*psisq)*
+				(-3*p.con41*(1-2*eeta+etasq*(1.5-0.5*eeta))+
+					0.75*p.x1mth2*(2*etasq-eeta*(1+etasq))*math.Cos(2*p.argpo)))
+	p.cc5 = 2 * coef1 * ao * omeosq * (1 + 2.75*(etasq+eeta) + eeta*etasq)
+
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * j2WGS72 * pinvsq * p.no
+	temp2 := 0.5 * temp1 * j2WGS72 * pinvsq
+	temp3 := -0.46875 * j4WGS72 * pinvsq * pinvsq * p.no
+	p.mdot = p.no + 0.5*temp1*rteosq*p.con41 + 0.0625*temp2*rteosq*(13-78*cosio2+137*cosio4)
+	p.argpdot = -0.5*temp1*con42 + 0.0625*temp2*(7-114*cosio2+395*cosio4) +
+		temp3*(3-36*cosio2+49*cosio4)
+	xhdot1 := -temp1 * cosio
+	p.nodedot = xhdot1 + (0.5*temp2*(4-19*cosio2)+2*temp3*(3-7*cosio2))*cosio
+	p.omgcof = p.bstar * cc3 * math.Cos(p.argpo)
+	if p.ecco > 1.0e-4 {
+		p.xmcof = -x2o3 * coef * p.bstar / eeta
+	}
+	p.nodecf = 3.5 * omeosq * xhdot1 * p.cc1
+	p.t2cof = 1.5 * p.cc1
+	if math.Abs(cosio+1) > 1.5e-12 {
+		p.xlcof = -0.25 * j3oj2 * sinio * (3 + 5*cosio) / (1 + cosio)
+	} else {
+		p.xlcof = -0.25 * j3oj2 * sinio * (3 + 5*cosio) / 1.5e-12
+	}
+	p.aycof = -0.5 * j3oj2 * sinio
+	p.delmo = math.Pow(1+p.eta*math.Cos(p.mo), 3)
+	p.sinmao = math.Sin(p.mo)
+	p.x7thm1 = 7*cosio2 - 1
+
+	if !p.isimp {
+		cc1sq := p.cc1 * p.cc1
+		p.d2 = 4 * ao * tsi * cc1sq
+		temp := p.d2 * tsi * p.cc1 / 3
+		p.d3 = (17*ao + sfour) * temp
+		p.d4 = 0.5 * temp * ao * tsi * (221*ao + 31*sfour) * p.cc1
+		p.t3cof = p.d2 + 2*cc1sq
+		p.t4cof = 0.25 * (3*p.d3 + p.cc1*(12*p.d2+10*cc1sq))
+		p.t5cof = 0.2 * (3*p.d4 + 12*p.cc1*p.d3 + 6*p.d2*p.d2 + 15*cc1sq*(2*p.d2+cc1sq))
+	}
+
+	return p, nil
+}
+
+// NewPropagatorFromTLE parses a TLE and initializes a propagator for it
+func NewPropagatorFromTLE(line1, line2 string) (*Propagator, error) {
+	el, err := ParseTLE(line1, line2)
+	if err != nil {
+		return nil, err
+	}
+	return NewPropagator(el)
+}
+
+// Propagate returns the TEME position (km) and velocity (km/s) at time t
+func (p *Propagator) Propagate(t time.Time) (Vector, Vector, error) {
+	if p.Ephemeris != nil && (p.Ephemeris.Covers(t) || !p.sgp4) {
+		return p.Ephemeris.Interpolate(t)
+	}
+	return p.PropagateMinutes(t.Sub(p.Elements.Epoch).Minutes())
+}
+
+// PropagateMinutes returns the TEME position (km) and velocity (km/s) at tsince minutes from epoch
+func (p *Propagator) PropagateMinutes(tsince float64) (Vector, Vector, error) {
+	const x2o3 = 2.0 / 3.0
+
+	xmdf := p.mo + p.mdot*tsince
+	argpdf := p.argpo + p.argpdot*tsince
+	nodedf := p.nodeo + p.nodedot*tsince
+	argpm := argpdf
+	mm := xmdf
+	t2 := tsince * tsince
+	nodem := nodedf + p.nodecf*t2
+	tempa := 1 - p.cc1*tsince
+	tempe := p.bstar * p.cc4 * tsince
+	templ := p.t2cof * t2
+
+	if !p.isimp {
+		delomg := p.omgcof * tsince
+		delmtemp := 1 + p.eta*math.Cos(xmdf)
+		delm := p.xmcof * (delmtemp*delmtemp*delmtemp - p.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * tsince
+		t4 := t3 * tsince
+		tempa = tempa - p.d2*t2 - p.d3*t3 - p.d4*t4
+		tempe = tempe + p.bstar*p.cc5*(math.Sin(mm)-p.sinmao)
+		templ = templ + p.t3cof*t3 + t4*(p.t4cof+tsince*p.t5cof)
+	}
+
+	am := math.Pow(xke/p.no, x2o3) * tempa * tempa
+	nm := xke / math.Pow(am, 1.5)
+	em := p.ecco - tempe
+	if em >= 1 || em < -0.001 {
+		return Vector{}, Vector{}, fmt.Errorf("satellite %s: mean elements out of range at %.1f min", p.Elements.NoradID, tsince)
+	}
+	if em < 1.0e-6 {
+		em = 1.0e-6
+	}
+	mm = mm + p.no*templ
+	xlm := mm + argpm + nodem
+
+	nodem = math.Mod(nodem, twoPi)
+	argpm = math.Mod(argpm, twoPi)
+	xlm = math.Mod(xlm, twoPi)
+	mm = math.Mod(xlm-argpm-nodem, twoPi)
+
+	sinip := math.Sin(p.inclo)
+	cosip := math.Cos(p.inclo)
+
+	// Long period periodics
+	axnl := em * math.Cos(argpm)
+	temp := 1 / (am * (1 - em*em))
+	aynl := em*math.Sin(argpm) + temp*p.aycof
+	xl := mm + argpm + nodem + temp*p.xlcof*axnl
+
+	// Solve Kepler's equation
+	u := math.Mod(xl-nodem, twoPi)
+	eo1 := u
+	tem5 := 9999.9
+	var sineo1, coseo1 float64
+	for ktr := 1; math.Abs(tem5) >= 1.0e-12 && ktr <= 10; ktr++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = 1 - coseo1*axnl - sineo1*aynl
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / tem5
+		if math.Abs(tem5) >= 0.95 {
+			tem5 = math.Copysign(0.95, tem5)
+		}
+		eo1 += tem5
+	}
+
+	// Short period preliminary quantities
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1 - el2)
+	if pl < 0 {
+		return Vector{}, Vector{}, fmt.Errorf("satellite %s: semi-latus rectum < 0 at %.1f min", p.Elements.NoradID, tsince)
+	}
+	rl := am * (1 - ecose)
+	rdotl := math.Sqrt(am) * esine / rl
+	rvdotl := math.Sqrt(pl) / rl
+	betal := math.Sqrt(1 - el2)
+	temp = esine / (1 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1 - 2*sinu*sinu
+	temp = 1 / pl
+	temp1 := 0.5 * j2WGS72 * temp
+	temp2 := temp1 * temp
+
+	// Update for short period periodics
+	mrt := rl*(1-1.5*temp2*betal*p.con41) + 0.5*temp1*p.x1mth2*cos2u
+	su = su - 0.25*temp2*p.x7thm1*sin2u
+	xnode := nodem + 1.5*temp2*cosip*sin2u
+	xinc := p.inclo + 1.5*temp2*cosip*sinip*cos2u
+	mvt := rdotl - nm*temp1*p.x1mth2*sin2u/xke
+	rvdot := rvdotl + nm*temp1*(p.x1mth2*cos2u+1.5*p.con41)/xke
+
+	// Orientation vectors
+	sinsu, cossu := math.Sin(su), math.Cos(su)
+	snod, cnod := math.Sin(xnode), math.Cos(xnode)
+	sini, cosi := math.Sin(xinc), math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	ux := xmx*sinsu + cnod*cossu
+	uy := xmy*sinsu + snod*cossu
+	uz := sini * sinsu
+	vx := xmx*cossu - cnod*sinsu
+	vy := xmy*cossu - snod*sinsu
+	vz := sini * cossu
+
+	if mrt < 1 {
+		return Vector{}, Vector{}, fmt.Errorf("satellite %s: decayed at %.1f min", p.Elements.NoradID, tsince)
+	}
+
+	r := Vector{mrt * ux * radiusWGS72, mrt * uy * radiusWGS72, mrt * uz * radiusWGS72}
+	v := Vector{
+		(mvt*ux + rvdot*vx) * vkmpersec,
+		(mvt*uy + rvdot*vy) * vkmpersec,
+		(mvt*uz + rvdot*vz) * vkmpersec,
+	}
+	return r, v, nil
+}
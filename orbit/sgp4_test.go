@@ -0,0 +1,35 @@
+package orbit
+
+import "testing"
+
+// TestSGP4Vallado checks the propagator against the test vectors of satellite 00005 in
+// Vallado et al., "Revisiting Spacetrack Report #3" (AIAA 2006-6753), TEME km and km/s
+func TestSGP4Vallado(t *testing.T) {
+	p, err := NewPropagatorFromTLE(
+		"1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tsince float64 // minutes
+		r, v   Vector
+	}{
+		{0, Vector{7022.46529266, -1400.08296755, 0.03995155}, Vector{1.893841015, 6.405893759, 4.534807250}},
+		{360, Vector{-7154.03120202, -3783.17682504, -3536.19412294}, Vector{4.741887409, -4.151817765, -2.093935425}},
+		{720, Vector{-7134.59340119, 6531.68641334, 3260.27186483}, Vector{-4.113793027, -2.911922039, -2.557327851}},
+	}
+	for _, tt := range tests {
+		r, v, err := p.PropagateMinutes(tt.tsince)
+		if err != nil {
+			t.Fatalf("t+%g min: %v", tt.tsince, err)
+		}
+		if d := r.Sub(tt.r).Norm(); d > 1e-3 {
+			t.Errorf("t+%g min: position %v is %g km from %v", tt.tsince, r, d, tt.r)
+		}
+		if d := v.Sub(tt.v).Norm(); d > 1e-6 {
+			t.Errorf("t+%g min: velocity %v is %g km/s from %v", tt.tsince, v, d, tt.v)
+		}
+	}
+}
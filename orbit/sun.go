@@ -0,0 +1,181 @@
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// SunRadius is the radius of the sun's photosphere in km
+	SunRadius = 696000.0
+	// AstronomicalUnit in km
+	AstronomicalUnit = 149597870.7
+)
+
+// SubSolarPoint returns the latitude and longitude (degrees) where the sun is at zenith.
+// It uses the same low-precision almanac as calculateSunPosition in static/script.js.
+func SubSolarPoint(t time.Time) (lat, lon float64) {
+	lat, lon, _ = solarAlmanac(t)
+	return lat, lon
+}
+
+// solarAlmanac returns the sub-solar point in degrees and the sun's distance in AU from
+// the Astronomical Almanac's low-precision formulae, good to about 0.01° until 2050
+func solarAlmanac(t time.Time) (lat, lon, distance float64) {
+	elapsedDays := JulianDate(t) - 2451545.0
+	meanLongitude := math.Mod(280.46+0.9856474*elapsedDays, 360)
+	meanAnomaly := math.Mod(357.528+0.9856003*elapsedDays, 360) * deg2rad
+	eclipticLongitude := (meanLongitude + 1.915*math.Sin(meanAnomaly) + 0.02*math.Sin(2*meanAnomaly)) * deg2rad
+	obliquity := (23.439 - 0.0000004*elapsedDays) * deg2rad
+	declination := math.Asin(math.Sin(obliquity)*math.Sin(eclipticLongitude)) * rad2deg
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLongitude), math.Cos(eclipticLongitude)) * rad2deg
+	gmst := math.Mod(18.697374558+24.06570982441908*elapsedDays, 24)
+	distance = 1.00014 - 0.01671*math.Cos(meanAnomaly) - 0.00014*math.Cos(2*meanAnomaly)
+
+	lon = -(gmst*15 - rightAscension)
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return declination, lon, distance
+}
+
+// SolarElevation returns the sun elevation angle in degrees seen from (lat, lon) at t
+func SolarElevation(lat, lon float64, t time.Time) float64 {
+	sunLat, sunLon := SubSolarPoint(t)
+	latRad, sunLatRad := lat*deg2rad, sunLat*deg2rad
+	sinElev := math.Sin(latRad)*math.Sin(sunLatRad) +
+		math.Cos(latRad)*math.Cos(sunLatRad)*math.Cos((lon-sunLon)*deg2rad)
+	return math.Asin(math.Max(-1, math.Min(1, sinElev))) * rad2deg
+}
+
+// SolarAzimuth returns the sun azimuth in degrees clockwise from north seen from (lat, lon) at t
+func SolarAzimuth(lat, lon float64, t time.Time) float64 {
+	sunLat, sunLon := SubSolarPoint(t)
+	latRad, sunLatRad := lat*deg2rad, sunLat*deg2rad
+	deltaLon := (sunLon - lon) * deg2rad
+	az := math.Atan2(math.Sin(deltaLon)*math.Cos(sunLatRad),
+		math.Cos(latRad)*math.Sin(sunLatRad)-math.Sin(latRad)*math.Cos(sunLatRad)*math.Cos(deltaLon)) * rad2deg
+	return math.Mod(az+360, 360)
+}
+
+// SunDirection returns the unit vector from the earth's centre towards the sun in the
+// earth-fixed frame at t
+func SunDirection(t time.Time) Vector {
+	sunLat, sunLon := SubSolarPoint(t)
+	return surfaceNormal(sunLat, sunLon)
+}
+
+// surfaceNormal returns the unit vector pointing up from the sphere at (lat, lon)
+func surfaceNormal(lat, lon float64) Vector {
+	latRad, lonRad := lat*deg2rad, lon*deg2rad
+	return Vector{
+		X: math.Cos(latRad) * math.Cos(lonRad),
+		Y: math.Cos(latRad) * math.Sin(lonRad),
+		Z: math.Sin(latRad),
+	}
+}
+
+// GlintAngle returns the angle in degrees between the direction from the ground point
+// (lat, lon) to the satellite at ECEF position sat and the direction of the sun's specular
+// reflection at that point. Small angles mean the sensor looks into the sun glint.
+func GlintAngle(lat, lon float64, sat Vector, t time.Time) float64 {
+	normal := surfaceNormal(lat, lon)
+	sun := SunDirection(t)
+	reflected := normal.Scale(2 * sun.Dot(normal)).Sub(sun)
+	view := sat.Sub(GeodeticToECEF(Geodetic{Lat: lat, Lon: lon})).Unit()
+	cos := math.Max(-1, math.Min(1, reflected.Dot(view)))
+	return math.Acos(cos) * rad2deg
+}
+
+// SunPosition returns the position of the sun in the earth-fixed frame at t, in km
+func SunPosition(t time.Time) Vector {
+	lat, lon, distance := solarAlmanac(t)
+	return surfaceNormal(lat, lon).Scale(distance * AstronomicalUnit)
+}
+
+// ShadowAngles returns, seen from ECEF position pos at t, the apparent radii in degrees
+// of the sun and of the earth and the angle between their centres. The satellite is in
+// the umbra when separation <= earth-sun, in the penumbra while separation < earth+sun,
+// and sunlit beyond.
+func ShadowAngles(pos Vector, t time.Time) (sun, earth, separation float64) {
+	toSun := SunPosition(t).Sub(pos)
+	toEarth := pos.Scale(-1)
+	sun = math.Asin(SunRadius/toSun.Norm()) * rad2deg
+	earth = math.Asin(math.Min(1, RadiusWGS84/toEarth.Norm())) * rad2deg
+	cos := toSun.Unit().Dot(toEarth.Unit())
+	separation = math.Acos(math.Max(-1, math.Min(1, cos))) * rad2deg
+	return sun, earth, separation
+}
+
+// Illumination returns the fraction of the sun's disc visible from ECEF position pos at
+// t, with the earth's shadow modelled as umbra and penumbra cones: 1 in sunlight, 0 in
+// the umbra and in between in the penumbra
+func Illumination(pos Vector, t time.Time) float64 {
+	a, b, c := ShadowAngles(pos, t)
+	switch {
+	case c >= a+b:
+		return 1
+	case c <= b-a:
+		return 0
+	case c <= a-b:
+		// annular: the earth is inside the sun's disc, never the case in earth orbit
+		return 1 - b*b/(a*a)
+	}
+	// area of the lens where the two discs overlap
+	a, b, c = a*deg2rad, b*deg2rad, c*deg2rad
+	x := (c*c + a*a - b*b) / (2 * c)
+	y := math.Sqrt(math.Max(0, a*a-x*x))
+	overlap := a*a*math.Acos(math.Max(-1, math.Min(1, x/a))) +
+		b*b*math.Acos(math.Max(-1, math.Min(1, (c-x)/b))) - c*y
+	return math.Max(0, math.Min(1, 1-overlap/(math.Pi*a*a)))
+}
+
+// Sunlit reports whether a satellite at ECEF position pos sees any part of the sun,
+// being outside the umbra
+func Sunlit(pos Vector, t time.Time) bool {
+	return Illumination(pos, t) > 0
+}
+
+// TerminatorPoints returns [lon, lat] points along the day/night terminator
+func TerminatorPoints(sunLat, sunLon float64, segments int) [][2]float64 {
+	points := make([][2]float64, 0, segments+1)
+	tangent := math.Tan(sunLat * deg2rad)
+	for i := 0; i <= segments; i++ {
+		lon := -180 + float64(i)*360/float64(segments)
+		deltaLon := NormalizeLongitude(lon - sunLon)
+		lat := math.Atan(-math.Cos(deltaLon*deg2rad)/tangent) * rad2deg
+		points = append(points, [2]float64{lon, lat})
+	}
+	return points
+}
+
+// NightPolygon returns a closed [lon, lat] ring covering the night side of the earth at t
+func NightPolygon(t time.Time, segments int) [][2]float64 {
+	sunLat, sunLon := SubSolarPoint(t)
+	points := TerminatorPoints(sunLat, sunLon, segments)
+	poleLat := 90.0
+	if sunLat > 0 {
+		poleLat = -90
+	} else {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	first, last := points[0], points[len(points)-1]
+	ring := append(points, [2]float64{last[0], poleLat}, [2]float64{first[0], poleLat}, first)
+	return ring
+}
+
+// NormalizeLongitude wraps a longitude into [-180, 180]
+func NormalizeLongitude(lon float64) float64 {
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return lon
+}
@@ -0,0 +1,148 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Elements holds the mean orbital elements parsed from a Two-Line Element set
+type Elements struct {
+	NoradID      string
+	Epoch        time.Time
+	NDot         float64 // first derivative of mean motion divided by two, as on line 1 (rev/day^2)
+	NDDot        float64 // second derivative of mean motion (rev/day^3)
+	BStar        float64 // drag term (1/earth radii)
+	Inclination  float64 // degrees
+	RAAN         float64 // degrees
+	Eccentricity float64
+	ArgPerigee   float64 // degrees
+	MeanAnomaly  float64 // degrees
+	MeanMotion   float64 // rev/day
+	Line1        string
+	Line2        string
+}
+
+// ParseTLE parses the two data lines of a TLE set
+func ParseTLE(line1, line2 string) (*Elements, error) {
+	line1 = strings.TrimRight(line1, " \r\n")
+	line2 = strings.TrimRight(line2, " \r\n")
+
+	if len(line1) < 61 || !strings.HasPrefix(line1, "1 ") {
+		return nil, fmt.Errorf("invalid TLE line 1")
+	}
+	if len(line2) < 63 || !strings.HasPrefix(line2, "2 ") {
+		return nil, fmt.Errorf("invalid TLE line 2")
+	}
+
+	el := &Elements{Line1: line1, Line2: line2}
+
+	noradID, err := strconv.Atoi(strings.TrimSpace(line1[2:7]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog number: %v", err)
+	}
+	el.NoradID = strconv.Itoa(noradID)
+
+	year, err := strconv.Atoi(strings.TrimSpace(line1[18:20]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid epoch year: %v", err)
+	}
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	day, err := strconv.ParseFloat(strings.TrimSpace(line1[20:32]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid epoch day: %v", err)
+	}
+	el.Epoch = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration((day - 1) * 86400 * float64(time.Second)))
+
+	if el.NDot, err = strconv.ParseFloat(strings.TrimSpace(line1[33:43]), 64); err != nil {
+		return nil, fmt.Errorf("invalid mean motion derivative: %v", err)
+	}
+	if el.NDDot, err = parseExponent(line1[44:52]); err != nil {
+		return nil, fmt.Errorf("invalid mean motion second derivative: %v", err)
+	}
+	if el.BStar, err = parseExponent(line1[53:61]); err != nil {
+		return nil, fmt.Errorf("invalid BSTAR: %v", err)
+	}
+
+	fields := []struct {
+		dst  *float64
+		s    string
+		name string
+	}{
+		{&el.Inclination, line2[8:16], "inclination"},
+		{&el.RAAN, line2[17:25], "right ascension of ascending node"},
+		{&el.ArgPerigee, line2[34:42], "argument of perigee"},
+		{&el.MeanAnomaly, line2[43:51], "mean anomaly"},
+		{&el.MeanMotion, line2[52:63], "mean motion"},
+	}
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f.s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", f.name, err)
+		}
+		*f.dst = v
+	}
+
+	ecc, err := strconv.ParseFloat("0."+strings.TrimSpace(line2[26:33]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eccentricity: %v", err)
+	}
+	el.Eccentricity = ecc
+
+	if el.MeanMotion <= 0 {
+		return nil, fmt.Errorf("invalid mean motion: %v", el.MeanMotion)
+	}
+
+	return el, nil
+}
+
+// Period returns the orbital period derived from the mean motion
+func (el *Elements) Period() time.Duration {
+	return time.Duration(86400 / el.MeanMotion * float64(time.Second))
+}
+
+// SemiMajorAxis returns the semi-major axis in km derived from the mean motion
+func (el *Elements) SemiMajorAxis() float64 {
+	n := el.MeanMotion * 2 * math.Pi / 86400
+	return math.Cbrt(muWGS72 / (n * n))
+}
+
+// parseExponent parses the TLE implied-decimal exponent notation, e.g. " 66816-4" = 0.66816e-4
+func parseExponent(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	sign := 1.0
+	switch s[0] {
+	case '-':
+		sign = -1
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	idx := strings.LastIndexAny(s, "+-")
+	if idx <= 0 {
+		v, err := strconv.ParseFloat("0."+s, 64)
+		return sign * v, err
+	}
+
+	mantissa, err := strconv.ParseFloat("0."+strings.TrimSpace(s[:idx]), 64)
+	if err != nil {
+		return 0, err
+	}
+	exp, err := strconv.Atoi(s[idx:])
+	if err != nil {
+		return 0, err
+	}
+	return sign * mantissa * math.Pow(10, float64(exp)), nil
+}
@@ -0,0 +1,156 @@
+package planner
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+const (
+	// accessStep is the sampling step used to find passes over point targets
+	accessStep = 20 * time.Second
+	// maxAccessHalfWidth bounds the search for the edges of a pitched access window
+	maxAccessHalfWidth = 15 * time.Minute
+)
+
+// PointTarget is a point on the ground to be imaged
+type PointTarget struct {
+	ID       int
+	Name     string
+	Location orbit.Geodetic // Alt in km
+}
+
+// AccessLimits are optional limits on the viewing geometry of an access
+type AccessLimits struct {
+	MaxOffNadir  float64 // degrees, 0 for the sensor's side angle limits only
+	MinElevation float64 // degrees of the satellite above the target's horizon
+}
+
+// Access is a window during which a sensor can image a point target
+type Access struct {
+	Target       int // index into the targets
+	SensorID     int
+	Start        int64
+	End          int64
+	Time         int64   // time of the minimum off-nadir angle, when the satellite is abeam
+	Roll         float64 // roll to the target at Time, degrees, positive to the right
+	SideAngle    float64 // side angle to command so that the target is in the swath
+	OffNadir     float64 // minimum off-nadir angle, degrees
+	Elevation    float64 // satellite elevation seen from the target at Time, degrees
+	GSDCross     float64 // metres
+	GSDAlong     float64 // metres
+	SunElevation float64
+}
+
+// AccessWindows returns the windows between start and end in which the sensors of one
+// satellite can image each target within their side angle limits and the given limits.
+// Sensors that cannot pitch see a target only when the satellite is abeam of it, so their
+// windows start and end at Time; sensors with a max_pitch_angle can image it while the
+// line of sight stays within that pitch. SAR sensors see a target abeam, on their look
+// side and within their incidence range; sensors restricted to one orbit direction only
+// on those passes.
+func AccessWindows(prop *orbit.Propagator, sensors []models.Sensor, targets []PointTarget, start, end time.Time, limits AccessLimits) ([]Access, error) {
+	states, err := Sample(prop, start, end, accessStep)
+	if err != nil {
+		return nil, err
+	}
+	refAltitude := MeanAltitude(prop)
+	minElevation := math.Max(limits.MinElevation, 0)
+
+	accesses := []Access{}
+	for t, target := range targets {
+		point := orbit.GeodeticToECEF(target.Location)
+		pitch := func(s orbit.State) float64 { return lookAt(s, point).Pitch }
+
+		prev := lookAt(states[0], point)
+		for i := 1; i < len(states); i++ {
+			cur := lookAt(states[i], point)
+			crossing := prev.Pitch > 0 && cur.Pitch <= 0 && prev.Elevation > 0 && cur.Elevation > 0
+			prev = cur
+			if !crossing {
+				continue
+			}
+
+			abeam := refineCrossing(prop, states[i-1].Time, states[i].Time, pitch)
+			s, err := prop.StateAt(abeam)
+			if err != nil {
+				return nil, err
+			}
+			look := lookAt(s, point)
+			if look.Elevation < minElevation || (limits.MaxOffNadir > 0 && look.OffNadir > limits.MaxOffNadir) {
+				continue
+			}
+
+			direction := OrbitDirection(s)
+			for _, sensor := range sensors {
+				if sensor.OrbitDirection != "" && sensor.OrbitDirection != direction {
+					continue
+				}
+				sar := Sensor{Sensor: sensor}.IsSAR()
+				side := look.Roll - sensor.InitAngle
+				half := sensor.ObserveAngle / 2
+				if sar {
+					if !sarSees(Sensor{Sensor: sensor}, look) {
+						continue
+					}
+				} else if side < -sensor.LeftSideAngle-half || side > sensor.RightSideAngle+half {
+					continue
+				}
+				a := Access{
+					Target:       t,
+					SensorID:     sensor.ID,
+					Start:        look.Time,
+					End:          look.Time,
+					Time:         look.Time,
+					Roll:         look.Roll,
+					SideAngle:    math.Max(-sensor.LeftSideAngle, math.Min(sensor.RightSideAngle, side)),
+					OffNadir:     look.OffNadir,
+					Elevation:    look.Elevation,
+					SunElevation: orbit.SolarElevation(target.Location.Lat, target.Location.Lon, abeam),
+				}
+				a.GSDCross, a.GSDAlong = GSD(sensor.Resolution, refAltitude, look.Altitude, look.OffNadir)
+				if sar {
+					a.SideAngle = look.Roll
+					a.GSDCross, a.GSDAlong = sensor.Resolution, sensor.Resolution
+				}
+
+				if sensor.MaxPitchAngle > 0 && !sar {
+					inside := func(s orbit.State) float64 {
+						l := lookAt(s, point)
+						return math.Min(sensor.MaxPitchAngle-math.Abs(l.Pitch), l.Elevation-minElevation)
+					}
+					a.Start = accessEdge(prop, abeam, -accessStep, inside).Unix()
+					a.End = accessEdge(prop, abeam, accessStep, inside).Unix()
+				}
+				accesses = append(accesses, a)
+			}
+		}
+	}
+
+	sort.SliceStable(accesses, func(i, j int) bool { return accesses[i].Time < accesses[j].Time })
+	return accesses, nil
+}
+
+// accessEdge steps from t, where inside is non-negative, until inside turns negative and
+// returns the refined time of the change
+func accessEdge(prop *orbit.Propagator, t time.Time, step time.Duration, inside func(orbit.State) float64) time.Time {
+	last := t
+	for d := step; d.Abs() <= maxAccessHalfWidth; d += step {
+		next := t.Add(d)
+		s, err := prop.StateAt(next)
+		if err != nil {
+			return last
+		}
+		if inside(s) < 0 {
+			if step < 0 {
+				return refineCrossing(prop, next, last, inside)
+			}
+			return refineCrossing(prop, last, next, inside)
+		}
+		last = next
+	}
+	return last
+}
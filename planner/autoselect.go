@@ -0,0 +1,145 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+
+	"satplan/models"
+)
+
+// Objective selects the greedy strategy used by AutoSelect
+type Objective string
+
+const (
+	// MaxCoverage repeatedly picks the strip that adds the most new coverage
+	MaxCoverage Objective = "max-coverage"
+	// MinTime picks strips by the highest new coverage per second of observation
+	MinTime Objective = "min-time"
+	// MinStrips stops once the next-best strip adds less than 1% of the area
+	MinStrips Objective = "min-strips"
+)
+
+// DefaultGridSize matches the 25x25 grid used by the browser auto-select
+const DefaultGridSize = 25
+
+// AutoSelectOptions configures AutoSelect
+type AutoSelectOptions struct {
+	Objective Objective
+	// GridSize is the number of sample points per axis; ignored when Exact is set
+	GridSize int
+	// Exact measures coverage by the exact area of the union of strips instead of a grid
+	Exact bool
+	// Weights holds per-satellite cost weights keyed by NORAD ID. A strip's gain is
+	// divided by the weight of its satellite, so heavier satellites are picked last.
+	Weights map[string]float64
+}
+
+// AutoSelectResult is the outcome of AutoSelect
+type AutoSelectResult struct {
+	// Selected holds the indexes of the chosen strips in selection order
+	Selected []int
+	// Coverage is the percentage of the area covered by the chosen strips
+	Coverage float64
+}
+
+// AutoSelect runs the greedy strip selection of the browser's Auto-select dialog on the
+// given strips. With default options and unit weights it reproduces greedyAutoSelect in
+// script.js exactly.
+func AutoSelect(strips []models.Strip, area TargetArea, opts AutoSelectOptions) (AutoSelectResult, error) {
+	if !area.Valid() {
+		return AutoSelectResult{}, fmt.Errorf("invalid target area")
+	}
+	switch opts.Objective {
+	case MaxCoverage, MinTime, MinStrips:
+	case "":
+		opts.Objective = MaxCoverage
+	default:
+		return AutoSelectResult{}, fmt.Errorf("unknown objective %q", opts.Objective)
+	}
+	if opts.GridSize == 0 {
+		opts.GridSize = DefaultGridSize
+	}
+	if !opts.Exact && (opts.GridSize < 2 || opts.GridSize > 500) {
+		return AutoSelectResult{}, fmt.Errorf("grid size must be between 2 and 500")
+	}
+
+	weights := make([]float64, len(strips))
+	rings := make([][][2]float64, len(strips))
+	centerLon := (area.West + area.East) / 2
+	for i, s := range strips {
+		weights[i] = 1
+		if w, ok := opts.Weights[s.SatNoardID]; ok {
+			if w <= 0 {
+				return AutoSelectResult{}, fmt.Errorf("weight for satellite %s must be positive", s.SatNoardID)
+			}
+			weights[i] = w
+		}
+		rings[i] = alignRing(s.Coordinates, centerLon)
+	}
+
+	var model coverageModel
+	var threshold float64
+	if opts.Exact {
+		exact, err := newExactCoverage(rings, area)
+		if err != nil {
+			return AutoSelectResult{}, err
+		}
+		model = exact
+		threshold = model.total() * 0.01
+	} else {
+		model = newGridCoverage(rings, area, opts.GridSize)
+		threshold = math.Max(1, math.Floor(model.total()*0.01))
+	}
+	// gains below epsilon are treated as zero so that rounding in the exact model
+	// does not keep selecting strips that add nothing
+	epsilon := model.total() * 1e-9
+
+	selected := []int{}
+	remaining := make([]bool, len(strips))
+	for i := range remaining {
+		remaining[i] = true
+	}
+
+	for {
+		bestIdx, bestScore, bestGain := -1, 0.0, 0.0
+		if opts.Objective == MinTime {
+			bestScore = -1
+		}
+		for i := range strips {
+			if !remaining[i] {
+				continue
+			}
+			gain := model.gain(i)
+			var score float64
+			if opts.Objective == MinTime {
+				if gain <= epsilon {
+					remaining[i] = false
+					continue
+				}
+				duration := math.Max(1, float64(strips[i].StopTimestamp-strips[i].StartTimestamp))
+				score = gain / duration / weights[i]
+			} else {
+				score = gain / weights[i]
+			}
+			if score > bestScore {
+				bestIdx, bestScore, bestGain = i, score, gain
+			}
+		}
+
+		if bestIdx == -1 || bestGain <= epsilon {
+			break
+		}
+		if opts.Objective == MinStrips && bestGain < threshold {
+			break
+		}
+
+		selected = append(selected, bestIdx)
+		remaining[bestIdx] = false
+		model.add(bestIdx)
+	}
+
+	return AutoSelectResult{
+		Selected: selected,
+		Coverage: 100 * model.covered() / model.total(),
+	}, nil
+}
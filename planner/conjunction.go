@@ -0,0 +1,186 @@
+package planner
+
+import (
+	"math"
+	"time"
+
+	"satplan/orbit"
+)
+
+const (
+	// screenStep is the sampling step of the close-approach screening. Approaches are
+	// found from sign changes of the range rate, so the step only needs to be shorter
+	// than half an orbit.
+	screenStep = time.Minute
+	// shellMargin widens the perigee-apogee overlap test for drag and perturbations, km
+	shellMargin = 25.0
+)
+
+// ScreenObject is an object taking part in a close-approach screening. Approaches are
+// searched between every pair involving at least one primary object.
+type ScreenObject struct {
+	NoradID string
+	Name    string
+	Prop    *orbit.Propagator
+	Primary bool
+}
+
+// Conjunction is a close approach between two screened objects
+type Conjunction struct {
+	Primary          int // index into the objects
+	Secondary        int
+	TCA              time.Time
+	MissDistance     float64 // km
+	RelativeVelocity float64 // km/s
+	// Radial, InTrack and CrossTrack split the miss vector from the primary to the
+	// secondary in the primary's orbital frame, km
+	Radial     float64
+	InTrack    float64
+	CrossTrack float64
+}
+
+// ScreenStats counts what a screening covered
+type ScreenStats struct {
+	Pairs   int // pairs whose altitude shells overlap and were propagated together
+	Skipped int // objects that could not be propagated over the range
+}
+
+// track is an object's TEME states at the screening sample times
+type track struct {
+	pos, vel []orbit.Vector
+}
+
+// Screen searches the objects for approaches closer than threshold (km) between start
+// and end. Pairs whose perigee-apogee shells are further apart than the threshold are
+// not propagated together. Each approach is located where the range rate changes sign
+// and refined to a millisecond.
+func Screen(objects []ScreenObject, start, end time.Time, threshold float64) ([]Conjunction, ScreenStats) {
+	stats := ScreenStats{}
+	n := int(end.Sub(start)/screenStep) + 1
+	times := make([]time.Time, n)
+	for k := range times {
+		times[k] = start.Add(time.Duration(k) * screenStep)
+	}
+
+	tracks := make([]*track, len(objects))
+	failed := make([]bool, len(objects))
+	load := func(i int) *track {
+		if tracks[i] != nil || failed[i] {
+			return tracks[i]
+		}
+		t := &track{pos: make([]orbit.Vector, n), vel: make([]orbit.Vector, n)}
+		for k, tm := range times {
+			r, v, err := objects[i].Prop.Propagate(tm)
+			if err != nil {
+				failed[i] = true
+				stats.Skipped++
+				return nil
+			}
+			t.pos[k], t.vel[k] = r, v
+		}
+		tracks[i] = t
+		return t
+	}
+
+	conjunctions := []Conjunction{}
+	for j := range objects {
+		for i := range objects {
+			if i == j || !objects[i].Primary || (objects[j].Primary && j < i) {
+				continue
+			}
+			if !shellsOverlap(objects[i].Prop.Elements, objects[j].Prop.Elements, threshold) {
+				continue
+			}
+			a, b := load(i), load(j)
+			if a == nil || b == nil {
+				continue
+			}
+			stats.Pairs++
+			conjunctions = append(conjunctions, pairApproaches(objects, i, j, a, b, times, threshold)...)
+		}
+		// secondaries are only needed while their column of pairs is screened
+		if !objects[j].Primary {
+			tracks[j] = nil
+		}
+	}
+	return conjunctions, stats
+}
+
+// pairApproaches finds the approaches of objects i and j closer than threshold
+func pairApproaches(objects []ScreenObject, i, j int, a, b *track, times []time.Time, threshold float64) []Conjunction {
+	found := []Conjunction{}
+	rangeRate := func(k int) (float64, float64, float64) {
+		dr, dv := b.pos[k].Sub(a.pos[k]), b.vel[k].Sub(a.vel[k])
+		return dr.Dot(dv), dr.Norm(), dv.Norm()
+	}
+	prev, _, _ := rangeRate(0)
+	for k := 1; k < len(times); k++ {
+		cur, dist, speed := rangeRate(k)
+		closing := prev < 0 && cur >= 0
+		prev = cur
+		// the approach lies within one step before sample k; skip it when the objects
+		// cannot have come within the threshold in that time
+		if !closing || dist-speed*screenStep.Seconds() > threshold {
+			continue
+		}
+		c, ok := refineApproach(objects[i].Prop, objects[j].Prop, times[k-1], times[k])
+		if !ok || c.MissDistance > threshold {
+			continue
+		}
+		c.Primary, c.Secondary = i, j
+		found = append(found, c)
+	}
+	return found
+}
+
+// refineApproach bisects the range rate between lo, where the objects are closing, and
+// hi, where they are separating
+func refineApproach(p, q *orbit.Propagator, lo, hi time.Time) (Conjunction, bool) {
+	var r1, v1, r2, v2 orbit.Vector
+	state := func(t time.Time) (float64, bool) {
+		var err1, err2 error
+		r1, v1, err1 = p.Propagate(t)
+		r2, v2, err2 = q.Propagate(t)
+		if err1 != nil || err2 != nil {
+			return 0, false
+		}
+		return r2.Sub(r1).Dot(v2.Sub(v1)), true
+	}
+	for hi.Sub(lo) > time.Millisecond {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		f, ok := state(mid)
+		if !ok {
+			return Conjunction{}, false
+		}
+		if f < 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if _, ok := state(hi); !ok {
+		return Conjunction{}, false
+	}
+
+	miss := r2.Sub(r1)
+	radial := r1.Unit()
+	cross := r1.Cross(v1).Unit()
+	inTrack := cross.Cross(radial)
+	return Conjunction{
+		TCA:              hi,
+		MissDistance:     miss.Norm(),
+		RelativeVelocity: v2.Sub(v1).Norm(),
+		Radial:           miss.Dot(radial),
+		InTrack:          miss.Dot(inTrack),
+		CrossTrack:       miss.Dot(cross),
+	}, true
+}
+
+// shellsOverlap reports whether the perigee-apogee ranges of two orbits come within
+// threshold (km) of each other
+func shellsOverlap(a, b *orbit.Elements, threshold float64) bool {
+	perigeeA, apogeeA := a.SemiMajorAxis()*(1-a.Eccentricity), a.SemiMajorAxis()*(1+a.Eccentricity)
+	perigeeB, apogeeB := b.SemiMajorAxis()*(1-b.Eccentricity), b.SemiMajorAxis()*(1+b.Eccentricity)
+	gap := math.Max(perigeeA, perigeeB) - math.Min(apogeeA, apogeeB)
+	return gap <= threshold+shellMargin
+}
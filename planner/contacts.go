@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"satplan/orbit"
+)
+
+// contactStep is the sampling step used to find contact windows before refinement
+const contactStep = 20 * time.Second
+
+// Station is a ground station that can receive downlinks
+type Station struct {
+	ID           int
+	Name         string
+	Location     orbit.Geodetic // Alt in km
+	MinElevation float64        // degrees
+}
+
+// Contact is a window during which a satellite is above a station's elevation mask
+type Contact struct {
+	StationID    int
+	Station      string
+	Start        int64
+	End          int64
+	MaxElevation float64
+}
+
+// ContactWindows returns the contact windows of the satellite with each station between
+// start and end, sorted by start time. Window edges are refined to the second.
+func ContactWindows(prop *orbit.Propagator, stations []Station, start, end time.Time) ([]Contact, error) {
+	states, err := Sample(prop, start, end, contactStep)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := []Contact{}
+	for _, st := range stations {
+		elevation := func(s orbit.State) float64 {
+			_, el, _ := orbit.LookAngles(st.Location, s.Position)
+			return el - st.MinElevation
+		}
+
+		var current *Contact
+		for i, s := range states {
+			el := elevation(s)
+			if current == nil && el >= 0 {
+				aos := s.Time
+				if i > 0 {
+					aos = refineCrossing(prop, states[i-1].Time, s.Time, elevation)
+				}
+				current = &Contact{StationID: st.ID, Station: st.Name, Start: aos.Unix()}
+			}
+			if current == nil {
+				continue
+			}
+			if el >= 0 {
+				current.MaxElevation = math.Max(current.MaxElevation, el+st.MinElevation)
+				continue
+			}
+			current.End = refineCrossing(prop, states[i-1].Time, s.Time, elevation).Unix()
+			contacts = append(contacts, *current)
+			current = nil
+		}
+		if current != nil {
+			current.End = states[len(states)-1].Time.Unix()
+			contacts = append(contacts, *current)
+		}
+	}
+
+	sort.SliceStable(contacts, func(i, j int) bool { return contacts[i].Start < contacts[j].Start })
+	return contacts, nil
+}
+
+// refineCrossing bisects between a and b, whose elevations have opposite signs, for the
+// time at which f changes sign
+func refineCrossing(prop *orbit.Propagator, a, b time.Time, f func(orbit.State) float64) time.Time {
+	sa, err := prop.StateAt(a)
+	if err != nil {
+		return b
+	}
+	fa := f(sa)
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2)
+		sm, err := prop.StateAt(mid)
+		if err != nil {
+			return b
+		}
+		if fm := f(sm); (fm >= 0) == (fa >= 0) {
+			a, fa = mid, fm
+		} else {
+			b = mid
+		}
+	}
+	return b
+}
@@ -0,0 +1,306 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// coverageModel measures how much of the target area a set of strips covers
+type coverageModel interface {
+	// gain returns the area that strip i would add to the current coverage
+	gain(i int) float64
+	// add marks strip i as covered
+	add(i int)
+	// covered returns the area covered so far
+	covered() float64
+	// total returns the area of the target area
+	total() float64
+}
+
+// alignRing converts strip coordinates to [lon, lat] points whose longitudes are
+// continuous and within 180 degrees of refLon
+func alignRing(coords [][]float64, refLon float64) [][2]float64 {
+	ring := make([][2]float64, len(coords))
+	ref := refLon
+	for i, c := range coords {
+		if i == 0 {
+			ref = unwrapLongitude(c[0], refLon)
+		} else {
+			ref = unwrapLongitude(c[0], ref)
+		}
+		ring[i] = [2]float64{ref, c[1]}
+	}
+	return ring
+}
+
+// gridCoverage samples the area on a gridSize x gridSize lattice of points, the same
+// way computeGridCoverage does in script.js
+type gridCoverage struct {
+	points    int
+	stripHits [][]int
+	hit       []bool
+	count     int
+}
+
+func newGridCoverage(rings [][][2]float64, area TargetArea, gridSize int) *gridCoverage {
+	points := make([][2]float64, 0, gridSize*gridSize)
+	dLon := (area.East - area.West) / float64(gridSize-1)
+	dLat := (area.North - area.South) / float64(gridSize-1)
+	for i := 0; i < gridSize; i++ {
+		for j := 0; j < gridSize; j++ {
+			points = append(points, [2]float64{area.West + float64(i)*dLon, area.South + float64(j)*dLat})
+		}
+	}
+
+	g := &gridCoverage{
+		points:    len(points),
+		stripHits: make([][]int, len(rings)),
+		hit:       make([]bool, len(points)),
+	}
+	for i, ring := range rings {
+		if len(ring) < 3 {
+			continue
+		}
+		for idx, p := range points {
+			if PointInPolygon(p, ring) {
+				g.stripHits[i] = append(g.stripHits[i], idx)
+			}
+		}
+	}
+	return g
+}
+
+func (g *gridCoverage) gain(i int) float64 {
+	n := 0
+	for _, idx := range g.stripHits[i] {
+		if !g.hit[idx] {
+			n++
+		}
+	}
+	return float64(n)
+}
+
+func (g *gridCoverage) add(i int) {
+	for _, idx := range g.stripHits[i] {
+		if !g.hit[idx] {
+			g.hit[idx] = true
+			g.count++
+		}
+	}
+}
+
+func (g *gridCoverage) covered() float64 { return float64(g.count) }
+func (g *gridCoverage) total() float64   { return float64(g.points) }
+
+// exactCoverage computes the exact planar (lon/lat) area of the union of strips clipped
+// to the area. The area is cut into vertical slabs at every vertex and edge crossing, so
+// that inside a slab each strip covers a fixed set of trapezoids and the covered length
+// varies linearly; the area of a slab is then its width times the covered length at its
+// centre line.
+type exactCoverage struct {
+	widths    []float64
+	intervals [][][][2]float64 // [strip][slab] -> covered y-intervals at the slab centre
+	union     [][][2]float64   // [slab] -> union of selected intervals
+	area      float64
+	coveredA  float64
+}
+
+// Limits of the exact model: every pair of clipped edges is tested for a crossing, and
+// each strip keeps its intervals in every slab
+const (
+	maxExactEdges = 5000
+	maxExactCells = 2000000 // strips × slabs
+)
+
+func newExactCoverage(rings [][][2]float64, area TargetArea) (*exactCoverage, error) {
+	clipped := make([][][2]float64, len(rings))
+	xs := []float64{area.West, area.East}
+	type edge struct{ a, b [2]float64 }
+	edges := []edge{}
+	for i, ring := range rings {
+		clipped[i] = clipToArea(ring, area)
+		poly := clipped[i]
+		for k := range poly {
+			xs = append(xs, poly[k][0])
+			edges = append(edges, edge{poly[k], poly[(k+1)%len(poly)]})
+		}
+	}
+	if len(edges) > maxExactEdges {
+		return nil, fmt.Errorf("too many strip edges for the exact method (%d, at most %d); use the grid method", len(edges), maxExactEdges)
+	}
+	for i := 0; i < len(edges); i++ {
+		for j := i + 1; j < len(edges); j++ {
+			if x, ok := intersectionX(edges[i].a, edges[i].b, edges[j].a, edges[j].b); ok {
+				xs = append(xs, x)
+			}
+		}
+	}
+
+	sort.Float64s(xs)
+	slabs := [][2]float64{}
+	for i := 1; i < len(xs); i++ {
+		if xs[i]-xs[i-1] > 1e-12 && xs[i-1] >= area.West && xs[i] <= area.East {
+			slabs = append(slabs, [2]float64{xs[i-1], xs[i]})
+		}
+	}
+
+	if len(slabs)*len(rings) > maxExactCells {
+		return nil, fmt.Errorf("too many strips and slabs for the exact method (%d × %d, at most %d); use the grid method",
+			len(rings), len(slabs), maxExactCells)
+	}
+
+	e := &exactCoverage{
+		widths:    make([]float64, len(slabs)),
+		intervals: make([][][][2]float64, len(rings)),
+		union:     make([][][2]float64, len(slabs)),
+		area:      (area.East - area.West) * (area.North - area.South),
+	}
+	for s, slab := range slabs {
+		e.widths[s] = slab[1] - slab[0]
+	}
+	for i, poly := range clipped {
+		e.intervals[i] = make([][][2]float64, len(slabs))
+		if len(poly) < 3 {
+			continue
+		}
+		for s, slab := range slabs {
+			e.intervals[i][s] = crossingIntervals(poly, (slab[0]+slab[1])/2)
+		}
+	}
+	return e, nil
+}
+
+func (e *exactCoverage) gain(i int) float64 {
+	g := 0.0
+	for s, w := range e.widths {
+		if len(e.intervals[i][s]) == 0 {
+			continue
+		}
+		merged := mergeIntervals(append(append([][2]float64{}, e.union[s]...), e.intervals[i][s]...))
+		g += w * (intervalLength(merged) - intervalLength(e.union[s]))
+	}
+	return g
+}
+
+func (e *exactCoverage) add(i int) {
+	for s := range e.widths {
+		if len(e.intervals[i][s]) == 0 {
+			continue
+		}
+		e.union[s] = mergeIntervals(append(e.union[s], e.intervals[i][s]...))
+	}
+	e.coveredA = 0
+	for s, w := range e.widths {
+		e.coveredA += w * intervalLength(e.union[s])
+	}
+}
+
+func (e *exactCoverage) covered() float64 { return e.coveredA }
+func (e *exactCoverage) total() float64   { return e.area }
+
+// clipToArea clips a polygon ring to the area rectangle (Sutherland-Hodgman)
+func clipToArea(ring [][2]float64, area TargetArea) [][2]float64 {
+	poly := ring
+	if n := len(poly); n > 1 && poly[0] == poly[n-1] {
+		poly = poly[:n-1]
+	}
+	type boundary struct {
+		inside func(p [2]float64) bool
+		cut    func(a, b [2]float64) [2]float64
+	}
+	atX := func(x float64) func(a, b [2]float64) [2]float64 {
+		return func(a, b [2]float64) [2]float64 {
+			t := (x - a[0]) / (b[0] - a[0])
+			return [2]float64{x, a[1] + t*(b[1]-a[1])}
+		}
+	}
+	atY := func(y float64) func(a, b [2]float64) [2]float64 {
+		return func(a, b [2]float64) [2]float64 {
+			t := (y - a[1]) / (b[1] - a[1])
+			return [2]float64{a[0] + t*(b[0]-a[0]), y}
+		}
+	}
+	boundaries := []boundary{
+		{func(p [2]float64) bool { return p[0] >= area.West }, atX(area.West)},
+		{func(p [2]float64) bool { return p[0] <= area.East }, atX(area.East)},
+		{func(p [2]float64) bool { return p[1] >= area.South }, atY(area.South)},
+		{func(p [2]float64) bool { return p[1] <= area.North }, atY(area.North)},
+	}
+	for _, b := range boundaries {
+		if len(poly) == 0 {
+			break
+		}
+		out := [][2]float64{}
+		prev := poly[len(poly)-1]
+		for _, cur := range poly {
+			if b.inside(cur) {
+				if !b.inside(prev) {
+					out = append(out, b.cut(prev, cur))
+				}
+				out = append(out, cur)
+			} else if b.inside(prev) {
+				out = append(out, b.cut(prev, cur))
+			}
+			prev = cur
+		}
+		poly = out
+	}
+	return poly
+}
+
+// intersectionX returns the x coordinate where two segments properly cross
+func intersectionX(p1, p2, q1, q2 [2]float64) (float64, bool) {
+	d := (p2[0]-p1[0])*(q2[1]-q1[1]) - (p2[1]-p1[1])*(q2[0]-q1[0])
+	if d == 0 {
+		return 0, false
+	}
+	t := ((q1[0]-p1[0])*(q2[1]-q1[1]) - (q1[1]-p1[1])*(q2[0]-q1[0])) / d
+	u := ((q1[0]-p1[0])*(p2[1]-p1[1]) - (q1[1]-p1[1])*(p2[0]-p1[0])) / d
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return 0, false
+	}
+	return p1[0] + t*(p2[0]-p1[0]), true
+}
+
+// crossingIntervals returns the y-intervals covered by the polygon on the vertical line x
+func crossingIntervals(poly [][2]float64, x float64) [][2]float64 {
+	ys := []float64{}
+	for k := range poly {
+		a, b := poly[k], poly[(k+1)%len(poly)]
+		if (a[0] > x) != (b[0] > x) {
+			ys = append(ys, a[1]+(x-a[0])*(b[1]-a[1])/(b[0]-a[0]))
+		}
+	}
+	sort.Float64s(ys)
+	intervals := make([][2]float64, 0, len(ys)/2)
+	for k := 0; k+1 < len(ys); k += 2 {
+		intervals = append(intervals, [2]float64{ys[k], ys[k+1]})
+	}
+	return mergeIntervals(intervals)
+}
+
+func mergeIntervals(intervals [][2]float64) [][2]float64 {
+	if len(intervals) < 2 {
+		return intervals
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i][0] < intervals[j][0] })
+	merged := [][2]float64{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv[0] <= last[1] {
+			last[1] = math.Max(last[1], iv[1])
+		} else {
+			merged = append(merged, iv)
+		}
+	}
+	return merged
+}
+
+func intervalLength(intervals [][2]float64) float64 {
+	l := 0.0
+	for _, iv := range intervals {
+		l += iv[1] - iv[0]
+	}
+	return l
+}
@@ -0,0 +1,133 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/orbit"
+)
+
+const (
+	// reentryAltitude is the altitude at which a decaying orbit is taken to be lost, km
+	reentryAltitude = 120.0
+	// minTrendSpan is the shortest span of element sets fitted for a trend; shorter
+	// histories fall back to the mean motion derivative of the latest set
+	minTrendSpan = 2 * 24 * time.Hour
+)
+
+// Decay describes how an orbit is decaying, from the element set history or, without
+// enough history, from the mean motion derivative of the latest set
+type Decay struct {
+	Epoch          time.Time
+	MeanMotion     float64 // rev/day
+	MeanMotionRate float64 // rev/day²
+	Altitude       float64 // mean altitude, km
+	Perigee        float64 // km
+	Apogee         float64 // km
+	AltitudeRate   float64 // km/day, negative while decaying
+	BStar          float64 // 1/earth radii
+	Samples        int     // element sets fitted, 1 when the derivative was used
+	// Lifetime is the estimated time to reentry in days, 0 when the orbit is not decaying
+	Lifetime float64
+}
+
+// atmosphereLayers is the exponential atmosphere of Vallado, Fundamentals of
+// Astrodynamics, table 8-4: base altitude (km), density (kg/m³) and scale height (km)
+var atmosphereLayers = []struct{ base, density, scale float64 }{
+	{100, 5.297e-7, 5.877}, {110, 9.661e-8, 7.263}, {120, 2.438e-8, 9.473},
+	{130, 8.484e-9, 12.636}, {140, 3.845e-9, 16.149}, {150, 2.070e-9, 22.523},
+	{180, 5.464e-10, 29.740}, {200, 2.789e-10, 37.105}, {250, 7.248e-11, 45.546},
+	{300, 2.418e-11, 53.628}, {350, 9.518e-12, 53.298}, {400, 3.725e-12, 58.515},
+	{450, 1.585e-12, 60.828}, {500, 6.967e-13, 63.822}, {600, 1.454e-13, 71.835},
+	{700, 3.614e-14, 88.667}, {800, 1.170e-14, 124.64}, {900, 5.245e-15, 181.05},
+	{1000, 3.019e-15, 268.00},
+}
+
+// AtmosphericDensity returns the density of the exponential atmosphere at altitude (km)
+// in kg/m³
+func AtmosphericDensity(altitude float64) float64 {
+	i := sort.Search(len(atmosphereLayers), func(i int) bool { return atmosphereLayers[i].base > altitude }) - 1
+	if i < 0 {
+		i = 0
+	}
+	l := atmosphereLayers[i]
+	return l.density * math.Exp(-(altitude-l.base)/l.scale)
+}
+
+// OrbitDecay fits the decay of an orbit from its element sets within span before the
+// latest one. The sets may be in any order.
+func OrbitDecay(history []*orbit.Elements, span time.Duration) (Decay, error) {
+	if len(history) == 0 {
+		return Decay{}, fmt.Errorf("no TLE available")
+	}
+	sets := append([]*orbit.Elements(nil), history...)
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Epoch.Before(sets[j].Epoch) })
+	latest := sets[len(sets)-1]
+
+	a := latest.SemiMajorAxis()
+	d := Decay{
+		Epoch:      latest.Epoch,
+		MeanMotion: latest.MeanMotion,
+		Altitude:   a - orbit.MeanEarthRadius,
+		Perigee:    a*(1-latest.Eccentricity) - orbit.MeanEarthRadius,
+		Apogee:     a*(1+latest.Eccentricity) - orbit.MeanEarthRadius,
+		BStar:      latest.BStar,
+	}
+
+	// least-squares slopes of mean motion and altitude against days before the latest set
+	var n, sx, sxx, sn, sxn, sh, sxh float64
+	first := latest.Epoch
+	for _, el := range sets {
+		if latest.Epoch.Sub(el.Epoch) > span {
+			continue
+		}
+		x := el.Epoch.Sub(latest.Epoch).Hours() / 24
+		h := el.SemiMajorAxis() - orbit.MeanEarthRadius
+		n++
+		sx += x
+		sxx += x * x
+		sn += el.MeanMotion
+		sxn += x * el.MeanMotion
+		sh += h
+		sxh += x * h
+		if el.Epoch.Before(first) {
+			first = el.Epoch
+		}
+	}
+	if n >= 2 && latest.Epoch.Sub(first) >= minTrendSpan {
+		denom := n*sxx - sx*sx
+		d.MeanMotionRate = (n*sxn - sx*sn) / denom
+		d.AltitudeRate = (n*sxh - sx*sh) / denom
+		d.Samples = int(n)
+	} else {
+		// line 1 carries half the first derivative of mean motion
+		d.MeanMotionRate = 2 * latest.NDot
+		d.AltitudeRate = -2.0 / 3.0 * a / latest.MeanMotion * d.MeanMotionRate
+		d.Samples = 1
+	}
+	d.Lifetime = Lifetime(d.Perigee, d.AltitudeRate)
+	return d, nil
+}
+
+// Lifetime estimates the days until an orbit decaying at rate (km/day) from altitude
+// (km) reaches the reentry altitude. The decay rate is scaled with the density of the
+// exponential atmosphere as the orbit descends, ignoring solar activity, so the result
+// is a rough figure. It returns 0 when the orbit is not decaying.
+func Lifetime(altitude, rate float64) float64 {
+	if rate >= 0 {
+		return 0
+	}
+	if altitude <= reentryAltitude {
+		return 0
+	}
+	ref := AtmosphericDensity(altitude)
+	days := 0.0
+	const step = 1.0 // km
+	for h := altitude; h > reentryAltitude; h -= step {
+		dh := math.Min(step, h-reentryAltitude)
+		days += dh / (-rate * AtmosphericDensity(h-dh/2) / ref)
+	}
+	return days
+}
@@ -0,0 +1,163 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+// DutyCycle holds the operating limits of a sensor. Zero values mean no limit.
+type DutyCycle struct {
+	MaxOnTime       float64 // seconds of acquisition per orbit
+	MaxAcquisitions int     // acquisitions started per orbit
+	MinGap          float64 // seconds between the end of one acquisition and the next
+	// Period is the orbital period in seconds; per-orbit limits apply to every window
+	// of this length
+	Period float64
+	// Sunlit returns the sunlit fraction of the orbit window starting at a unix time.
+	// When set, MaxOnTime is scaled by it, so the sensor may only run for part of an
+	// orbit spent mostly in eclipse.
+	Sunlit func(start int64) float64
+}
+
+// DutyCycleOf returns the duty-cycle limits of a sensor on a satellite propagated by prop.
+// Eclipse scaling is only enabled for sensors that ask for it.
+func DutyCycleOf(s models.Sensor, prop *orbit.Propagator) DutyCycle {
+	d := DutyCycle{
+		MaxOnTime:       s.MaxOnTime,
+		MaxAcquisitions: s.MaxAcquisitions,
+		MinGap:          s.MinGap,
+	}
+	if prop != nil {
+		d.Period = prop.Elements.Period().Seconds()
+		if s.EclipseAware && s.MaxOnTime > 0 {
+			d.Sunlit = SunlitFraction(prop, prop.Elements.Period())
+		}
+	}
+	return d
+}
+
+// Limited reports whether any limit is set
+func (d DutyCycle) Limited() bool {
+	return d.MaxOnTime > 0 || d.MaxAcquisitions > 0 || d.MinGap > 0
+}
+
+// SunlitFraction returns a function giving the sunlit fraction of the window
+// [start, start+window), as GET /eclipse reports it per orbit: the penumbra counts as half
+// lit. Results are cached per start time.
+func SunlitFraction(prop *orbit.Propagator, window time.Duration) func(start int64) float64 {
+	cache := map[int64]float64{}
+	return func(start int64) float64 {
+		if f, ok := cache[start]; ok {
+			return f
+		}
+		from := time.Unix(start, 0).UTC()
+		to := from.Add(window)
+		f := 1.0
+		if eclipses, _, err := Eclipses(prop, from, to); err == nil {
+			f = windowLight(eclipses, from.Unix(), to.Unix()).SunlitFraction
+		}
+		cache[start] = f
+		return f
+	}
+}
+
+// Check returns why strip c cannot be acquired in addition to the acquisitions of the
+// same sensor in taken, or "" if the limits allow it
+func (d DutyCycle) Check(taken []models.Strip, c models.Strip) string {
+	if !d.Limited() {
+		return ""
+	}
+	duration := float64(c.StopTimestamp - c.StartTimestamp)
+	if d.MaxOnTime > 0 && d.Sunlit == nil && duration > d.MaxOnTime {
+		return fmt.Sprintf("%s %s at %s runs %.0f s, more than the %.0f s allowed per orbit",
+			c.SatName, c.SensorName, formatTime(c.StartTimestamp), duration, d.MaxOnTime)
+	}
+
+	if d.MinGap > 0 {
+		for _, a := range taken {
+			gap := math.Max(float64(c.StartTimestamp-a.StopTimestamp), float64(a.StartTimestamp-c.StopTimestamp))
+			if gap < d.MinGap {
+				return fmt.Sprintf("%s %s at %s is %.0f s from its acquisition at %s, less than the %.0f s minimum gap",
+					c.SatName, c.SensorName, formatTime(c.StartTimestamp), math.Max(gap, 0),
+					formatTime(a.StartTimestamp), d.MinGap)
+			}
+		}
+	}
+
+	if d.Period <= 0 || (d.MaxOnTime <= 0 && d.MaxAcquisitions <= 0) {
+		return ""
+	}
+	all := append(append([]models.Strip{}, taken...), c)
+	sort.Slice(all, func(i, j int) bool { return all[i].StartTimestamp < all[j].StartTimestamp })
+
+	// the on-time in a sliding window peaks when the window starts at an acquisition
+	// start or ends at an acquisition stop; the count peaks at an acquisition start
+	period := int64(d.Period)
+	windows := []int64{}
+	for _, a := range all {
+		windows = append(windows, a.StartTimestamp, a.StopTimestamp-period)
+	}
+	for _, w := range windows {
+		end := w + period
+		if c.StopTimestamp <= w || c.StartTimestamp >= end {
+			continue
+		}
+		onTime, count := 0.0, 0
+		for _, a := range all {
+			from, to := max(a.StartTimestamp, w), min(a.StopTimestamp, end)
+			if to > from {
+				onTime += float64(to - from)
+			}
+			if a.StartTimestamp >= w && a.StartTimestamp < end {
+				count++
+			}
+		}
+		if d.MaxAcquisitions > 0 && count > d.MaxAcquisitions {
+			return fmt.Sprintf("%s %s at %s would be acquisition %d within one orbit from %s, more than the %d allowed",
+				c.SatName, c.SensorName, formatTime(c.StartTimestamp), count, formatTime(w), d.MaxAcquisitions)
+		}
+		if d.MaxOnTime > 0 {
+			limit := d.MaxOnTime
+			if d.Sunlit != nil {
+				limit *= d.Sunlit(w)
+			}
+			if onTime > limit {
+				return fmt.Sprintf("%s %s at %s would run %.0f s within one orbit from %s, more than the %.0f s allowed",
+					c.SatName, c.SensorName, formatTime(c.StartTimestamp), onTime, formatTime(w), limit)
+			}
+		}
+	}
+	return ""
+}
+
+// FlagDutyCycles marks the strips that cannot all be acquired under their sensor's
+// duty-cycle limits. Strips are taken in time order; a strip that would break a limit
+// given the earlier unflagged strips of the same sensor gets the reason in Violation.
+func FlagDutyCycles(strips []models.Strip, cycles map[int]DutyCycle) {
+	order := make([]int, len(strips))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return strips[order[a]].StartTimestamp < strips[order[b]].StartTimestamp
+	})
+
+	taken := map[int][]models.Strip{}
+	for _, i := range order {
+		s := &strips[i]
+		d, ok := cycles[s.SensorID]
+		if !ok {
+			continue
+		}
+		if reason := d.Check(taken[s.SensorID], *s); reason != "" {
+			s.Violation = reason
+			continue
+		}
+		taken[s.SensorID] = append(taken[s.SensorID], *s)
+	}
+}
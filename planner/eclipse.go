@@ -0,0 +1,126 @@
+package planner
+
+import (
+	"math"
+	"time"
+
+	"satplan/orbit"
+)
+
+// eclipseStep is the sampling step used to find shadow boundaries. The penumbra of a low
+// orbit is crossed in seconds, so both boundaries are searched within every step.
+const eclipseStep = 30 * time.Second
+
+// Eclipse is one passage through the earth's shadow, in unix seconds. UmbraStart and
+// UmbraEnd are 0 when only the penumbra was crossed. A passage under way at the start
+// or end of the search range is cut there.
+type Eclipse struct {
+	PenumbraStart int64
+	UmbraStart    int64
+	UmbraEnd      int64
+	PenumbraEnd   int64
+}
+
+// OrbitLight is the illumination of one orbit, from ascending node to ascending node, or
+// of another time window
+type OrbitLight struct {
+	Start    int64
+	End      int64
+	Umbra    float64 // seconds
+	Penumbra float64 // seconds in the penumbra only
+	// SunlitFraction weighs the penumbra by half, the mean visible fraction of the sun
+	SunlitFraction float64
+}
+
+// Eclipses finds the satellite's passages through the umbra and penumbra between start
+// and end, and the illumination of each complete orbit in the range
+func Eclipses(prop *orbit.Propagator, start, end time.Time) ([]Eclipse, []OrbitLight, error) {
+	states, err := Sample(prop, start, end, eclipseStep)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// negative inside the penumbra (including the umbra) and inside the umbra
+	penumbra := func(s orbit.State) float64 {
+		a, b, c := orbit.ShadowAngles(s.Position, s.Time)
+		return c - (a + b)
+	}
+	umbra := func(s orbit.State) float64 {
+		a, b, c := orbit.ShadowAngles(s.Position, s.Time)
+		return c - (b - a)
+	}
+	node := func(s orbit.State) float64 { return s.Position.Z }
+
+	eclipses := []Eclipse{}
+	nodes := []int64{}
+	var cur *Eclipse
+	prevP, prevU := penumbra(states[0]), umbra(states[0])
+	if prevP < 0 {
+		cur = &Eclipse{PenumbraStart: start.Unix()}
+		if prevU < 0 {
+			cur.UmbraStart = start.Unix()
+		}
+	}
+	for i := 1; i < len(states); i++ {
+		a, b := states[i-1], states[i]
+		p, u := penumbra(b), umbra(b)
+
+		if a.Position.Z < 0 && b.Position.Z >= 0 {
+			nodes = append(nodes, refineCrossing(prop, a.Time, b.Time, node).Unix())
+		}
+		if prevP >= 0 && p < 0 {
+			cur = &Eclipse{PenumbraStart: refineCrossing(prop, a.Time, b.Time, penumbra).Unix()}
+		}
+		if cur != nil && prevU >= 0 && u < 0 {
+			cur.UmbraStart = refineCrossing(prop, a.Time, b.Time, umbra).Unix()
+		}
+		if cur != nil && prevU < 0 && u >= 0 {
+			cur.UmbraEnd = refineCrossing(prop, a.Time, b.Time, umbra).Unix()
+		}
+		if cur != nil && prevP < 0 && p >= 0 {
+			cur.PenumbraEnd = refineCrossing(prop, a.Time, b.Time, penumbra).Unix()
+			eclipses = append(eclipses, *cur)
+			cur = nil
+		}
+		prevP, prevU = p, u
+	}
+	if cur != nil {
+		if cur.UmbraStart != 0 && cur.UmbraEnd == 0 {
+			cur.UmbraEnd = end.Unix()
+		}
+		cur.PenumbraEnd = end.Unix()
+		eclipses = append(eclipses, *cur)
+	}
+
+	orbits := make([]OrbitLight, 0, len(nodes))
+	for k := 1; k < len(nodes); k++ {
+		orbits = append(orbits, windowLight(eclipses, nodes[k-1], nodes[k]))
+	}
+	return eclipses, orbits, nil
+}
+
+// windowLight returns the time spent in the umbra and in the penumbra only between from
+// and to, and the sunlit fraction of that window. This is the one definition of the
+// sunlit fraction, used per orbit by Eclipses and per duty-cycle window by SunlitFraction.
+func windowLight(eclipses []Eclipse, from, to int64) OrbitLight {
+	o := OrbitLight{Start: from, End: to}
+	for _, e := range eclipses {
+		shadow := overlap(e.PenumbraStart, e.PenumbraEnd, from, to)
+		dark := 0.0
+		if e.UmbraStart != 0 {
+			dark = overlap(e.UmbraStart, e.UmbraEnd, from, to)
+		}
+		o.Umbra += dark
+		o.Penumbra += shadow - dark
+	}
+	o.SunlitFraction = 1
+	if length := float64(to - from); length > 0 {
+		o.SunlitFraction = math.Max(0, (length-o.Umbra-o.Penumbra/2)/length)
+	}
+	return o
+}
+
+// overlap returns the length in seconds of the intersection of [a0, a1] and [b0, b1]
+func overlap(a0, a1, b0, b1 int64) float64 {
+	return math.Max(0, float64(min(a1, b1)-max(a0, b0)))
+}
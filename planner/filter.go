@@ -0,0 +1,88 @@
+package planner
+
+import (
+	"math"
+
+	"satplan/models"
+)
+
+// DefaultGlintAngle is the glint angle below which a strip is considered to look into
+// the sun glint
+const DefaultGlintAngle = 25.0
+
+// StripFilter constrains which computed strips are kept
+type StripFilter struct {
+	// MinSunElevation and MaxSunElevation bound the sun elevation at the strip centre
+	MinSunElevation *float64
+	MaxSunElevation *float64
+	// ExcludeGlint drops sunlit strips whose glint angle is below GlintAngle. There is no
+	// land/water mask, so the test applies to any target; enable it for water targets.
+	ExcludeGlint bool
+	GlintAngle   float64
+	// MaxGSD drops strips whose cross- or along-track GSD exceeds it, in metres
+	MaxGSD *float64
+	// MinIncidence and MaxIncidence bound the incidence angles across the whole swath
+	MinIncidence *float64
+	MaxIncidence *float64
+	// OrbitDirection and LookSide keep the strips of one pass direction or look side, as
+	// interferometric stacks need
+	OrbitDirection string
+	LookSide       string
+	// ExcludeEclipse drops strips acquired while the satellite is in the earth's shadow,
+	// for sensors that cannot run on batteries
+	ExcludeEclipse bool
+	// ExcludeViolations drops strips flagged by FlagDutyCycles
+	ExcludeViolations bool
+}
+
+// Match reports whether the strip satisfies the filter
+func (f StripFilter) Match(s models.Strip) bool {
+	if f.MinSunElevation != nil && s.SunElevation < *f.MinSunElevation {
+		return false
+	}
+	if f.MaxSunElevation != nil && s.SunElevation > *f.MaxSunElevation {
+		return false
+	}
+	if f.ExcludeGlint {
+		limit := f.GlintAngle
+		if limit == 0 {
+			limit = DefaultGlintAngle
+		}
+		if s.SunElevation > 0 && s.GlintAngle < limit {
+			return false
+		}
+	}
+	if f.MaxGSD != nil && math.Max(s.GSDCrossTrack, s.GSDAlongTrack) > *f.MaxGSD {
+		return false
+	}
+	if f.MinIncidence != nil && s.IncidenceNear < *f.MinIncidence {
+		return false
+	}
+	if f.MaxIncidence != nil && s.IncidenceFar > *f.MaxIncidence {
+		return false
+	}
+	if f.OrbitDirection != "" && s.OrbitDirection != f.OrbitDirection {
+		return false
+	}
+	if f.LookSide != "" && s.LookSide != f.LookSide {
+		return false
+	}
+	if f.ExcludeEclipse && s.SatIllumination < 1 {
+		return false
+	}
+	if f.ExcludeViolations && s.Violation != "" {
+		return false
+	}
+	return true
+}
+
+// Apply returns the strips that satisfy the filter
+func (f StripFilter) Apply(strips []models.Strip) []models.Strip {
+	kept := make([]models.Strip, 0, len(strips))
+	for _, s := range strips {
+		if f.Match(s) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,170 @@
+package planner
+
+import (
+	"math"
+
+	"satplan/orbit"
+)
+
+const (
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+)
+
+// TargetArea is a lon/lat bounding box, mirroring TargetArea in the WASM module
+type TargetArea struct {
+	West  float64 `json:"min_lon"`
+	East  float64 `json:"max_lon"`
+	North float64 `json:"max_lat"`
+	South float64 `json:"min_lat"`
+}
+
+// Contains reports whether the [lon, lat] point lies inside the area
+func (a TargetArea) Contains(p [2]float64) bool {
+	return p[0] >= a.West && p[0] <= a.East && p[1] >= a.South && p[1] <= a.North
+}
+
+// Corners returns the corners of the area as a closed [lon, lat] ring
+func (a TargetArea) Corners() [][2]float64 {
+	return [][2]float64{
+		{a.West, a.South}, {a.East, a.South}, {a.East, a.North}, {a.West, a.North}, {a.West, a.South},
+	}
+}
+
+// Valid reports whether the area has a positive extent within lon/lat limits
+func (a TargetArea) Valid() bool {
+	return a.West < a.East && a.South < a.North &&
+		a.West >= -180 && a.East <= 180 && a.South >= -90 && a.North <= 90
+}
+
+// Intersects reports whether the polygon overlaps the area. The polygon longitudes
+// are unwrapped so that they are continuous with each other and with the area.
+func (a TargetArea) Intersects(polygon [][2]float64) bool {
+	if len(polygon) == 0 {
+		return false
+	}
+	ref := unwrapLongitude(polygon[0][0], (a.West+a.East)/2)
+	poly := make([][2]float64, len(polygon))
+	for i, p := range polygon {
+		ref = unwrapLongitude(p[0], ref)
+		poly[i] = [2]float64{ref, p[1]}
+	}
+
+	for _, p := range poly {
+		if a.Contains(p) {
+			return true
+		}
+	}
+	corners := a.Corners()
+	for _, c := range corners[:4] {
+		if PointInPolygon(c, poly) {
+			return true
+		}
+	}
+	for i := 0; i < len(poly); i++ {
+		p1, p2 := poly[i], poly[(i+1)%len(poly)]
+		for j := 0; j < 4; j++ {
+			if segmentsIntersect(p1, p2, corners[j], corners[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PointInPolygon tests a [lon, lat] point against a polygon ring by ray casting
+func PointInPolygon(point [2]float64, polygon [][2]float64) bool {
+	x, y := point[0], point[1]
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := polygon[i][0], polygon[i][1]
+		xj, yj := polygon[j][0], polygon[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func segmentsIntersect(p1, p2, q1, q2 [2]float64) bool {
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func cross(o, a, b [2]float64) float64 {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+// DestinationPoint returns the [lon, lat] reached by travelling the given central angle
+// (radians) from (lat, lon) along the azimuth (degrees clockwise from north)
+func DestinationPoint(lat, lon, azimuth, angle float64) [2]float64 {
+	lat1, lon1, az := lat*deg2rad, lon*deg2rad, azimuth*deg2rad
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(angle) + math.Cos(lat1)*math.Sin(angle)*math.Cos(az))
+	lon2 := lon1 + math.Atan2(math.Sin(az)*math.Sin(angle)*math.Cos(lat1),
+		math.Cos(angle)-math.Sin(lat1)*math.Sin(lat2))
+	return [2]float64{orbit.NormalizeLongitude(lon2 * rad2deg), lat2 * rad2deg}
+}
+
+// CentralAngle returns the earth central angle (radians) between the sub-satellite point
+// and the ground point seen at the given off-nadir angle (degrees) from radius r (km).
+// Angles beyond the horizon are clamped to the horizon.
+func CentralAngle(r, offNadir float64) float64 {
+	sinRho := orbit.MeanEarthRadius / r
+	sinEta := math.Sin(math.Abs(offNadir) * deg2rad)
+	if sinEta >= sinRho {
+		return math.Pi/2 - math.Asin(sinRho)
+	}
+	epsilon := math.Acos(sinEta / sinRho)
+	return math.Pi/2 - math.Abs(offNadir)*deg2rad - epsilon
+}
+
+// GroundPoint returns the [lon, lat] seen from the satellite state at the given roll
+// angle (degrees, positive to the right of the ground track)
+func GroundPoint(s orbit.State, roll float64) [2]float64 {
+	if roll == 0 {
+		return [2]float64{s.Geodetic.Lon, s.Geodetic.Lat}
+	}
+	azimuth := s.Heading() + 90
+	if roll < 0 {
+		azimuth = s.Heading() - 90
+	}
+	return DestinationPoint(s.Geodetic.Lat, s.Geodetic.Lon, azimuth, CentralAngle(s.Position.Norm(), roll))
+}
+
+// HaversineAngle returns the central angle (radians) between two [lon, lat] points
+func HaversineAngle(a, b [2]float64) float64 {
+	lat1, lat2 := a[1]*deg2rad, b[1]*deg2rad
+	dLat := lat2 - lat1
+	dLon := (b[0] - a[0]) * deg2rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// unwrapLongitude shifts lon by a multiple of 360 so that it is within 180 of ref
+func unwrapLongitude(lon, ref float64) float64 {
+	for lon-ref > 180 {
+		lon -= 360
+	}
+	for lon-ref < -180 {
+		lon += 360
+	}
+	return lon
+}
+
+// Centroid returns the mean [lon, lat] of a strip ring, like getRegionCentroid in script.js
+func Centroid(coords [][]float64) [2]float64 {
+	if len(coords) == 0 {
+		return [2]float64{}
+	}
+	var sumLon, sumLat float64
+	for _, c := range coords {
+		sumLon += c[0]
+		sumLat += c[1]
+	}
+	n := float64(len(coords))
+	return [2]float64{orbit.NormalizeLongitude(sumLon / n), sumLat / n}
+}
@@ -0,0 +1,42 @@
+package planner
+
+import (
+	"math"
+
+	"satplan/orbit"
+)
+
+// MeanAltitude returns the altitude in km of the satellite's mean orbit, the altitude at
+// which sensor resolutions are taken to be specified
+func MeanAltitude(prop *orbit.Propagator) float64 {
+	return prop.Elements.SemiMajorAxis() - orbit.MeanEarthRadius
+}
+
+// GSD returns the cross-track and along-track ground sample distance in metres of a
+// sensor with the given nadir GSD at refAltitude (km), seen from altitude (km) at the
+// off-nadir angle (degrees). The pixel footprint grows with the slant range in both
+// directions and is stretched across track by the obliquity of the line of sight.
+func GSD(nadirGSD, refAltitude, altitude, offNadir float64) (cross, along float64) {
+	if refAltitude <= 0 || altitude <= 0 {
+		return nadirGSD, nadirGSD
+	}
+	rng, incidence := SlantRange(altitude, offNadir)
+	ifov := nadirGSD / refAltitude
+	along = ifov * rng
+	cross = along / math.Cos(incidence*deg2rad)
+	return cross, along
+}
+
+// SlantRange returns the range in km from a satellite at altitude (km) to the ground point
+// seen at the off-nadir angle (degrees), and the incidence angle in degrees of the line of
+// sight at that point. Angles beyond the horizon are clamped to the horizon.
+func SlantRange(altitude, offNadir float64) (float64, float64) {
+	r := orbit.MeanEarthRadius
+	eta := math.Abs(offNadir) * deg2rad
+	if eta == 0 {
+		return altitude, 0
+	}
+	sinTheta := math.Min(1, (r+altitude)/r*math.Sin(eta))
+	theta := math.Asin(sinTheta)
+	return r * math.Sin(theta-eta) / math.Sin(eta), theta * rad2deg
+}
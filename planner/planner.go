@@ -0,0 +1,220 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+// DefaultStep is the propagation step used to sample ground tracks
+const DefaultStep = 5 * time.Second
+
+// Sensor is a sensor pointed at a fixed side angle for the duration of a plan
+type Sensor struct {
+	models.Sensor
+	SideAngle float64
+}
+
+// Roll returns the boresight roll angle: the mounting angle plus the commanded side angle
+func (s Sensor) Roll() float64 {
+	return s.InitAngle + s.SideAngle
+}
+
+// EdgeRolls returns the roll angles of the left and right swath edges
+func (s Sensor) EdgeRolls() (float64, float64) {
+	return s.Roll() - s.ObserveAngle/2, s.Roll() + s.ObserveAngle/2
+}
+
+// SideAngles returns the side angles at which a sensor can be pointed to reach targets
+// across its whole field of regard: nadir first, then outwards in steps of one field of
+// view, ending exactly on the left and right limits. A SAR sensor's side angle only
+// chooses its look side, so it gets one per side it can look to.
+func SideAngles(sensor models.Sensor) []float64 {
+	if sensor.SensorType == SAR {
+		if sensor.LookSide == LookBoth {
+			return []float64{1, -1}
+		}
+		return []float64{0}
+	}
+	angles := []float64{0}
+	step := sensor.ObserveAngle
+	if step <= 0 {
+		step = 1
+	}
+	for a := step; a < sensor.RightSideAngle || a < sensor.LeftSideAngle; a += step {
+		if a < sensor.LeftSideAngle {
+			angles = append(angles, -a)
+		}
+		if a < sensor.RightSideAngle {
+			angles = append(angles, a)
+		}
+	}
+	if sensor.LeftSideAngle > 0 {
+		angles = append(angles, -sensor.LeftSideAngle)
+	}
+	if sensor.RightSideAngle > 0 {
+		angles = append(angles, sensor.RightSideAngle)
+	}
+	return angles
+}
+
+// Sample propagates the satellite from start to end at the given step and returns the
+// earth-fixed states. The end time is always included.
+func Sample(prop *orbit.Propagator, start, end time.Time, step time.Duration) ([]orbit.State, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	if step <= 0 {
+		step = DefaultStep
+	}
+
+	states := make([]orbit.State, 0, int(end.Sub(start)/step)+2)
+	for t := start; ; t = t.Add(step) {
+		if t.After(end) {
+			t = end
+		}
+		s, err := prop.StateAt(t)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+		if !t.Before(end) {
+			break
+		}
+	}
+	return states, nil
+}
+
+// SensorInRegion computes the strips swept over the target area by the sensors of one
+// satellite between start and end. It is the server-side counterpart of
+// Calculator.SensorInRegion in the WASM module.
+func SensorInRegion(prop *orbit.Propagator, satName string, sensors []Sensor, start, end time.Time, area TargetArea) ([]models.Strip, error) {
+	if !area.Valid() {
+		return nil, fmt.Errorf("invalid target area")
+	}
+
+	states, err := Sample(prop, start, end, DefaultStep)
+	if err != nil {
+		return nil, err
+	}
+
+	refAltitude := MeanAltitude(prop)
+	strips := []models.Strip{}
+	for _, sensor := range sensors {
+		if sensor.IsSAR() {
+			sensor = sarBeam(sensor, refAltitude)
+		}
+		strips = append(strips, sensorStrips(states, prop.Elements.NoradID, satName, sensor, area, refAltitude)...)
+	}
+
+	sort.SliceStable(strips, func(i, j int) bool {
+		return strips[i].StartTimestamp < strips[j].StartTimestamp
+	})
+	return strips, nil
+}
+
+// SwathEdges returns the left and right swath edge points of the sensor at each state
+func SwathEdges(states []orbit.State, sensor Sensor) ([][2]float64, [][2]float64) {
+	leftRoll, rightRoll := sensor.EdgeRolls()
+	left := make([][2]float64, len(states))
+	right := make([][2]float64, len(states))
+	for i, s := range states {
+		left[i] = GroundPoint(s, leftRoll)
+		right[i] = GroundPoint(s, rightRoll)
+	}
+	return left, right
+}
+
+// sensorStrips finds the contiguous runs of swath segments that overlap the area, on
+// passes in the sensor's orbit direction. refAltitude is the altitude at which the
+// sensor's resolution applies.
+func sensorStrips(states []orbit.State, noradID, satName string, sensor Sensor, area TargetArea, refAltitude float64) []models.Strip {
+	left, right := SwathEdges(states, sensor)
+
+	strips := []models.Strip{}
+	first := -1
+	for i := 0; i < len(states)-1; i++ {
+		quad := [][2]float64{left[i], left[i+1], right[i+1], right[i]}
+		if area.Intersects(quad) {
+			if first < 0 {
+				first = i
+			}
+			continue
+		}
+		if first >= 0 {
+			strips = append(strips, buildStrip(states, left, right, first, i, noradID, satName, sensor, refAltitude))
+			first = -1
+		}
+	}
+	if first >= 0 {
+		strips = append(strips, buildStrip(states, left, right, first, len(states)-1, noradID, satName, sensor, refAltitude))
+	}
+	if sensor.OrbitDirection == "" {
+		return strips
+	}
+	kept := strips[:0]
+	for _, s := range strips {
+		if s.OrbitDirection == sensor.OrbitDirection {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// buildStrip assembles the strip polygon between sample indexes from and to (inclusive)
+func buildStrip(states []orbit.State, left, right [][2]float64, from, to int, noradID, satName string, sensor Sensor, refAltitude float64) models.Strip {
+	ring := make([][]float64, 0, 2*(to-from+1)+1)
+	ref := left[from][0]
+	for i := from; i <= to; i++ {
+		lon := unwrapLongitude(left[i][0], ref)
+		ring = append(ring, []float64{lon, left[i][1]})
+		ref = lon
+	}
+	for i := to; i >= from; i-- {
+		lon := unwrapLongitude(right[i][0], ref)
+		ring = append(ring, []float64{lon, right[i][1]})
+		ref = lon
+	}
+	ring = append(ring, []float64{ring[0][0], ring[0][1]})
+
+	// illumination, GSD and incidence are evaluated at the centre of the strip, seen from
+	// the middle sample along the boresight. A SAR resolution does not degrade with range.
+	mid := states[(from+to)/2]
+	center := Centroid(ring)
+	lat, lon := center[1], center[0]
+	gsdCross, gsdAlong := sensor.Resolution, sensor.Resolution
+	if !sensor.IsSAR() {
+		gsdCross, gsdAlong = GSD(sensor.Resolution, refAltitude, mid.Geodetic.Alt, sensor.Roll())
+	}
+	leftRoll, rightRoll := sensor.EdgeRolls()
+	incNear, incFar := IncidenceRange(mid.Geodetic.Alt, leftRoll, rightRoll)
+
+	return models.Strip{
+		SatNoardID:      noradID,
+		SatName:         satName,
+		SensorID:        sensor.ID,
+		SensorName:      sensor.Name,
+		HexColor:        sensor.HexColor,
+		Resolution:      sensor.Resolution,
+		SideAngle:       sensor.SideAngle,
+		OffNadirAngle:   math.Abs(sensor.Roll()),
+		StartTimestamp:  states[from].Time.Unix(),
+		StopTimestamp:   states[to].Time.Unix(),
+		SunElevation:    orbit.SolarElevation(lat, lon, mid.Time),
+		SunAzimuth:      orbit.SolarAzimuth(lat, lon, mid.Time),
+		GlintAngle:      orbit.GlintAngle(lat, lon, mid.Position, mid.Time),
+		GSDCrossTrack:   gsdCross,
+		GSDAlongTrack:   gsdAlong,
+		IncidenceNear:   incNear,
+		IncidenceFar:    incFar,
+		LookSide:        sensor.Side(),
+		OrbitDirection:  OrbitDirection(mid),
+		SatIllumination: orbit.Illumination(mid.Position, mid.Time),
+		DataRate:        SensorDataRate(sensor.Sensor),
+		Coordinates:     ring,
+	}
+}
@@ -0,0 +1,168 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+)
+
+// DefaultMergeGap is the interval within which accesses to a cell count as one visit,
+// so that several sensors on the same pass are not reported as a revisit
+const DefaultMergeGap = 10 * time.Minute
+
+// RevisitOptions configures Revisit
+type RevisitOptions struct {
+	// GridSize is the number of cells per axis
+	GridSize int
+	// MergeGap merges accesses to a cell that are closer than this into one visit
+	MergeGap time.Duration
+}
+
+// Revisit computes per-cell revisit statistics and the cumulative coverage over time of
+// the area from the given strips. The area is divided into GridSize x GridSize cells and a
+// cell is imaged by a strip when the strip polygon contains the cell centre; the time of
+// the visit is the time the swath crosses the centre.
+func Revisit(strips []models.Strip, area TargetArea, start, end time.Time, opts RevisitOptions) (models.RevisitAnalysis, error) {
+	if !area.Valid() {
+		return models.RevisitAnalysis{}, fmt.Errorf("invalid target area")
+	}
+	if opts.GridSize == 0 {
+		opts.GridSize = DefaultGridSize
+	}
+	if opts.GridSize < 1 || opts.GridSize > 200 {
+		return models.RevisitAnalysis{}, fmt.Errorf("grid size must be between 1 and 200")
+	}
+	if opts.MergeGap < 0 {
+		return models.RevisitAnalysis{}, fmt.Errorf("merge gap must not be negative")
+	}
+
+	n := opts.GridSize
+	dLon := (area.East - area.West) / float64(n)
+	dLat := (area.North - area.South) / float64(n)
+	centerLon := (area.West + area.East) / 2
+
+	rings := make([][][2]float64, len(strips))
+	for i, s := range strips {
+		rings[i] = alignRing(s.Coordinates, centerLon)
+	}
+
+	analysis := models.RevisitAnalysis{
+		StartTime:        start.Unix(),
+		EndTime:          end.Unix(),
+		GridSize:         n,
+		CellWidth:        dLon,
+		CellHeight:       dLat,
+		MergeGap:         int64(opts.MergeGap / time.Second),
+		Strips:           len(strips),
+		CoverageOverTime: []models.CoverageSample{{Time: start.Unix(), Coverage: 0}},
+		Cells:            make([]models.RevisitCell, 0, n*n),
+	}
+
+	firstAccesses := []int64{}
+	allGaps := []float64{}
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; col++ {
+			p := [2]float64{area.West + (float64(col)+0.5)*dLon, area.South + (float64(row)+0.5)*dLat}
+			cell := models.RevisitCell{Row: row, Col: col, Lon: p[0], Lat: p[1]}
+
+			times := []int64{}
+			for i, ring := range rings {
+				if t, ok := accessTime(strips[i], ring, p); ok {
+					times = append(times, t)
+				}
+			}
+			visits := mergeAccesses(times, int64(opts.MergeGap/time.Second))
+			cell.Accesses = len(visits)
+			if len(visits) > 0 {
+				cell.FirstAccess = visits[0]
+				firstAccesses = append(firstAccesses, visits[0])
+			}
+
+			gaps := make([]float64, 0, len(visits))
+			for k := 1; k < len(visits); k++ {
+				gaps = append(gaps, float64(visits[k]-visits[k-1]))
+			}
+			cell.MinGap, cell.MeanGap, cell.MaxGap = gapStats(gaps)
+			allGaps = append(allGaps, gaps...)
+
+			analysis.Cells = append(analysis.Cells, cell)
+		}
+	}
+
+	analysis.MinGap, analysis.MeanGap, analysis.MaxGap = gapStats(allGaps)
+
+	total := float64(n * n)
+	sort.Slice(firstAccesses, func(i, j int) bool { return firstAccesses[i] < firstAccesses[j] })
+	for k, t := range firstAccesses {
+		if k+1 < len(firstAccesses) && firstAccesses[k+1] == t {
+			continue
+		}
+		analysis.CoverageOverTime = append(analysis.CoverageOverTime, models.CoverageSample{
+			Time:     t,
+			Coverage: 100 * float64(k+1) / total,
+		})
+	}
+	analysis.Coverage = 100 * float64(len(firstAccesses)) / total
+	if len(firstAccesses) == n*n {
+		ttf := firstAccesses[len(firstAccesses)-1] - start.Unix()
+		analysis.TimeToFullCoverage = &ttf
+	}
+
+	return analysis, nil
+}
+
+// accessTime returns the time at which the strip's swath crosses point p. Strip polygons
+// are built from the left edge forward and the right edge backward, one vertex per
+// DefaultStep sample, so the segment between samples k and k+1 is the quad
+// (left[k], left[k+1], right[k+1], right[k]).
+func accessTime(strip models.Strip, ring [][2]float64, p [2]float64) (int64, bool) {
+	if len(ring) < 4 || !PointInPolygon(p, ring) {
+		return 0, false
+	}
+	samples := (len(ring) - 1) / 2
+	step := int64(DefaultStep / time.Second)
+	for k := 0; k < samples-1; k++ {
+		quad := [][2]float64{ring[k], ring[k+1], ring[2*samples-2-k], ring[2*samples-1-k]}
+		if PointInPolygon(p, quad) {
+			t := strip.StartTimestamp + int64(k)*step + step/2
+			if t > strip.StopTimestamp {
+				t = strip.StopTimestamp
+			}
+			return t, true
+		}
+	}
+	// the point is on a boundary shared by two quads; fall back to the strip's mid time
+	return (strip.StartTimestamp + strip.StopTimestamp) / 2, true
+}
+
+// mergeAccesses sorts access times and drops those within mergeGap seconds of the
+// previous visit
+func mergeAccesses(times []int64, mergeGap int64) []int64 {
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	visits := []int64{}
+	for _, t := range times {
+		if len(visits) > 0 && t-visits[len(visits)-1] < mergeGap {
+			continue
+		}
+		visits = append(visits, t)
+	}
+	return visits
+}
+
+// gapStats returns the minimum, mean and maximum of gaps, or nils when there are none
+func gapStats(gaps []float64) (*float64, *float64, *float64) {
+	if len(gaps) == 0 {
+		return nil, nil, nil
+	}
+	min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, g := range gaps {
+		min = math.Min(min, g)
+		max = math.Max(max, g)
+		sum += g
+	}
+	mean := sum / float64(len(gaps))
+	return &min, &mean, &max
+}
@@ -0,0 +1,113 @@
+package planner
+
+import (
+	"math"
+
+	"satplan/orbit"
+)
+
+// Sensor types
+const (
+	Optical = "optical"
+	SAR     = "sar"
+)
+
+// Look sides
+const (
+	LookRight = "right"
+	LookLeft  = "left"
+	LookBoth  = "both"
+)
+
+// Orbit directions
+const (
+	Ascending  = "ascending"
+	Descending = "descending"
+)
+
+// IsSAR reports whether the sensor is a radar whose geometry is set by its incidence range
+func (s Sensor) IsSAR() bool {
+	return s.SensorType == SAR
+}
+
+// Side returns the side the sensor looks to. A SAR sensor that can look both ways looks
+// left when its side angle is negative; an optical sensor looks to the side of its
+// boresight, "" at nadir.
+func (s Sensor) Side() string {
+	if s.IsSAR() {
+		if s.LookSide == LookLeft || (s.LookSide == LookBoth && s.SideAngle < 0) {
+			return LookLeft
+		}
+		return LookRight
+	}
+	return sideOf(s.Roll())
+}
+
+// sideOf returns the side of a roll angle
+func sideOf(roll float64) string {
+	switch {
+	case roll < 0:
+		return LookLeft
+	case roll > 0:
+		return LookRight
+	}
+	return ""
+}
+
+// LookAngle returns the off-nadir angle in degrees at which a satellite at altitude (km)
+// sees the ground at the incidence angle (degrees)
+func LookAngle(altitude, incidence float64) float64 {
+	r := orbit.MeanEarthRadius
+	return math.Asin(r/(r+altitude)*math.Sin(incidence*deg2rad)) * rad2deg
+}
+
+// sarBeam returns the optical equivalent of a SAR sensor seen from altitude (km): a
+// boresight at the centre of its incidence range on its look side, with a field of view
+// spanning the range. The side angle becomes the signed look angle of the beam centre,
+// so that slews between strips follow the look side.
+func sarBeam(s Sensor, altitude float64) Sensor {
+	near, far := LookAngle(altitude, s.NearIncidence), LookAngle(altitude, s.FarIncidence)
+	beam := s
+	beam.InitAngle = 0
+	beam.SideAngle = (near + far) / 2
+	if s.Side() == LookLeft {
+		beam.SideAngle = -beam.SideAngle
+	}
+	beam.ObserveAngle = far - near
+	return beam
+}
+
+// IncidenceRange returns the incidence angles in degrees at the near and far edges of a
+// swath between the edge rolls, seen from altitude (km). A swath across the nadir has a
+// near incidence of 0.
+func IncidenceRange(altitude, leftRoll, rightRoll float64) (float64, float64) {
+	near, far := math.Abs(leftRoll), math.Abs(rightRoll)
+	if near > far {
+		near, far = far, near
+	}
+	if leftRoll <= 0 && rightRoll >= 0 {
+		near = 0
+	}
+	_, nearInc := SlantRange(altitude, near)
+	_, farInc := SlantRange(altitude, far)
+	return nearInc, farInc
+}
+
+// OrbitDirection returns whether the satellite is moving north or south
+func OrbitDirection(s orbit.State) string {
+	if s.Velocity.Z < 0 {
+		return Descending
+	}
+	return Ascending
+}
+
+// sarSees reports whether a SAR sensor can image a point seen with the given look, on
+// one of its look sides and within its incidence range
+func sarSees(s Sensor, l Look) bool {
+	side := sideOf(l.Roll)
+	if s.LookSide != LookBoth && side != s.Side() {
+		return false
+	}
+	_, incidence := SlantRange(l.Altitude, l.OffNadir)
+	return incidence >= s.NearIncidence && incidence <= s.FarIncidence
+}
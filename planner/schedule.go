@@ -0,0 +1,257 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+)
+
+// Agility describes how fast a satellite can re-point its sensors
+type Agility struct {
+	RollRate          float64 // deg/s
+	PitchRate         float64 // deg/s
+	RollAcceleration  float64 // deg/s²
+	PitchAcceleration float64 // deg/s²
+	SettleTime        float64 // seconds
+}
+
+// AgilityOf returns the agility attributes of a satellite
+func AgilityOf(sat models.Satellite) Agility {
+	return Agility{
+		RollRate:          sat.MaxRollRate,
+		PitchRate:         sat.MaxPitchRate,
+		RollAcceleration:  sat.RollAcceleration,
+		PitchAcceleration: sat.PitchAcceleration,
+		SettleTime:        sat.SettleTime,
+	}
+}
+
+// SlewTime returns the seconds needed to re-point by dRoll and dPitch degrees and settle.
+// Both axes slew at the same time; no time is needed when the pointing does not change.
+func (a Agility) SlewTime(dRoll, dPitch float64) float64 {
+	if dRoll == 0 && dPitch == 0 {
+		return 0
+	}
+	t := math.Max(axisSlewTime(math.Abs(dRoll), a.RollRate, a.RollAcceleration),
+		axisSlewTime(math.Abs(dPitch), a.PitchRate, a.PitchAcceleration))
+	return t + a.SettleTime
+}
+
+// axisSlewTime returns the duration of a rest-to-rest rotation with a trapezoidal (or,
+// for short slews, triangular) rate profile. An unknown rate means an instantaneous slew.
+func axisSlewTime(angle, rate, accel float64) float64 {
+	if angle == 0 || rate <= 0 {
+		return 0
+	}
+	if accel <= 0 {
+		return angle / rate
+	}
+	if angle <= rate*rate/accel {
+		return 2 * math.Sqrt(angle/accel)
+	}
+	return angle/rate + rate/accel
+}
+
+// TaskRequest asks for one acquisition over a target area; any of its candidate strips
+// satisfies it
+type TaskRequest struct {
+	ID         int
+	Name       string
+	Candidates []models.Strip
+	// Rejected holds the reason candidates were dropped before scheduling, if any
+	Rejected string
+}
+
+// Acquisition is a strip placed on a satellite timeline
+type Acquisition struct {
+	Request int
+	Name    string
+	Strip   models.Strip
+	// SlewTime is the time needed to re-point from the previous acquisition, in seconds
+	SlewTime float64
+	// Volume is the data recorded, and RecorderFill the recorder content once the
+	// acquisition ends, in Mbit; both are zero when the recorder is not modelled
+	Volume       float64
+	RecorderFill float64
+}
+
+// Unscheduled explains why a request could not be fitted
+type Unscheduled struct {
+	Request    int
+	Name       string
+	Candidates int
+	Reason     string
+}
+
+// ScheduleOptions configures Schedule
+type ScheduleOptions struct {
+	// Agility holds the agility of each satellite keyed by NORAD ID
+	Agility map[string]Agility
+	// Storage holds the recorder and contacts of each satellite keyed by NORAD ID;
+	// satellites without an entry have unlimited storage
+	Storage map[string]Storage
+	// DutyCycles holds the duty-cycle limits of each sensor keyed by sensor ID
+	DutyCycles map[int]DutyCycle
+}
+
+// ScheduleResult holds the per-satellite timelines and the requests left out
+type ScheduleResult struct {
+	Timelines   map[string][]Acquisition
+	Downlinks   map[string][]Downlink
+	Unscheduled []Unscheduled
+}
+
+// Schedule fits one acquisition per request onto conflict-free satellite timelines.
+// Requests are taken in the given order, so earlier requests win conflicts; each gets
+// its earliest candidate that leaves enough time to slew and settle between it and the
+// neighbouring acquisitions of the same satellite. Strips of one satellite with the same
+// side angle share a pointing and may overlap. A candidate is also skipped when it would
+// break its sensor's duty-cycle limits or, for satellites with a modelled recorder, when
+// its data would overflow the recorder before the next downlink.
+func Schedule(requests []TaskRequest, opts ScheduleOptions) ScheduleResult {
+	result := ScheduleResult{
+		Timelines:   map[string][]Acquisition{},
+		Downlinks:   map[string][]Downlink{},
+		Unscheduled: []Unscheduled{},
+	}
+
+	for _, req := range requests {
+		if len(req.Candidates) == 0 {
+			reason := req.Rejected
+			if reason == "" {
+				reason = "no strip over the target area in the time window"
+			}
+			result.Unscheduled = append(result.Unscheduled, Unscheduled{Request: req.ID, Name: req.Name, Reason: reason})
+			continue
+		}
+
+		candidates := append([]models.Strip{}, req.Candidates...)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].StartTimestamp < candidates[j].StartTimestamp
+		})
+
+		placed := false
+		firstConflict := ""
+		for _, c := range candidates {
+			timeline := result.Timelines[c.SatNoardID]
+			conflict := findConflict(timeline, c, opts.Agility[c.SatNoardID])
+			if conflict == "" {
+				conflict = dutyCycleConflict(timeline, c, opts.DutyCycles)
+			}
+			if conflict == "" {
+				// insert into a copy so that a rejected candidate leaves the timeline intact
+				tentative := append([]Acquisition{}, timeline...)
+				tentative = insertAcquisition(tentative, Acquisition{Request: req.ID, Name: req.Name, Strip: c})
+				conflict = storageConflict(tentative, c, opts)
+				if conflict == "" {
+					result.Timelines[c.SatNoardID] = tentative
+					placed = true
+					break
+				}
+			}
+			if firstConflict == "" {
+				firstConflict = conflict
+			}
+		}
+
+		if !placed {
+			result.Unscheduled = append(result.Unscheduled, Unscheduled{
+				Request:    req.ID,
+				Name:       req.Name,
+				Candidates: len(candidates),
+				Reason:     fmt.Sprintf("all %d candidate strip(s) conflict with scheduled acquisitions, duty cycles or storage; earliest: %s", len(candidates), firstConflict),
+			})
+		}
+	}
+
+	for noradID, timeline := range result.Timelines {
+		agility := opts.Agility[noradID]
+		for i := 1; i < len(timeline); i++ {
+			timeline[i].SlewTime = agility.SlewTime(timeline[i].Strip.SideAngle-timeline[i-1].Strip.SideAngle, 0)
+		}
+		if storage, ok := opts.Storage[noradID]; ok {
+			run := simulateRecorder(timeline, storage)
+			for i := range timeline {
+				timeline[i].Volume = run.volumes[i]
+				timeline[i].RecorderFill = run.fills[i]
+			}
+			result.Downlinks[noradID] = run.downlinks
+		}
+	}
+	return result
+}
+
+// dutyCycleConflict returns why strip c would break its sensor's duty-cycle limits given
+// the acquisitions already on the timeline, or "" if it would not
+func dutyCycleConflict(timeline []Acquisition, c models.Strip, cycles map[int]DutyCycle) string {
+	d, ok := cycles[c.SensorID]
+	if !ok {
+		return ""
+	}
+	taken := []models.Strip{}
+	for _, a := range timeline {
+		if a.Strip.SensorID == c.SensorID {
+			taken = append(taken, a.Strip)
+		}
+	}
+	return d.Check(taken, c)
+}
+
+// storageConflict returns why the timeline, which already holds strip c, overflows the
+// satellite's recorder, or "" if it fits or the recorder is not modelled
+func storageConflict(timeline []Acquisition, c models.Strip, opts ScheduleOptions) string {
+	storage, ok := opts.Storage[c.SatNoardID]
+	if !ok {
+		return ""
+	}
+	run := simulateRecorder(timeline, storage)
+	if run.overflowAt == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s %s at %s would fill the %.1f Gbit recorder at %s before the next downlink",
+		c.SatName, c.SensorName, formatTime(c.StartTimestamp), storage.Capacity/1000, formatTime(run.overflowAt))
+}
+
+// findConflict returns why strip c cannot be added to the timeline, or "" if it can
+func findConflict(timeline []Acquisition, c models.Strip, agility Agility) string {
+	for _, a := range timeline {
+		if a.Strip.SideAngle == c.SideAngle {
+			continue
+		}
+		first, second := a.Strip, c
+		if c.StartTimestamp < a.Strip.StartTimestamp {
+			first, second = c, a.Strip
+		}
+		slew := agility.SlewTime(second.SideAngle-first.SideAngle, 0)
+		free := float64(second.StartTimestamp - first.StopTimestamp)
+		if free >= slew {
+			continue
+		}
+		if free < 0 {
+			return fmt.Sprintf("%s %s at %s overlaps %q pointed at %.1f°",
+				c.SatName, c.SensorName, formatTime(c.StartTimestamp), a.Name, a.Strip.SideAngle)
+		}
+		return fmt.Sprintf("%s %s at %s needs %.1f s to slew %.1f° and settle but only %.0f s are free next to %q",
+			c.SatName, c.SensorName, formatTime(c.StartTimestamp), slew,
+			math.Abs(second.SideAngle-first.SideAngle), free, a.Name)
+	}
+	return ""
+}
+
+// insertAcquisition adds a to the timeline keeping it sorted by start time
+func insertAcquisition(timeline []Acquisition, a Acquisition) []Acquisition {
+	i := sort.Search(len(timeline), func(i int) bool {
+		return timeline[i].Strip.StartTimestamp > a.Strip.StartTimestamp
+	})
+	timeline = append(timeline, Acquisition{})
+	copy(timeline[i+1:], timeline[i:])
+	timeline[i] = a
+	return timeline
+}
+
+func formatTime(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05")
+}
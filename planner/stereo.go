@@ -0,0 +1,330 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+const (
+	// stereoMargin is how far before and after a strip the satellite may look forward or
+	// back at the target
+	stereoMargin = 150 * time.Second
+	// alongTrackStep is the spacing of the along-track look times considered
+	alongTrackStep = 5
+	// samePass is the time within which two looks of one satellite belong to one pass
+	samePass = 30 * time.Minute
+	// stereoDwell is the time a look spends imaging the scene around the target point
+	stereoDwell = 3 * time.Second
+)
+
+// Stereo acquisition types
+const (
+	AlongTrack = "along_track"
+	CrossPass  = "cross_pass"
+)
+
+// Look is a view of the target from one satellite position
+type Look struct {
+	Strip     models.Strip // the strip whose pointing the look uses
+	Time      int64
+	Pitch     float64 // degrees, positive forward
+	Roll      float64 // degrees to the target, positive to the right
+	OffNadir  float64 // degrees
+	Azimuth   float64 // azimuth of the satellite seen from the target, degrees
+	Elevation float64 // elevation of the satellite seen from the target, degrees
+	Position  orbit.Vector
+	Altitude  float64 // km
+	// Pass numbers the strip the look was derived from; looks of one pass share it
+	Pass int
+}
+
+// StereoOptions constrains the combinations searched by Stereo
+type StereoOptions struct {
+	Images         int // 2 for pairs, 3 for triplets
+	AlongTrack     bool
+	CrossPass      bool
+	MinConvergence float64 // degrees
+	MaxConvergence float64 // degrees, 0 for no limit
+	MinBH          float64 // base-to-height ratio, 0 for no limit
+	MaxBH          float64
+	MaxTimeSpan    time.Duration // 0 for no limit
+	Limit          int
+	// Agility is used to check the pitch slews of along-track combinations
+	Agility map[string]Agility
+}
+
+// StereoCandidate is a combination of looks meeting the stereo constraints
+type StereoCandidate struct {
+	Type        string
+	Looks       []Look
+	Convergence float64 // degrees, between the outermost looks
+	BH          float64
+	TimeSpan    int64 // seconds
+	MaxOffNadir float64
+	Score       float64
+}
+
+// StereoLooks returns the looks at the target point ([lon, lat]) available during a
+// strip. The broadside look is taken when the satellite passes abeam of the target; with
+// alongTrack set and a sensor that can pitch, looks forward and back within its pitch
+// limit are added every alongTrackStep seconds. No look is returned when the target is
+// outside the sensor's swath.
+func StereoLooks(prop *orbit.Propagator, strip models.Strip, sensor Sensor, target [2]float64, alongTrack bool) ([]Look, error) {
+	start := time.Unix(strip.StartTimestamp, 0).UTC().Add(-stereoMargin)
+	end := time.Unix(strip.StopTimestamp, 0).UTC().Add(stereoMargin)
+	states, err := Sample(prop, start, end, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	point := orbit.GeodeticToECEF(orbit.Geodetic{Lat: target[1], Lon: target[0]})
+	looks := make([]Look, len(states))
+	broadside := -1
+	for i, s := range states {
+		looks[i] = lookAt(s, point)
+		looks[i].Strip = strip
+		if broadside < 0 || math.Abs(looks[i].Pitch) < math.Abs(looks[broadside].Pitch) {
+			broadside = i
+		}
+	}
+
+	inSwath := func(l Look) bool {
+		return l.Elevation > 0 && math.Abs(l.Roll-sensor.Roll()) <= sensor.ObserveAngle/2
+	}
+
+	kept := []Look{}
+	if inSwath(looks[broadside]) {
+		kept = append(kept, looks[broadside])
+	}
+	if alongTrack && sensor.MaxPitchAngle > 0 {
+		for i := 0; i < len(looks); i += alongTrackStep {
+			if i == broadside || math.Abs(looks[i].Pitch) > sensor.MaxPitchAngle || !inSwath(looks[i]) {
+				continue
+			}
+			kept = append(kept, looks[i])
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Time < kept[j].Time })
+	return kept, nil
+}
+
+// lookAt computes the pointing from state s to the earth-fixed point
+func lookAt(s orbit.State, point orbit.Vector) Look {
+	nadir := s.Position.Scale(-1).Unit()
+	along := s.Velocity.Sub(nadir.Scale(s.Velocity.Dot(nadir))).Unit()
+	right := nadir.Cross(along)
+	view := point.Sub(s.Position).Unit()
+
+	down := view.Dot(nadir)
+	az, el, _ := orbit.LookAngles(orbit.ECEFToGeodetic(point), s.Position)
+	return Look{
+		Time:      s.Time.Unix(),
+		Pitch:     math.Atan2(view.Dot(along), down) * rad2deg,
+		Roll:      math.Atan2(view.Dot(right), down) * rad2deg,
+		OffNadir:  math.Acos(math.Max(-1, math.Min(1, down))) * rad2deg,
+		Azimuth:   az,
+		Elevation: el,
+		Position:  s.Position,
+		Altitude:  s.Geodetic.Alt,
+	}
+}
+
+// convergence returns the angle in degrees between the views of the target from two
+// looks and their base-to-height ratio
+func convergence(a, b Look, point orbit.Vector) (float64, float64) {
+	va, vb := a.Position.Sub(point).Unit(), b.Position.Sub(point).Unit()
+	angle := math.Acos(math.Max(-1, math.Min(1, va.Dot(vb)))) * rad2deg
+	base := a.Position.Sub(b.Position).Norm()
+	return angle, base / ((a.Altitude + b.Altitude) / 2)
+}
+
+// Stereo searches the looks at the target point ([lon, lat]) for pairs or triplets
+// meeting the convergence, base-to-height and time span constraints. Along-track
+// combinations come from one pass, with time between the looks to image the target and
+// slew the pitch; cross-pass combinations use the broadside looks of different passes.
+// Only the best combination of each set of passes is kept. Candidates are ranked by
+// score, which weighs closeness of the geometry to the middle of the allowed range (60%),
+// short time span (20%) and low off-nadir angles (20%).
+func Stereo(looks []Look, target [2]float64, window time.Duration, opts StereoOptions) ([]StereoCandidate, error) {
+	if opts.Images != 2 && opts.Images != 3 {
+		return nil, fmt.Errorf("images must be 2 or 3")
+	}
+	point := orbit.GeodeticToECEF(orbit.Geodetic{Lat: target[1], Lon: target[0]})
+
+	sorted := append([]Look{}, looks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+	maxSpan := opts.MaxTimeSpan
+	if maxSpan <= 0 {
+		maxSpan = window
+	}
+
+	best := map[string]StereoCandidate{}
+	consider := func(combo []Look) {
+		kind, ok := stereoType(combo, opts)
+		if !ok {
+			return
+		}
+		first, last := combo[0], combo[len(combo)-1]
+		span := last.Time - first.Time
+		if opts.MaxTimeSpan > 0 && time.Duration(span)*time.Second > opts.MaxTimeSpan {
+			return
+		}
+		conv, bh := convergence(first, last, point)
+		if conv < opts.MinConvergence || (opts.MaxConvergence > 0 && conv > opts.MaxConvergence) {
+			return
+		}
+		if bh < opts.MinBH || (opts.MaxBH > 0 && bh > opts.MaxBH) {
+			return
+		}
+		for k := 1; k < len(combo); k++ {
+			if c, _ := convergence(combo[k-1], combo[k], point); c < opts.MinConvergence/2 {
+				return
+			}
+		}
+
+		c := StereoCandidate{
+			Type:        kind,
+			Looks:       append([]Look{}, combo...),
+			Convergence: conv,
+			BH:          bh,
+			TimeSpan:    span,
+		}
+		for _, l := range combo {
+			c.MaxOffNadir = math.Max(c.MaxOffNadir, l.OffNadir)
+		}
+		c.Score = stereoScore(c, opts, maxSpan)
+
+		key := kind
+		for _, l := range combo {
+			key += fmt.Sprintf("/%d", l.Pass)
+		}
+		if prev, ok := best[key]; !ok || c.Score > prev.Score {
+			best[key] = c
+		}
+	}
+
+	// along-track combinations are searched within each pass and cross-pass ones among
+	// the broadside looks only
+	groups := map[int][]Look{}
+	broadside := []Look{}
+	for _, l := range sorted {
+		groups[l.Pass] = append(groups[l.Pass], l)
+		if math.Abs(l.Pitch) <= 1 {
+			broadside = append(broadside, l)
+		}
+	}
+	if opts.AlongTrack {
+		for _, g := range groups {
+			combinations(g, opts.Images, consider)
+		}
+	}
+	if opts.CrossPass {
+		combinations(broadside, opts.Images, consider)
+	}
+
+	candidates := make([]StereoCandidate, 0, len(best))
+	for _, c := range best {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Looks[0].Time < candidates[j].Looks[0].Time
+	})
+	if opts.Limit > 0 && len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+	}
+	return candidates, nil
+}
+
+// combinations calls f with every time-ordered combination of size looks
+func combinations(looks []Look, size int, f func([]Look)) {
+	n := len(looks)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if size == 2 {
+				f([]Look{looks[i], looks[j]})
+				continue
+			}
+			for k := j + 1; k < n; k++ {
+				f([]Look{looks[i], looks[j], looks[k]})
+			}
+		}
+	}
+}
+
+// stereoType classifies a time-ordered combination of looks, reporting false when it is
+// neither a feasible along-track nor a cross-pass combination allowed by opts
+func stereoType(combo []Look, opts StereoOptions) (string, bool) {
+	pass := combo[0].Pass
+	samePassCombo := true
+	for _, l := range combo[1:] {
+		if l.Pass != pass {
+			samePassCombo = false
+		}
+	}
+
+	if samePassCombo {
+		if !opts.AlongTrack {
+			return "", false
+		}
+		// each look images the scene around the target, not the whole strip, before the
+		// satellite pitches to the next one
+		agility := opts.Agility[combo[0].Strip.SatNoardID]
+		for k := 1; k < len(combo); k++ {
+			slew := agility.SlewTime(0, combo[k].Pitch-combo[k-1].Pitch)
+			if float64(combo[k].Time-combo[k-1].Time) < stereoDwell.Seconds()+slew {
+				return "", false
+			}
+		}
+		return AlongTrack, true
+	}
+
+	if !opts.CrossPass {
+		return "", false
+	}
+	for i := range combo {
+		if math.Abs(combo[i].Pitch) > 1 {
+			return "", false // only broadside looks are combined across passes
+		}
+		for j := i + 1; j < len(combo); j++ {
+			a, b := combo[i], combo[j]
+			if a.Strip.SatNoardID == b.Strip.SatNoardID && time.Duration(b.Time-a.Time)*time.Second < samePass {
+				return "", false
+			}
+		}
+	}
+	return CrossPass, true
+}
+
+// stereoScore rates a candidate between 0 and 1
+func stereoScore(c StereoCandidate, opts StereoOptions, maxSpan time.Duration) float64 {
+	geometry := 1.0
+	switch {
+	case opts.MaxConvergence > 0:
+		mid, half := (opts.MinConvergence+opts.MaxConvergence)/2, (opts.MaxConvergence-opts.MinConvergence)/2
+		if half > 0 {
+			geometry = 1 - math.Abs(c.Convergence-mid)/half
+		}
+	case opts.MaxBH > 0:
+		mid, half := (opts.MinBH+opts.MaxBH)/2, (opts.MaxBH-opts.MinBH)/2
+		if half > 0 {
+			geometry = 1 - math.Abs(c.BH-mid)/half
+		}
+	}
+
+	span := 1.0
+	if maxSpan > 0 {
+		span = 1 - math.Min(1, float64(c.TimeSpan)/maxSpan.Seconds())
+	}
+	offNadir := 1 - math.Min(1, c.MaxOffNadir/90)
+
+	return 0.6*math.Max(0, geometry) + 0.2*span + 0.2*offNadir
+}
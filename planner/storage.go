@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"math"
+	"sort"
+
+	"satplan/models"
+)
+
+const (
+	// groundSpeed is the typical ground track speed of a low earth orbit in m/s
+	groundSpeed = 6800.0
+	// bitsPerPixel is assumed for sensors without an explicit data rate
+	bitsPerPixel = 8.0
+)
+
+// SensorDataRate returns the data rate of a sensor in Mbit/s. Sensors without an explicit
+// data rate are assumed to produce one 8-bit sample per ground pixel: width/resolution
+// pixels per line and groundSpeed/resolution lines per second.
+func SensorDataRate(s models.Sensor) float64 {
+	if s.DataRate > 0 {
+		return s.DataRate
+	}
+	if s.Resolution <= 0 || s.Width <= 0 {
+		return 0
+	}
+	pixelsPerLine := s.Width * 1000 / s.Resolution
+	linesPerSecond := groundSpeed / s.Resolution
+	return pixelsPerLine * linesPerSecond * bitsPerPixel / 1e6
+}
+
+// Storage describes a satellite's recorder and its downlink opportunities
+type Storage struct {
+	Capacity     float64 // Mbit
+	DownlinkRate float64 // Mbit/s
+	Contacts     []Contact
+}
+
+// Downlink is a contact window with the volume downlinked during it
+type Downlink struct {
+	Contact
+	Volume       float64 // Mbit
+	RecorderFill float64 // Mbit left on the recorder at the end of the contact
+}
+
+// recorderRun is the outcome of simulating a recorder over a timeline
+type recorderRun struct {
+	overflowAt int64   // first time the recorder is full, 0 if it never is
+	peak       float64 // Mbit
+	volumes    []float64
+	fills      []float64 // recorder fill at the end of each acquisition
+	downlinks  []Downlink
+}
+
+// simulateRecorder plays the acquisitions and contacts of one satellite forward in time.
+// Acquisitions record at the data rate of their strip and contacts drain the recorder at the
+// downlink rate; both may overlap.
+func simulateRecorder(timeline []Acquisition, storage Storage) recorderRun {
+	run := recorderRun{
+		volumes:   make([]float64, len(timeline)),
+		fills:     make([]float64, len(timeline)),
+		downlinks: make([]Downlink, len(storage.Contacts)),
+	}
+
+	times := []int64{}
+	for i, a := range timeline {
+		times = append(times, a.Strip.StartTimestamp, a.Strip.StopTimestamp)
+		run.volumes[i] = a.Strip.DataRate * float64(a.Strip.StopTimestamp-a.Strip.StartTimestamp)
+	}
+	for i, c := range storage.Contacts {
+		times = append(times, c.Start, c.End)
+		run.downlinks[i].Contact = c
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	fill := 0.0
+	for k := 0; k+1 < len(times); k++ {
+		t0, t1 := times[k], times[k+1]
+		if t1 == t0 {
+			continue
+		}
+		dt := float64(t1 - t0)
+
+		in := 0.0
+		for _, a := range timeline {
+			if a.Strip.StartTimestamp <= t0 && a.Strip.StopTimestamp >= t1 {
+				in += a.Strip.DataRate
+			}
+		}
+		contact := -1
+		for i, c := range storage.Contacts {
+			if c.Start <= t0 && c.End >= t1 {
+				contact = i
+				break
+			}
+		}
+		out := 0.0
+		if contact >= 0 {
+			out = storage.DownlinkRate
+		}
+
+		next := fill + (in-out)*dt
+		if next < 0 {
+			next = 0
+		}
+		if contact >= 0 {
+			run.downlinks[contact].Volume += fill + in*dt - next
+		}
+		if next > storage.Capacity && run.overflowAt == 0 {
+			run.overflowAt = t0 + int64(math.Ceil((storage.Capacity-fill)/(in-out)))
+		}
+		fill = next
+		run.peak = math.Max(run.peak, fill)
+
+		for i, a := range timeline {
+			if a.Strip.StopTimestamp == t1 {
+				run.fills[i] = fill
+			}
+		}
+		if contact >= 0 && storage.Contacts[contact].End == t1 {
+			run.downlinks[contact].RecorderFill = fill
+		}
+	}
+	return run
+}
@@ -0,0 +1,173 @@
+// Package satcat reads satellite catalogue records from SATCAT-style CSV files such as the
+// CelesTrak satellite catalogue
+package satcat
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"satplan/models"
+)
+
+// Entry is one catalogue record. Fields missing from the file are left empty.
+type Entry struct {
+	NoradID        string
+	Name           string
+	IntlDesignator string
+	Operator       string
+	Country        string
+	LaunchDate     string // YYYY-MM-DD
+	Status         string
+	OrbitClass     string
+	Notes          string
+}
+
+// columns maps the accepted header names, upper-cased, to the fields of an entry. Both
+// the CelesTrak SATCAT names and the satellite API field names are accepted.
+var columns = map[string]string{
+	"NORAD_CAT_ID":    "norad",
+	"NOARD_ID":        "norad",
+	"NORAD_ID":        "norad",
+	"OBJECT_NAME":     "name",
+	"SATNAME":         "name",
+	"NAME":            "name",
+	"OBJECT_ID":       "intl",
+	"INTLDES":         "intl",
+	"INTL_DESIGNATOR": "intl",
+	"COSPAR_ID":       "intl",
+	"OPERATOR":        "operator",
+	"OWNER":           "country",
+	"COUNTRY":         "country",
+	"LAUNCH_DATE":     "launch",
+	"LAUNCH":          "launch",
+	"OPS_STATUS_CODE": "ops",
+	"STATUS":          "status",
+	"ORBIT_CLASS":     "class",
+	"PERIOD":          "period",
+	"APOGEE":          "apogee",
+	"PERIGEE":         "perigee",
+	"DECAY_DATE":      "decay",
+	"NOTES":           "notes",
+}
+
+// Parse reads a CSV file with a header row. Only the NORAD catalogue number column is
+// required. The mission status is read from a status column or else from the SATCAT
+// decay date and operational status code; the orbit class is read from an orbit class
+// column or else derived from the period, apogee and perigee.
+func Parse(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty file")
+	} else if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := columns[name]; ok {
+			if _, seen := index[field]; !seen {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["norad"]; !ok {
+		return nil, fmt.Errorf("no NORAD_CAT_ID column")
+	}
+
+	entries := []Entry{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		get := func(field string) string {
+			if i, ok := index[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		e := Entry{
+			Name:           get("name"),
+			IntlDesignator: get("intl"),
+			Operator:       get("operator"),
+			Country:        get("country"),
+			LaunchDate:     get("launch"),
+			Notes:          get("notes"),
+		}
+		n, err := strconv.Atoi(get("norad"))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("line %d: invalid NORAD catalogue number %q", line, get("norad"))
+		}
+		e.NoradID = strconv.Itoa(n)
+
+		e.Status = strings.ToLower(get("status"))
+		if e.Status == "" && get("decay") != "" {
+			e.Status = models.SatelliteRetired
+		}
+		if e.Status == "" {
+			e.Status = statusFromCode(get("ops"))
+		}
+
+		e.OrbitClass = strings.ToUpper(get("class"))
+		if e.OrbitClass == "" {
+			e.OrbitClass = classify(get("period"), get("apogee"), get("perigee"))
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// statusFromCode maps a SATCAT operational status code to a mission status: operational,
+// extended mission, backup and spare satellites are active, partially operational ones
+// degraded and non-operational or decayed ones retired. Unknown codes give "".
+func statusFromCode(code string) string {
+	switch code {
+	case "+", "X", "B", "S":
+		return models.SatelliteActive
+	case "P":
+		return models.SatelliteDegraded
+	case "-", "D":
+		return models.SatelliteRetired
+	}
+	return ""
+}
+
+// classify derives the orbit class from the period (minutes) and the apogee and perigee
+// altitudes (km), returning "" when they are missing
+func classify(period, apogee, perigee string) string {
+	p, err1 := strconv.ParseFloat(period, 64)
+	ha, err2 := strconv.ParseFloat(apogee, 64)
+	hp, err3 := strconv.ParseFloat(perigee, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ""
+	}
+	return Classify(p, ha, hp)
+}
+
+// Classify returns the orbit class of an orbit with the given period (minutes) and apogee
+// and perigee altitudes (km): GEO for near-circular orbits with a period within an hour
+// of a sidereal day, HEO for an eccentricity above 0.25 or an apogee beyond GEO, LEO for
+// an apogee below 2000 km and MEO otherwise
+func Classify(period, apogee, perigee float64) string {
+	const earthRadius = 6378.137
+	e := (apogee - perigee) / (apogee + perigee + 2*earthRadius)
+	switch {
+	case e < 0.1 && period > 1376 && period < 1496:
+		return models.OrbitGEO
+	case e > 0.25 || apogee > 36000:
+		return models.OrbitHEO
+	case apogee < 2000:
+		return models.OrbitLEO
+	}
+	return models.OrbitMEO
+}
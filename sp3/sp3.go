@@ -0,0 +1,122 @@
+// Package sp3 reads the position and velocity records of SP3-c and SP3-d precise orbit
+// files
+package sp3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"satplan/orbit"
+)
+
+// Record is the state of one vehicle at one epoch, position in km and velocity in km/s.
+// Epochs are in the file's time system.
+type Record struct {
+	Epoch       time.Time
+	Position    orbit.Vector
+	Velocity    orbit.Vector
+	HasVelocity bool
+}
+
+// File is a parsed SP3 file
+type File struct {
+	CoordinateSystem string // e.g. IGS20 or ITR14
+	TimeSystem       string // GPS, UTC, TAI...
+	Vehicles         []string
+	Records          map[string][]Record // by vehicle ID, in file order
+}
+
+// Parse reads an SP3 file. Positions flagged as missing (all zero) are skipped.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{TimeSystem: "GPS", Records: map[string][]Record{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var epoch time.Time
+	haveEpoch := false
+	lineNo := 0
+	timeSystemRead := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNo++
+		switch {
+		case lineNo == 1:
+			if len(line) < 3 || line[0] != '#' || (line[1] != 'c' && line[1] != 'd') {
+				return nil, fmt.Errorf("not an SP3-c or SP3-d file")
+			}
+			if len(line) >= 51 {
+				f.CoordinateSystem = strings.TrimSpace(line[46:51])
+			}
+		case strings.HasPrefix(line, "%c") && !timeSystemRead:
+			// only the first %c line carries the time system
+			timeSystemRead = true
+			if len(line) >= 12 {
+				if ts := strings.TrimSpace(line[9:12]); ts != "" && ts != "ccc" {
+					f.TimeSystem = ts
+				}
+			}
+		case strings.HasPrefix(line, "*"):
+			fields := strings.Fields(line[1:])
+			if len(fields) < 6 {
+				return nil, fmt.Errorf("line %d: invalid epoch", lineNo)
+			}
+			var parts [5]int
+			for i := range parts {
+				v, err := strconv.Atoi(fields[i])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid epoch: %v", lineNo, err)
+				}
+				parts[i] = v
+			}
+			sec, err := strconv.ParseFloat(fields[5], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid epoch: %v", lineNo, err)
+			}
+			epoch = time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], 0, 0, time.UTC).
+				Add(time.Duration(sec * float64(time.Second)))
+			haveEpoch = true
+		case (strings.HasPrefix(line, "P") || strings.HasPrefix(line, "V")) && len(line) >= 46:
+			if !haveEpoch {
+				return nil, fmt.Errorf("line %d: record before the first epoch", lineNo)
+			}
+			id := strings.TrimSpace(line[1:4])
+			fields := strings.Fields(line[4:])
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("line %d: invalid record", lineNo)
+			}
+			var xyz [3]float64
+			for i := range xyz {
+				v, err := strconv.ParseFloat(fields[i], 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid record: %v", lineNo, err)
+				}
+				xyz[i] = v
+			}
+			vec := orbit.Vector{X: xyz[0], Y: xyz[1], Z: xyz[2]}
+			records := f.Records[id]
+			if line[0] == 'P' {
+				if vec == (orbit.Vector{}) {
+					continue
+				}
+				if _, ok := f.Records[id]; !ok {
+					f.Vehicles = append(f.Vehicles, id)
+				}
+				f.Records[id] = append(records, Record{Epoch: epoch, Position: vec})
+			} else if n := len(records); n > 0 && records[n-1].Epoch.Equal(epoch) {
+				// velocities are given in dm/s
+				records[n-1].Velocity = vec.Scale(1e-4)
+				records[n-1].HasVelocity = true
+			}
+		case strings.HasPrefix(line, "EOF"):
+			return f, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
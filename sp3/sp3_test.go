@@ -0,0 +1,64 @@
+package sp3
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `#cV2024  3  1  0  0  0.00000000       2 ORBIT IGS20 FIT  TST
+## 2303 432000.00000000    60.00000000 60370 0.0000000000000
++    2   L51L52  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+%c L  cc UTC ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc
+%c cc cc GPS ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc
+/* sample with a missing position
+*  2024  3  1  0  0  0.00000000
+PL51  -1234.567890   5678.901234  -3456.789012 999999.999999
+VL51  12345.678900 -23456.789000  34567.890100 999999.999999
+PL52      0.000000      0.000000      0.000000 999999.999999
+*  2024  3  1  0  1  0.00000000
+PL51  -1200.000000   5700.000000  -3400.000000 999999.999999
+PL52   7000.000000      0.000000      0.000000 999999.999999
+EOF
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.CoordinateSystem != "IGS20" || f.TimeSystem != "UTC" {
+		t.Errorf("header = %q %q, want IGS20 UTC", f.CoordinateSystem, f.TimeSystem)
+	}
+	if len(f.Vehicles) != 2 || f.Vehicles[0] != "L51" || f.Vehicles[1] != "L52" {
+		t.Errorf("Vehicles = %v", f.Vehicles)
+	}
+
+	l51 := f.Records["L51"]
+	if len(l51) != 2 {
+		t.Fatalf("L51 has %d records, want 2", len(l51))
+	}
+	first := l51[0]
+	if !first.Epoch.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || first.Position.X != -1234.56789 || !first.HasVelocity {
+		t.Errorf("first L51 record = %+v", first)
+	}
+	// velocities are read in dm/s
+	if math.Abs(first.Velocity.Y-(-2.3456789)) > 1e-12 {
+		t.Errorf("L51 velocity = %+v, want km/s", first.Velocity)
+	}
+	if l51[1].HasVelocity || !l51[1].Epoch.Equal(first.Epoch.Add(time.Minute)) {
+		t.Errorf("second L51 record = %+v", l51[1])
+	}
+
+	// the all-zero position at the first epoch is missing
+	if l52 := f.Records["L52"]; len(l52) != 1 || l52[0].Position.X != 7000 {
+		t.Errorf("L52 records = %+v", l52)
+	}
+}
+
+func TestParseRejectsOtherFormats(t *testing.T) {
+	if _, err := Parse(strings.NewReader("#aP2024  3  1  0  0  0.00000000\n")); err == nil {
+		t.Error("SP3-a file parsed, want an error")
+	}
+}
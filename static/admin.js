@@ -512,7 +512,7 @@ async function loadSatellites() {
                 <tr>
                     <td>${sat.id}</td>
                     <td>${sat.noard_id}</td>
-                    <td>${sat.name}</td>
+                    <td>${sat.name}${sat.decay && sat.decay.flagged ? ` <span class="decay-flag" title="${sat.decay.warnings.join(', ')}">Decaying</span>` : ''}</td>
                     <td>
                         <span class="color-preview" style="background-color: ${sat.hex_color}"></span>
                         ${sat.hex_color}
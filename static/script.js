@@ -228,6 +228,7 @@ let vectorSource = null;
 let vectorLayer = null;
 let solarOverlaySource = null;
 let solarOverlayLayer = null;
+let satellitePositionRequest = 0;
 let solarRefreshTimer = null;
 let solarOverlayReferenceTimeMs = null;
 let isDrawing = false;
@@ -557,9 +558,44 @@ function initMap() {
     solarRefreshTimer = window.setInterval(updateSolarOverlay, 60000);
 }
 
+// Marker colours of satellites in sunlight and in the earth's penumbra and umbra
+const SATELLITE_SHADOW_COLORS = {
+    sunlit: { fill: 'rgba(255, 214, 10, 0.95)', stroke: 'rgba(180, 83, 9, 1)' },
+    penumbra: { fill: 'rgba(156, 163, 175, 0.95)', stroke: 'rgba(75, 85, 99, 1)' },
+    umbra: { fill: 'rgba(17, 24, 39, 0.95)', stroke: 'rgba(255, 255, 255, 1)' }
+};
+
 function getSolarFeatureStyle(feature) {
     const featureType = feature.get('featureType');
 
+    if (featureType === 'satellite') {
+        const colors = SATELLITE_SHADOW_COLORS[feature.get('shadow')] || SATELLITE_SHADOW_COLORS.sunlit;
+        return new ol.style.Style({
+            image: new ol.style.Circle({
+                radius: 5,
+                fill: new ol.style.Fill({
+                    color: colors.fill
+                }),
+                stroke: new ol.style.Stroke({
+                    color: colors.stroke,
+                    width: 2
+                })
+            }),
+            text: new ol.style.Text({
+                text: `${feature.get('name')} (${feature.get('shadow')})`,
+                font: '11px sans-serif',
+                offsetY: -14,
+                fill: new ol.style.Fill({
+                    color: '#1f2937'
+                }),
+                stroke: new ol.style.Stroke({
+                    color: 'rgba(255, 255, 255, 0.9)',
+                    width: 3
+                })
+            })
+        });
+    }
+
     if (featureType === 'night') {
         return new ol.style.Style({
             fill: new ol.style.Fill({
@@ -681,6 +717,41 @@ function updateSolarOverlay(explicitTime) {
     solarOverlaySource.clear(true);
     solarOverlaySource.addFeatures(features);
     updateSolarInfo(now, sunPosition);
+    updateSatellitePositions(now);
+}
+
+/**
+ * Add the positions of the active satellites at the given Date to the solar overlay,
+ * marked by whether each is sunlit or in the earth's penumbra or umbra. The shadow state
+ * comes from the Go API; without it the overlay shows the sun alone.
+ */
+async function updateSatellitePositions(date) {
+    const config = getPlannerApiConfigs().find(candidate => candidate.kind === 'go');
+    if (!config) {
+        return;
+    }
+
+    const request = ++satellitePositionRequest;
+    let payload;
+    try {
+        const time = Math.floor(date.getTime() / 1000);
+        payload = await fetchJson(`${config.base}/sat/positions?time=${time}`, { cache: 'no-store' });
+    } catch (error) {
+        console.warn('SatPlan: failed to fetch satellite positions', error);
+        return;
+    }
+
+    // a newer update has cleared the overlay since this one was requested
+    if (request !== satellitePositionRequest || !payload || !Array.isArray(payload.data)) {
+        return;
+    }
+
+    solarOverlaySource.addFeatures(payload.data.map(position => new ol.Feature({
+        geometry: new ol.geom.Point(ol.proj.fromLonLat([position.lon, position.lat])),
+        featureType: 'satellite',
+        name: position.sat_name,
+        shadow: position.shadow
+    })));
 }
 
 function buildNightGeometry(terminatorPoints, sunLat) {
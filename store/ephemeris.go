@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"satplan/models"
+	"satplan/orbit"
+)
+
+// ephemerisColumns is the column list of an ephemeris read from ephemerisTable, with the
+// NORAD ID taken from the satellite
+const ephemerisColumns = `e.id, sat.noard_id, COALESCE(e.format, ''), COALESCE(e.object_name, ''),
+	COALESCE(e.frame, ''), COALESCE(e.time_system, ''), e.start_time, e.end_time, e.points, e.interpolation,
+	e.degree, COALESCE(e.has_velocity, 0), COALESCE(e.created_at, 0)`
+
+const ephemerisTable = "ephemeris e JOIN satellite sat ON sat.id = e.sat_id"
+
+func scanEphemeris(row rowScanner, e *models.Ephemeris) error {
+	return row.Scan(&e.ID, &e.SatNoardID, &e.Format, &e.ObjectName, &e.Frame, &e.TimeSystem, &e.StartTime,
+		&e.EndTime, &e.Points, &e.Interpolation, &e.Degree, &e.HasVelocity, &e.CreatedAt)
+}
+
+type ephemerisRepo struct{ db *sqlDB }
+
+func (r ephemerisRepo) List(ctx context.Context, noradID string) ([]models.Ephemeris, error) {
+	query := "SELECT " + ephemerisColumns + " FROM " + ephemerisTable
+	args := []interface{}{}
+	if noradID != "" {
+		query += " WHERE sat.noard_id = ?"
+		args = append(args, noradID)
+	}
+	rows, err := r.db.query(ctx, r.db, query+" ORDER BY sat.noard_id, e.start_time", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ephemerides := []models.Ephemeris{}
+	for rows.Next() {
+		var e models.Ephemeris
+		if err := scanEphemeris(rows, &e); err != nil {
+			return nil, err
+		}
+		ephemerides = append(ephemerides, e)
+	}
+	return ephemerides, rows.Err()
+}
+
+func (r ephemerisRepo) Get(ctx context.Context, id int) (models.Ephemeris, error) {
+	var e models.Ephemeris
+	err := scanEphemeris(r.db.queryRow(ctx, r.db, "SELECT "+ephemerisColumns+" FROM "+ephemerisTable+" WHERE e.id = ?", id), &e)
+	return e, notFound(err)
+}
+
+func (r ephemerisRepo) Create(ctx context.Context, e *models.Ephemeris, points []orbit.EphemerisPoint) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := r.db.insert(ctx, tx, `INSERT INTO ephemeris (sat_id, format, object_name, frame, time_system, start_time,
+			end_time, points, interpolation, degree, has_velocity, created_at)
+			SELECT id, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? FROM satellite WHERE noard_id = ?`,
+			e.Format, e.ObjectName, e.Frame, e.TimeSystem, e.StartTime, e.EndTime,
+			e.Points, e.Interpolation, e.Degree, boolInt(e.HasVelocity), e.CreatedAt, e.SatNoardID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSatelliteNotFound
+		} else if err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, r.db.rebind("INSERT INTO ephemeris_point (ephemeris_id, epoch, x, y, z, vx, vy, vz) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, p := range points {
+			_, err := stmt.ExecContext(ctx, id, float64(p.Time.UnixNano())/1e9, p.Position.X, p.Position.Y, p.Position.Z,
+				p.Velocity.X, p.Velocity.Y, p.Velocity.Z)
+			if err != nil {
+				return err
+			}
+		}
+		e.ID = id
+		return nil
+	})
+}
+
+func (r ephemerisRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM ephemeris_point WHERE ephemeris_id = ?", id); err != nil {
+			return err
+		}
+		return affected(r.db.exec(ctx, tx, "DELETE FROM ephemeris WHERE id = ?", id))
+	})
+}
+
+func (r ephemerisRepo) Covering(ctx context.Context, noradID string, start, end int64) (*orbit.Ephemeris, error) {
+	var meta models.Ephemeris
+	err := scanEphemeris(r.db.queryRow(ctx, r.db, `SELECT `+ephemerisColumns+` FROM `+ephemerisTable+`
+		WHERE sat.noard_id = ? AND e.start_time <= ? AND e.end_time >= ?
+		ORDER BY e.created_at DESC, e.id DESC LIMIT 1`, noradID, start, end), &meta)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.query(ctx, r.db, "SELECT epoch, x, y, z, vx, vy, vz FROM ephemeris_point WHERE ephemeris_id = ? ORDER BY epoch", meta.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eph := &orbit.Ephemeris{Method: meta.Interpolation, Degree: meta.Degree, HasVelocity: meta.HasVelocity}
+	for rows.Next() {
+		var epoch float64
+		var p orbit.EphemerisPoint
+		if err := rows.Scan(&epoch, &p.Position.X, &p.Position.Y, &p.Position.Z,
+			&p.Velocity.X, &p.Velocity.Y, &p.Velocity.Z); err != nil {
+			return nil, err
+		}
+		sec, frac := math.Modf(epoch)
+		p.Time = time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC()
+		eph.Points = append(eph.Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(eph.Points) < 2 {
+		return nil, fmt.Errorf("ephemeris %d has no states", meta.ID)
+	}
+	return eph, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+
+	"satplan/models"
+)
+
+type feedTokenRepo struct{ db *sqlDB }
+
+func (r feedTokenRepo) List(ctx context.Context, userID int) ([]models.FeedToken, error) {
+	rows, err := r.db.query(ctx, r.db, `SELECT id, user_id, name, created_at, COALESCE(last_used_at, 0)
+		FROM feed_token WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.FeedToken{}
+	for rows.Next() {
+		var t models.FeedToken
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.LastUsedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r feedTokenRepo) Create(ctx context.Context, t *models.FeedToken, hash string) error {
+	id, err := r.db.insert(ctx, r.db, "INSERT INTO feed_token (user_id, name, token_hash, created_at) VALUES (?, ?, ?, ?)",
+		t.UserID, t.Name, hash, t.CreatedAt)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	return nil
+}
+
+func (r feedTokenRepo) Delete(ctx context.Context, id, userID int) error {
+	return affected(r.db.exec(ctx, r.db, "DELETE FROM feed_token WHERE id = ? AND user_id = ?", id, userID))
+}
+
+func (r feedTokenRepo) Lookup(ctx context.Context, hash string) (int, models.User, error) {
+	var tokenID int
+	var u models.User
+	err := r.db.queryRow(ctx, r.db, `SELECT t.id, u.id, u.user_name FROM feed_token t
+		JOIN sys_user u ON u.id = t.user_id WHERE t.token_hash = ?`, hash).Scan(&tokenID, &u.ID, &u.Username)
+	return tokenID, u, notFound(err)
+}
+
+func (r feedTokenRepo) MarkUsed(ctx context.Context, id int, at int64) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE feed_token SET last_used_at = ? WHERE id = ?", at, id))
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"satplan/models"
+)
+
+// footprintRepo stores footprints as PostGIS polygons on PostgreSQL, searched through the
+// GIST index of footprint.geom. On SQLite the ring is stored as JSON with its bounding
+// box; the box narrows a search and planner.TargetArea.Intersects decides it.
+type footprintRepo struct{ db *sqlDB }
+
+func (r footprintRepo) Replace(ctx context.Context, planID int, strips []models.Strip) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM footprint WHERE plan_id = ?", planID); err != nil {
+			return err
+		}
+		for _, s := range strips {
+			if len(s.Coordinates) < 4 {
+				continue
+			}
+			var err error
+			if r.db.postgres {
+				geometry, _ := json.Marshal(struct {
+					Type        string        `json:"type"`
+					Coordinates [][][]float64 `json:"coordinates"`
+				}{"Polygon", [][][]float64{s.Coordinates}})
+				_, err = r.db.exec(ctx, tx, `INSERT INTO footprint (plan_id, sensor_id, sat_id, start_time, stop_time, geom)
+					SELECT ?, ?, id, ?, ?, ST_SetSRID(ST_GeomFromGeoJSON(?::text), 4326) FROM satellite WHERE noard_id = ?`,
+					planID, s.SensorID, s.StartTimestamp, s.StopTimestamp, string(geometry), s.SatNoardID)
+			} else {
+				coordinates, _ := json.Marshal(s.Coordinates)
+				minLon, maxLon, minLat, maxLat := ringBounds(s.Coordinates)
+				_, err = r.db.exec(ctx, tx, `INSERT INTO footprint (plan_id, sensor_id, sat_id, start_time, stop_time,
+					coordinates, min_lon, max_lon, min_lat, max_lat)
+					SELECT ?, ?, id, ?, ?, ?, ?, ?, ?, ? FROM satellite WHERE noard_id = ?`,
+					planID, s.SensorID, s.StartTimestamp, s.StopTimestamp,
+					string(coordinates), minLon, maxLon, minLat, maxLat, s.SatNoardID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r footprintRepo) Search(ctx context.Context, q FootprintQuery) ([]models.Footprint, error) {
+	a := q.Area
+	var query string
+	args := []interface{}{a.West, a.South, a.East, a.North}
+	if r.db.postgres {
+		// the rings are unwrapped and may run past ±180°, so the area is also tried a turn
+		// east and west
+		query = `WITH area AS (SELECT ST_MakeEnvelope(?, ?, ?, ?, 4326) AS env)
+			SELECT f.id, f.plan_id, COALESCE(f.sensor_id, 0), sat.noard_id, f.start_time, f.stop_time,
+			ST_AsGeoJSON(f.geom) FROM footprint f JOIN satellite sat ON sat.id = f.sat_id, area
+			WHERE (ST_Intersects(f.geom, area.env) OR ST_Intersects(f.geom, ST_Translate(area.env, 360, 0))
+			OR ST_Intersects(f.geom, ST_Translate(area.env, -360, 0)))`
+	} else {
+		query = `SELECT f.id, f.plan_id, COALESCE(f.sensor_id, 0), sat.noard_id, f.start_time, f.stop_time,
+			f.coordinates FROM footprint f JOIN satellite sat ON sat.id = f.sat_id
+			WHERE f.max_lon >= ? AND f.max_lat >= ? AND f.min_lon <= ? AND f.min_lat <= ?`
+	}
+	conditions := []string{query}
+	if q.Start != 0 {
+		conditions = append(conditions, "f.stop_time >= ?")
+		args = append(args, q.Start)
+	}
+	if q.End != 0 {
+		conditions = append(conditions, "f.start_time <= ?")
+		args = append(args, q.End)
+	}
+	if q.SensorID != 0 {
+		conditions = append(conditions, "f.sensor_id = ?")
+		args = append(args, q.SensorID)
+	}
+
+	rows, err := r.db.query(ctx, r.db, strings.Join(conditions, " AND ")+" ORDER BY f.start_time, f.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	footprints := []models.Footprint{}
+	for rows.Next() {
+		var f models.Footprint
+		var shape string
+		if err := rows.Scan(&f.ID, &f.PlanID, &f.SensorID, &f.SatNoardID, &f.StartTimestamp, &f.StopTimestamp, &shape); err != nil {
+			return nil, err
+		}
+		if r.db.postgres {
+			var geometry struct {
+				Coordinates [][][]float64 `json:"coordinates"`
+			}
+			if err := json.Unmarshal([]byte(shape), &geometry); err != nil || len(geometry.Coordinates) == 0 {
+				continue
+			}
+			f.Coordinates = geometry.Coordinates[0]
+		} else {
+			if err := json.Unmarshal([]byte(shape), &f.Coordinates); err != nil {
+				continue
+			}
+			ring := make([][2]float64, 0, len(f.Coordinates))
+			for _, c := range f.Coordinates {
+				if len(c) >= 2 {
+					ring = append(ring, [2]float64{c[0], c[1]})
+				}
+			}
+			if !a.Intersects(ring) {
+				continue
+			}
+		}
+		footprints = append(footprints, f)
+	}
+	return footprints, rows.Err()
+}
+
+// ringBounds returns the bounding box of a ring. A ring running past ±180° gets the full
+// longitude range, as the search area is not unwrapped with it.
+func ringBounds(ring [][]float64) (minLon, maxLon, minLat, maxLat float64) {
+	minLon, minLat = math.Inf(1), math.Inf(1)
+	maxLon, maxLat = math.Inf(-1), math.Inf(-1)
+	for _, c := range ring {
+		minLon, maxLon = math.Min(minLon, c[0]), math.Max(maxLon, c[0])
+		minLat, maxLat = math.Min(minLat, c[1]), math.Max(maxLat, c[1])
+	}
+	if minLon < -180 || maxLon > 180 {
+		minLon, maxLon = -180, 180
+	}
+	return minLon, maxLon, minLat, maxLat
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"satplan/models"
+)
+
+const groupColumns = "id, name, COALESCE(kind, ''), COALESCE(parent_id, 0), COALESCE(description, '')"
+
+func scanGroup(row rowScanner, g *models.SatelliteGroup) error {
+	return row.Scan(&g.ID, &g.Name, &g.Kind, &g.ParentID, &g.Description)
+}
+
+// errUnknownMembers rolls back the adding of group members when some satellites do not
+// exist
+var errUnknownMembers = errors.New("unknown satellites")
+
+type groupRepo struct{ db *sqlDB }
+
+func (r groupRepo) List(ctx context.Context) ([]models.SatelliteGroup, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT "+groupColumns+" FROM satellite_group ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.SatelliteGroup{}
+	index := map[int]int{}
+	for rows.Next() {
+		var g models.SatelliteGroup
+		if err := scanGroup(rows, &g); err != nil {
+			return nil, err
+		}
+		g.SatNoardIDs = []string{}
+		index[g.ID] = len(groups)
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	rows, err = r.db.query(ctx, r.db, `SELECT m.group_id, sat.noard_id FROM satellite_group_member m
+		JOIN satellite sat ON sat.id = m.sat_id ORDER BY sat.noard_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID int
+		var noradID string
+		if err := rows.Scan(&groupID, &noradID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[groupID]; ok {
+			groups[i].SatNoardIDs = append(groups[i].SatNoardIDs, noradID)
+		}
+	}
+	return groups, rows.Err()
+}
+
+func (r groupRepo) Get(ctx context.Context, id int) (models.SatelliteGroup, error) {
+	var g models.SatelliteGroup
+	if err := scanGroup(r.db.queryRow(ctx, r.db, "SELECT "+groupColumns+" FROM satellite_group WHERE id = ?", id), &g); err != nil {
+		return g, notFound(err)
+	}
+	var err error
+	g.SatNoardIDs, err = r.members(ctx, r.db, id)
+	return g, err
+}
+
+// members returns the NORAD IDs of the direct members of a group
+func (r groupRepo) members(ctx context.Context, q querier, id int) ([]string, error) {
+	rows, err := r.db.query(ctx, q, `SELECT sat.noard_id FROM satellite_group_member m JOIN satellite sat ON sat.id = m.sat_id
+		WHERE m.group_id = ? ORDER BY sat.noard_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var noradID string
+		if err := rows.Scan(&noradID); err != nil {
+			return nil, err
+		}
+		members = append(members, noradID)
+	}
+	return members, rows.Err()
+}
+
+func (r groupRepo) Parent(ctx context.Context, id int) (int, error) {
+	var parent int
+	err := r.db.queryRow(ctx, r.db, "SELECT COALESCE(parent_id, 0) FROM satellite_group WHERE id = ?", id).Scan(&parent)
+	return parent, notFound(err)
+}
+
+func (r groupRepo) Create(ctx context.Context, g *models.SatelliteGroup) ([]string, error) {
+	var unknown []string
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := r.db.insert(ctx, tx, "INSERT INTO satellite_group (name, kind, parent_id, description) VALUES (?, ?, ?, ?)",
+			g.Name, g.Kind, nullableID(g.ParentID), g.Description)
+		if err != nil {
+			return err
+		}
+		if unknown, err = r.addMembers(ctx, tx, id, g.SatNoardIDs); err != nil {
+			return err
+		}
+		g.ID = id
+		g.SatNoardIDs, err = r.members(ctx, tx, id)
+		return err
+	})
+	if err == errUnknownMembers {
+		return unknown, nil
+	}
+	return nil, err
+}
+
+func (r groupRepo) Update(ctx context.Context, g models.SatelliteGroup) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE satellite_group SET name = ?, kind = ?, parent_id = ?, description = ? WHERE id = ?",
+		g.Name, g.Kind, nullableID(g.ParentID), g.Description, g.ID))
+}
+
+func (r groupRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var subgroups, plans int
+		err := r.db.queryRow(ctx, tx, `SELECT
+			(SELECT COUNT(*) FROM satellite_group WHERE parent_id = ?),
+			(SELECT COUNT(*) FROM plan_group WHERE group_id = ?)`, id, id).Scan(&subgroups, &plans)
+		if err != nil {
+			return err
+		}
+		if subgroups > 0 || plans > 0 {
+			return conflictError(fmt.Sprintf("Group has %d subgroup(s) and is used by %d plan(s)", subgroups, plans))
+		}
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM satellite_group_member WHERE group_id = ?", id); err != nil {
+			return err
+		}
+		return affected(r.db.exec(ctx, tx, "DELETE FROM satellite_group WHERE id = ?", id))
+	})
+}
+
+func (r groupRepo) AddMembers(ctx context.Context, id int, noradIDs []string) ([]string, error) {
+	var unknown []string
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		unknown, err = r.addMembers(ctx, tx, id, noradIDs)
+		return err
+	})
+	if err == errUnknownMembers {
+		return unknown, nil
+	}
+	return nil, err
+}
+
+// addMembers adds satellites to a group, ignoring those already in it. It returns the
+// NORAD IDs that are not in the satellite table with errUnknownMembers.
+func (r groupRepo) addMembers(ctx context.Context, tx *sql.Tx, id int, noradIDs []string) ([]string, error) {
+	unknown := []string{}
+	for _, noradID := range noradIDs {
+		var satID int
+		err := r.db.queryRow(ctx, tx, "SELECT id FROM satellite WHERE noard_id = ?", noradID).Scan(&satID)
+		if errors.Is(err, sql.ErrNoRows) {
+			unknown = append(unknown, noradID)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if _, err := r.db.exec(ctx, tx, "INSERT INTO satellite_group_member (group_id, sat_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			id, satID); err != nil {
+			return nil, err
+		}
+	}
+	if len(unknown) > 0 {
+		return unknown, errUnknownMembers
+	}
+	return nil, nil
+}
+
+func (r groupRepo) RemoveMember(ctx context.Context, id int, noradID string) error {
+	return affected(r.db.exec(ctx, r.db, `DELETE FROM satellite_group_member WHERE group_id = ?
+		AND sat_id = (SELECT id FROM satellite WHERE noard_id = ?)`, id, noradID))
+}
+
+// groupTree selects the IDs of a group and all of its subgroups as sub(id)
+const groupTree = `WITH RECURSIVE sub(id) AS (
+		SELECT CAST(? AS INTEGER)
+		UNION
+		SELECT g.id FROM satellite_group g JOIN sub ON g.parent_id = sub.id
+	)`
+
+func (r groupRepo) Satellites(ctx context.Context, id int) ([]string, error) {
+	rows, err := r.db.query(ctx, r.db, groupTree+`
+		SELECT DISTINCT sat.noard_id FROM satellite_group_member m JOIN satellite sat ON sat.id = m.sat_id
+		WHERE m.group_id IN (SELECT id FROM sub)
+		ORDER BY sat.noard_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var noradID string
+		if err := rows.Scan(&noradID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, noradID)
+	}
+	return ids, rows.Err()
+}
+
+func (r groupRepo) Sensors(ctx context.Context, id int) ([]models.Sensor, error) {
+	return sensorRepo{r.db}.list(ctx, groupTree+`
+		SELECT `+sensorColumns+` FROM `+sensorTable+`
+		WHERE s.sat_id IN (SELECT m.sat_id FROM satellite_group_member m WHERE m.group_id IN (SELECT id FROM sub))
+		ORDER BY sat.noard_id, s.id`, id)
+}
+
+// nullableID stores a zero ID as NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
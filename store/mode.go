@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"satplan/models"
+)
+
+const modeColumns = `id, sensor_id, COALESCE(name, ''), COALESCE(type, ''), COALESCE(resolution, 0),
+	COALESCE(width, 0), COALESCE(right_side_angle, 0), COALESCE(left_side_angle, 0),
+	COALESCE(observe_angle, 0), COALESCE(data_rate, 0), COALESCE(near_incidence, 0), COALESCE(far_incidence, 0)`
+
+func scanMode(row rowScanner, m *models.SensorMode) error {
+	return row.Scan(&m.ID, &m.SensorID, &m.Name, &m.Type, &m.Resolution, &m.Width,
+		&m.RightSideAngle, &m.LeftSideAngle, &m.ObserveAngle, &m.DataRate, &m.NearIncidence, &m.FarIncidence)
+}
+
+type modeRepo struct{ db *sqlDB }
+
+func (r modeRepo) List(ctx context.Context) ([]models.SensorMode, error) {
+	return r.list(ctx, "1 = 1")
+}
+
+func (r modeRepo) ListBySensor(ctx context.Context, sensorID int) ([]models.SensorMode, error) {
+	return r.list(ctx, "sensor_id = ?", sensorID)
+}
+
+// list loads the modes matching the condition with their bands
+func (r modeRepo) list(ctx context.Context, where string, args ...interface{}) ([]models.SensorMode, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT "+modeColumns+" FROM sensor_mode WHERE "+where+" ORDER BY sensor_id, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modes := []models.SensorMode{}
+	index := map[int]int{}
+	for rows.Next() {
+		var m models.SensorMode
+		if err := scanMode(rows, &m); err != nil {
+			return nil, err
+		}
+		m.Bands = []models.Band{}
+		index[m.ID] = len(modes)
+		modes = append(modes, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	bands, err := r.bands(ctx, "mode_id IN (SELECT id FROM sensor_mode WHERE "+where+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bands {
+		if i, ok := index[b.ModeID]; ok {
+			modes[i].Bands = append(modes[i].Bands, b)
+		}
+	}
+	return modes, nil
+}
+
+func (r modeRepo) Get(ctx context.Context, id int) (models.SensorMode, error) {
+	var m models.SensorMode
+	if err := scanMode(r.db.queryRow(ctx, r.db, "SELECT "+modeColumns+" FROM sensor_mode WHERE id = ?", id), &m); err != nil {
+		return m, notFound(err)
+	}
+	var err error
+	m.Bands, err = r.bands(ctx, "mode_id = ?", m.ID)
+	return m, err
+}
+
+// bands loads the bands matching the condition in order of wavelength
+func (r modeRepo) bands(ctx context.Context, where string, args ...interface{}) ([]models.Band, error) {
+	rows, err := r.db.query(ctx, r.db, `SELECT id, mode_id, COALESCE(name, ''), COALESCE(center_wavelength, 0),
+		COALESCE(bandwidth, 0), COALESCE(gsd, 0) FROM band WHERE `+where+` ORDER BY mode_id, center_wavelength`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bands := []models.Band{}
+	for rows.Next() {
+		var b models.Band
+		if err := rows.Scan(&b.ID, &b.ModeID, &b.Name, &b.CenterWavelength, &b.Bandwidth, &b.GSD); err != nil {
+			return nil, err
+		}
+		bands = append(bands, b)
+	}
+	return bands, rows.Err()
+}
+
+func (r modeRepo) Create(ctx context.Context, m *models.SensorMode) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := r.db.queryRow(ctx, tx, "SELECT EXISTS(SELECT 1 FROM sensor WHERE id = ?)", m.SensorID).Scan(&exists); err != nil {
+			return err
+		} else if !exists {
+			return ErrNotFound
+		}
+		id, err := r.db.insert(ctx, tx, `INSERT INTO sensor_mode (sensor_id, name, type, resolution, width,
+			right_side_angle, left_side_angle, observe_angle, data_rate, near_incidence, far_incidence)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.SensorID, m.Name, m.Type, m.Resolution, m.Width,
+			m.RightSideAngle, m.LeftSideAngle, m.ObserveAngle, m.DataRate,
+			m.NearIncidence, m.FarIncidence)
+		if err != nil {
+			return err
+		}
+		m.ID = id
+		return r.insertBands(ctx, tx, m)
+	})
+}
+
+func (r modeRepo) Update(ctx context.Context, m *models.SensorMode) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		err := affected(r.db.exec(ctx, tx, `UPDATE sensor_mode SET name = ?, type = ?, resolution = ?, width = ?,
+			right_side_angle = ?, left_side_angle = ?, observe_angle = ?, data_rate = ?, near_incidence = ?,
+			far_incidence = ? WHERE id = ?`,
+			m.Name, m.Type, m.Resolution, m.Width,
+			m.RightSideAngle, m.LeftSideAngle, m.ObserveAngle, m.DataRate,
+			m.NearIncidence, m.FarIncidence, m.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM band WHERE mode_id = ?", m.ID); err != nil {
+			return err
+		}
+		return r.insertBands(ctx, tx, m)
+	})
+}
+
+// insertBands inserts the bands of a mode, setting their IDs
+func (r modeRepo) insertBands(ctx context.Context, tx *sql.Tx, m *models.SensorMode) error {
+	for i := range m.Bands {
+		b := &m.Bands[i]
+		b.ModeID = m.ID
+		id, err := r.db.insert(ctx, tx, "INSERT INTO band (mode_id, name, center_wavelength, bandwidth, gsd) VALUES (?, ?, ?, ?, ?)",
+			b.ModeID, b.Name, b.CenterWavelength, b.Bandwidth, b.GSD)
+		if err != nil {
+			return err
+		}
+		b.ID = id
+	}
+	return nil
+}
+
+func (r modeRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM band WHERE mode_id = ?", id); err != nil {
+			return err
+		}
+		return affected(r.db.exec(ctx, tx, "DELETE FROM sensor_mode WHERE id = ?", id))
+	})
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"satplan/models"
+)
+
+const planColumns = `id, user_id, name, min_lon, max_lon, min_lat, max_lat, start_time, end_time, created_at,
+	COALESCE(include_inactive, 0)`
+
+func scanPlan(row rowScanner, p *models.Plan) error {
+	return row.Scan(&p.ID, &p.UserID, &p.Name, &p.MinLon, &p.MaxLon, &p.MinLat, &p.MaxLat,
+		&p.StartTime, &p.EndTime, &p.CreatedAt, &p.IncludeInactive)
+}
+
+type planRepo struct{ db *sqlDB }
+
+func (r planRepo) List(ctx context.Context, filter PlanFilter) ([]models.Plan, error) {
+	query := "SELECT " + planColumns + " FROM plan"
+	var args []interface{}
+	if filter.Area != nil {
+		var where string
+		where, args = r.db.areaCondition(*filter.Area)
+		query += " WHERE " + where
+	}
+	rows, err := r.db.query(ctx, r.db, query+" ORDER BY created_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []models.Plan{}
+	for rows.Next() {
+		var p models.Plan
+		if err := scanPlan(rows, &p); err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range plans {
+		if err := r.selection(ctx, &plans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+func (r planRepo) Get(ctx context.Context, id int) (models.Plan, error) {
+	var p models.Plan
+	if err := scanPlan(r.db.queryRow(ctx, r.db, "SELECT "+planColumns+" FROM plan WHERE id = ?", id), &p); err != nil {
+		return p, notFound(err)
+	}
+	return p, r.selection(ctx, &p)
+}
+
+// selection loads the sensors and groups selected by a plan
+func (r planRepo) selection(ctx context.Context, p *models.Plan) error {
+	rows, err := r.db.query(ctx, r.db, "SELECT sensor_id, side_angle, COALESCE(mode_id, 0) FROM plan_sensor WHERE plan_id = ? ORDER BY sensor_id", p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.Sensors = []models.PlanSensor{}
+	for rows.Next() {
+		var s models.PlanSensor
+		if err := rows.Scan(&s.SensorID, &s.SideAngle, &s.ModeID); err != nil {
+			return err
+		}
+		p.Sensors = append(p.Sensors, s)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	rows, err = r.db.query(ctx, r.db, "SELECT group_id, COALESCE(side_angle, 0) FROM plan_group WHERE plan_id = ? ORDER BY group_id", p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.Groups = []models.PlanGroup{}
+	for rows.Next() {
+		var g models.PlanGroup
+		if err := rows.Scan(&g.GroupID, &g.SideAngle); err != nil {
+			return err
+		}
+		p.Groups = append(p.Groups, g)
+	}
+	return rows.Err()
+}
+
+func (r planRepo) Create(ctx context.Context, p *models.Plan) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := r.db.insert(ctx, tx, `INSERT INTO plan (user_id, name, min_lon, max_lon, min_lat, max_lat,
+			start_time, end_time, created_at, include_inactive) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.UserID, p.Name, p.MinLon, p.MaxLon, p.MinLat, p.MaxLat,
+			p.StartTime, p.EndTime, p.CreatedAt, boolInt(p.IncludeInactive))
+		if err != nil {
+			return err
+		}
+		p.ID = id
+		return r.insertSelection(ctx, tx, *p)
+	})
+}
+
+func (r planRepo) Update(ctx context.Context, p models.Plan) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		err := affected(r.db.exec(ctx, tx, `UPDATE plan SET name = ?, min_lon = ?, max_lon = ?, min_lat = ?, max_lat = ?,
+			start_time = ?, end_time = ?, include_inactive = ? WHERE id = ?`,
+			p.Name, p.MinLon, p.MaxLon, p.MinLat, p.MaxLat,
+			p.StartTime, p.EndTime, boolInt(p.IncludeInactive), p.ID))
+		if err != nil {
+			return err
+		}
+		for _, stmt := range []string{
+			"DELETE FROM plan_sensor WHERE plan_id = ?",
+			"DELETE FROM plan_group WHERE plan_id = ?",
+		} {
+			if _, err := r.db.exec(ctx, tx, stmt, p.ID); err != nil {
+				return err
+			}
+		}
+		return r.insertSelection(ctx, tx, p)
+	})
+}
+
+// insertSelection stores the sensors and groups selected by a plan
+func (r planRepo) insertSelection(ctx context.Context, tx *sql.Tx, p models.Plan) error {
+	for _, s := range p.Sensors {
+		if _, err := r.db.exec(ctx, tx, "INSERT INTO plan_sensor (plan_id, sensor_id, side_angle, mode_id) VALUES (?, ?, ?, ?)",
+			p.ID, s.SensorID, s.SideAngle, s.ModeID); err != nil {
+			return err
+		}
+	}
+	for _, g := range p.Groups {
+		if _, err := r.db.exec(ctx, tx, "INSERT INTO plan_group (plan_id, group_id, side_angle) VALUES (?, ?, ?)",
+			p.ID, g.GroupID, g.SideAngle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r planRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range []string{
+			"DELETE FROM plan_sensor WHERE plan_id = ?",
+			"DELETE FROM plan_group WHERE plan_id = ?",
+			"DELETE FROM footprint WHERE plan_id = ?",
+		} {
+			if _, err := r.db.exec(ctx, tx, stmt, id); err != nil {
+				return err
+			}
+		}
+		return affected(r.db.exec(ctx, tx, "DELETE FROM plan WHERE id = ?", id))
+	})
+}
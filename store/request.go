@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"satplan/models"
+)
+
+const taskingRequestColumns = `id, user_id, name, COALESCE(description, ''), min_lon, max_lon, min_lat, max_lat,
+	start_time, end_time, max_resolution, priority, max_cloud_cover, min_sun_elevation, max_sun_elevation,
+	status, created_at, updated_at`
+
+func scanTaskingRequest(row rowScanner, t *models.TaskingRequest) error {
+	var cloud, minSun, maxSun sql.NullFloat64
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.MinLon, &t.MaxLon, &t.MinLat, &t.MaxLat,
+		&t.StartTime, &t.EndTime, &t.MaxResolution, &t.Priority, &cloud, &minSun, &maxSun,
+		&t.Status, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	t.MaxCloudCover = nullFloat(cloud)
+	t.MinSunElevation = nullFloat(minSun)
+	t.MaxSunElevation = nullFloat(maxSun)
+	return nil
+}
+
+func nullFloat(n sql.NullFloat64) *float64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Float64
+}
+
+type taskingRequestRepo struct{ db *sqlDB }
+
+func (r taskingRequestRepo) List(ctx context.Context, filter TaskingRequestFilter) ([]models.TaskingRequest, error) {
+	query := "SELECT " + taskingRequestColumns + " FROM tasking_request"
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Area != nil {
+		where, areaArgs := r.db.areaCondition(*filter.Area)
+		conditions = append(conditions, where)
+		args = append(args, areaArgs...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	rows, err := r.db.query(ctx, r.db, query+" ORDER BY priority, end_time, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.TaskingRequest{}
+	for rows.Next() {
+		var t models.TaskingRequest
+		if err := scanTaskingRequest(rows, &t); err != nil {
+			return nil, err
+		}
+		requests = append(requests, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range requests {
+		if requests[i].SensorIDs, err = r.sensors(ctx, requests[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return requests, nil
+}
+
+func (r taskingRequestRepo) Get(ctx context.Context, id int) (models.TaskingRequest, error) {
+	var t models.TaskingRequest
+	err := scanTaskingRequest(r.db.queryRow(ctx, r.db, "SELECT "+taskingRequestColumns+" FROM tasking_request WHERE id = ?", id), &t)
+	if err != nil {
+		return t, notFound(err)
+	}
+	t.SensorIDs, err = r.sensors(ctx, t.ID)
+	return t, err
+}
+
+// sensors returns the IDs of the sensors a request allows
+func (r taskingRequestRepo) sensors(ctx context.Context, id int) ([]int, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT sensor_id FROM tasking_request_sensor WHERE request_id = ? ORDER BY sensor_id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sensorIDs := []int{}
+	for rows.Next() {
+		var sensorID int
+		if err := rows.Scan(&sensorID); err != nil {
+			return nil, err
+		}
+		sensorIDs = append(sensorIDs, sensorID)
+	}
+	return sensorIDs, rows.Err()
+}
+
+func (r taskingRequestRepo) Create(ctx context.Context, t *models.TaskingRequest) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		id, err := r.db.insert(ctx, tx, `INSERT INTO tasking_request (user_id, name, description, min_lon, max_lon,
+			min_lat, max_lat, start_time, end_time, max_resolution, priority, max_cloud_cover,
+			min_sun_elevation, max_sun_elevation, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.UserID, t.Name, t.Description, t.MinLon, t.MaxLon, t.MinLat, t.MaxLat,
+			t.StartTime, t.EndTime, t.MaxResolution, t.Priority, t.MaxCloudCover,
+			t.MinSunElevation, t.MaxSunElevation, t.Status, t.CreatedAt, t.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := r.insertSensors(ctx, tx, id, t.SensorIDs); err != nil {
+			return err
+		}
+		t.ID = id
+		return r.recordEvent(ctx, tx, id, t.UserID, "create", "", t.Status, nil, "")
+	})
+}
+
+func (r taskingRequestRepo) Update(ctx context.Context, t models.TaskingRequest, userID int, changes map[string][2]interface{}) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		// the status condition guards against a concurrent submit
+		err := affected(r.db.exec(ctx, tx, `UPDATE tasking_request SET name = ?, description = ?, min_lon = ?, max_lon = ?,
+			min_lat = ?, max_lat = ?, start_time = ?, end_time = ?, max_resolution = ?, priority = ?,
+			max_cloud_cover = ?, min_sun_elevation = ?, max_sun_elevation = ?, updated_at = ?
+			WHERE id = ? AND status = ?`,
+			t.Name, t.Description, t.MinLon, t.MaxLon, t.MinLat, t.MaxLat, t.StartTime, t.EndTime,
+			t.MaxResolution, t.Priority, t.MaxCloudCover, t.MinSunElevation, t.MaxSunElevation,
+			t.UpdatedAt, t.ID, models.RequestDraft))
+		if err == ErrNotFound {
+			return conflictError("Only draft requests can be edited")
+		} else if err != nil {
+			return err
+		}
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM tasking_request_sensor WHERE request_id = ?", t.ID); err != nil {
+			return err
+		}
+		if err := r.insertSensors(ctx, tx, t.ID, t.SensorIDs); err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, t.ID, userID, "update", models.RequestDraft, models.RequestDraft, changes, "")
+	})
+}
+
+func (r taskingRequestRepo) insertSensors(ctx context.Context, tx *sql.Tx, id int, sensorIDs []int) error {
+	for _, sensorID := range sensorIDs {
+		if _, err := r.db.exec(ctx, tx, "INSERT INTO tasking_request_sensor (request_id, sensor_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			id, sensorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r taskingRequestRepo) Delete(ctx context.Context, id, userID int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var status string
+		if err := r.db.queryRow(ctx, tx, "SELECT status FROM tasking_request WHERE id = ?", id).Scan(&status); err != nil {
+			return notFound(err)
+		}
+		if status != models.RequestDraft {
+			return conflictError(fmt.Sprintf("Only draft requests can be deleted; this request is %s", status))
+		}
+		for _, stmt := range []string{
+			"DELETE FROM tasking_request_sensor WHERE request_id = ?",
+			"DELETE FROM tasking_request WHERE id = ?",
+		} {
+			if _, err := r.db.exec(ctx, tx, stmt, id); err != nil {
+				return err
+			}
+		}
+		// the audit trail outlives the request and ends with its deletion
+		return r.recordEvent(ctx, tx, id, userID, "delete", status, "", nil, "")
+	})
+}
+
+// errRejected rolls back a transition that some requests do not allow
+var errRejected = errors.New("transition rejected")
+
+func (r taskingRequestRepo) Transition(ctx context.Context, action string, t RequestTransition, userID int, notes map[int]string) (map[int]string, error) {
+	ids := make([]int, 0, len(notes))
+	for id := range notes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	rejected := map[int]string{}
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			var status string
+			err := r.db.queryRow(ctx, tx, "SELECT status FROM tasking_request WHERE id = ?", id).Scan(&status)
+			if errors.Is(err, sql.ErrNoRows) {
+				rejected[id] = ""
+				continue
+			} else if err != nil {
+				return err
+			}
+			if !slices.Contains(t.From, status) {
+				rejected[id] = status
+				continue
+			}
+			// the status condition guards against a concurrent transition
+			err = affected(r.db.exec(ctx, tx, "UPDATE tasking_request SET status = ?, updated_at = ? WHERE id = ? AND status = ?",
+				t.To, time.Now().Unix(), id, status))
+			if err == ErrNotFound {
+				rejected[id] = status
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := r.recordEvent(ctx, tx, id, userID, action, status, t.To, nil, notes[id]); err != nil {
+				return err
+			}
+		}
+		if len(rejected) > 0 {
+			return errRejected
+		}
+		return nil
+	})
+	if err == errRejected {
+		return rejected, nil
+	}
+	return nil, err
+}
+
+// recordEvent appends an entry to a tasking request's audit trail
+func (r taskingRequestRepo) recordEvent(ctx context.Context, tx *sql.Tx, id, userID int, action, from, to string, changes map[string][2]interface{}, note string) error {
+	var changesJSON interface{}
+	if len(changes) > 0 {
+		b, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		changesJSON = string(b)
+	}
+	_, err := r.db.exec(ctx, tx, `INSERT INTO tasking_request_event (request_id, user_id, action, from_status, to_status,
+		changes, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, userID, action, from, to, changesJSON, note, time.Now().Unix())
+	return err
+}
+
+func (r taskingRequestRepo) Events(ctx context.Context, id int) ([]models.TaskingRequestEvent, error) {
+	var exists bool
+	if err := r.db.queryRow(ctx, r.db, `SELECT EXISTS(SELECT 1 FROM tasking_request WHERE id = ?)
+		OR EXISTS(SELECT 1 FROM tasking_request_event WHERE request_id = ?)`, id, id).Scan(&exists); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := r.db.query(ctx, r.db, `SELECT e.id, e.request_id, COALESCE(e.user_id, 0), COALESCE(u.user_name, ''), e.action,
+		COALESCE(e.from_status, ''), e.to_status, COALESCE(e.changes, ''), COALESCE(e.note, ''), e.created_at
+		FROM tasking_request_event e LEFT JOIN sys_user u ON u.id = e.user_id
+		WHERE e.request_id = ? ORDER BY e.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TaskingRequestEvent{}
+	for rows.Next() {
+		var e models.TaskingRequestEvent
+		var changes string
+		if err := rows.Scan(&e.ID, &e.RequestID, &e.UserID, &e.Username, &e.Action,
+			&e.FromStatus, &e.ToStatus, &changes, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if changes != "" {
+			e.Changes = json.RawMessage(changes)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
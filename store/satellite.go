@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"satplan/models"
+)
+
+// satelliteColumns is the column list read by scanSatellite
+const satelliteColumns = `id, noard_id, name, hex_color,
+	COALESCE(max_roll_rate, 0), COALESCE(max_pitch_rate, 0),
+	COALESCE(roll_acceleration, 0), COALESCE(pitch_acceleration, 0), COALESCE(settle_time, 0),
+	COALESCE(recorder_capacity, 0), COALESCE(downlink_rate, 0),
+	COALESCE(intl_designator, ''), COALESCE(operator, ''), COALESCE(country, ''), COALESCE(launch_date, ''),
+	COALESCE(status, 'active'), COALESCE(orbit_class, ''), COALESCE(notes, '')`
+
+// scanSatellite scans a row selected with satelliteColumns
+func scanSatellite(row rowScanner, s *models.Satellite) error {
+	return row.Scan(&s.ID, &s.NoardID, &s.Name, &s.HexColor,
+		&s.MaxRollRate, &s.MaxPitchRate, &s.RollAcceleration, &s.PitchAcceleration, &s.SettleTime,
+		&s.RecorderCapacity, &s.DownlinkRate,
+		&s.IntlDesignator, &s.Operator, &s.Country, &s.LaunchDate, &s.Status, &s.OrbitClass, &s.Notes)
+}
+
+type satelliteRepo struct{ db *sqlDB }
+
+func (r satelliteRepo) List(ctx context.Context, filter SatelliteFilter) ([]models.Satellite, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, f := range []struct {
+		column string
+		values []string
+	}{
+		{"COALESCE(status, 'active')", filter.Status},
+		{"operator", filter.Operator},
+		{"country", filter.Country},
+		{"orbit_class", filter.OrbitClass},
+	} {
+		if len(f.values) == 0 {
+			continue
+		}
+		for _, v := range f.values {
+			args = append(args, strings.ToLower(strings.TrimSpace(v)))
+		}
+		conditions = append(conditions, fmt.Sprintf("lower(%s) IN (%s)", f.column, placeholders(len(f.values))))
+	}
+	if filter.LaunchedAfter != "" {
+		conditions = append(conditions, "launch_date <> '' AND launch_date >= ?")
+		args = append(args, filter.LaunchedAfter)
+	}
+	if filter.LaunchedBefore != "" {
+		conditions = append(conditions, "launch_date <> '' AND launch_date <= ?")
+		args = append(args, filter.LaunchedBefore)
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "(lower(name) LIKE ? OR noard_id LIKE ? OR lower(intl_designator) LIKE ?)")
+		like := "%" + strings.ToLower(filter.Query) + "%"
+		args = append(args, like, like, like)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := r.db.query(ctx, r.db, "SELECT "+satelliteColumns+" FROM satellite"+where+" ORDER BY name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	satellites := []models.Satellite{}
+	for rows.Next() {
+		var s models.Satellite
+		if err := scanSatellite(rows, &s); err != nil {
+			return nil, err
+		}
+		satellites = append(satellites, s)
+	}
+	return satellites, rows.Err()
+}
+
+func (r satelliteRepo) Get(ctx context.Context, id int) (models.Satellite, error) {
+	var s models.Satellite
+	err := scanSatellite(r.db.queryRow(ctx, r.db, "SELECT "+satelliteColumns+" FROM satellite WHERE id = ?", id), &s)
+	return s, notFound(err)
+}
+
+func (r satelliteRepo) GetByNoradID(ctx context.Context, noradID string) (models.Satellite, error) {
+	var s models.Satellite
+	err := scanSatellite(r.db.queryRow(ctx, r.db, "SELECT "+satelliteColumns+" FROM satellite WHERE noard_id = ?", noradID), &s)
+	return s, notFound(err)
+}
+
+func (r satelliteRepo) Count(ctx context.Context) (int, error) {
+	return count(ctx, r.db, "satellite")
+}
+
+func (r satelliteRepo) Create(ctx context.Context, sat *models.Satellite) error {
+	id, err := r.db.insert(ctx, r.db, `INSERT INTO satellite (noard_id, name, hex_color,
+		max_roll_rate, max_pitch_rate, roll_acceleration, pitch_acceleration, settle_time,
+		recorder_capacity, downlink_rate,
+		intl_designator, operator, country, launch_date, status, orbit_class, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sat.NoardID, sat.Name, sat.HexColor,
+		sat.MaxRollRate, sat.MaxPitchRate, sat.RollAcceleration, sat.PitchAcceleration, sat.SettleTime,
+		sat.RecorderCapacity, sat.DownlinkRate,
+		sat.IntlDesignator, sat.Operator, sat.Country, sat.LaunchDate, sat.Status, sat.OrbitClass, sat.Notes)
+	if isUniqueViolation(err) {
+		return noradIDConflict(sat.NoardID)
+	} else if err != nil {
+		return err
+	}
+	sat.ID = id
+	return nil
+}
+
+func (r satelliteRepo) Update(ctx context.Context, sat models.Satellite) error {
+	result, err := r.db.exec(ctx, r.db, `UPDATE satellite SET noard_id = ?, name = ?, hex_color = ?,
+		max_roll_rate = ?, max_pitch_rate = ?, roll_acceleration = ?, pitch_acceleration = ?, settle_time = ?,
+		recorder_capacity = ?, downlink_rate = ?,
+		intl_designator = ?, operator = ?, country = ?, launch_date = ?, status = ?, orbit_class = ?, notes = ?
+		WHERE id = ?`,
+		sat.NoardID, sat.Name, sat.HexColor,
+		sat.MaxRollRate, sat.MaxPitchRate, sat.RollAcceleration, sat.PitchAcceleration, sat.SettleTime,
+		sat.RecorderCapacity, sat.DownlinkRate,
+		sat.IntlDesignator, sat.Operator, sat.Country, sat.LaunchDate, sat.Status, sat.OrbitClass, sat.Notes, sat.ID)
+	if isUniqueViolation(err) {
+		return noradIDConflict(sat.NoardID)
+	}
+	return affected(result, err)
+}
+
+func (r satelliteRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := r.db.queryRow(ctx, tx, "SELECT EXISTS(SELECT 1 FROM satellite WHERE id = ?)", id).Scan(&exists); err != nil {
+			return err
+		} else if !exists {
+			return ErrNotFound
+		}
+		var sensors int
+		if err := r.db.queryRow(ctx, tx, "SELECT COUNT(*) FROM sensor WHERE sat_id = ?", id).Scan(&sensors); err != nil {
+			return err
+		}
+		if sensors > 0 {
+			return conflictError(fmt.Sprintf("Satellite has %d sensor(s), delete them first", sensors))
+		}
+
+		// TLEs, ephemerides, group memberships, conjunctions and footprints are removed by
+		// the foreign key cascade; ephemeris states only refer to their ephemeris
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM ephemeris_point WHERE ephemeris_id IN (SELECT id FROM ephemeris WHERE sat_id = ?)",
+			id); err != nil {
+			return err
+		}
+		_, err := r.db.exec(ctx, tx, "DELETE FROM satellite WHERE id = ?", id)
+		if isForeignKeyViolation(err) {
+			// a sensor was added meanwhile
+			return conflictError("Satellite has sensors, delete them first")
+		}
+		return err
+	})
+}
+
+func (r satelliteRepo) Import(ctx context.Context, sats []models.Satellite, create bool) (models.SatelliteImportResult, error) {
+	result := models.SatelliteImportResult{NotFound: []string{}}
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, sat := range sats {
+			res, err := r.db.exec(ctx, tx, `UPDATE satellite SET
+				intl_designator = COALESCE(NULLIF(?, ''), intl_designator),
+				operator = COALESCE(NULLIF(?, ''), operator),
+				country = COALESCE(NULLIF(?, ''), country),
+				launch_date = COALESCE(NULLIF(?, ''), launch_date),
+				status = COALESCE(NULLIF(?, ''), status),
+				orbit_class = COALESCE(NULLIF(?, ''), orbit_class),
+				notes = COALESCE(NULLIF(?, ''), notes)
+				WHERE noard_id = ?`,
+				sat.IntlDesignator, sat.Operator, sat.Country, sat.LaunchDate, sat.Status, sat.OrbitClass, sat.Notes,
+				sat.NoardID)
+			if err != nil {
+				return fmt.Errorf("failed to update satellite %s: %v", sat.NoardID, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				result.Updated++
+				continue
+			}
+			if !create {
+				result.NotFound = append(result.NotFound, sat.NoardID)
+				continue
+			}
+
+			if sat.Name == "" {
+				sat.Name = sat.NoardID
+			}
+			if sat.Status == "" {
+				sat.Status = models.SatelliteActive
+			}
+			_, err = r.db.exec(ctx, tx, `INSERT INTO satellite (noard_id, name, hex_color,
+				intl_designator, operator, country, launch_date, status, orbit_class, notes)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				sat.NoardID, sat.Name, "",
+				sat.IntlDesignator, sat.Operator, sat.Country, sat.LaunchDate, sat.Status, sat.OrbitClass, sat.Notes)
+			if err != nil {
+				return fmt.Errorf("failed to insert satellite %s: %v", sat.NoardID, err)
+			}
+			result.Created++
+		}
+		return nil
+	})
+	return result, err
+}
+
+func noradIDConflict(noradID string) error {
+	return conflictError("A satellite with NORAD ID " + noradID + " already exists")
+}
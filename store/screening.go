@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"satplan/models"
+)
+
+// screeningRunColumns is the column list read by scanScreeningRun
+const screeningRunColumns = `id, status, started_at, COALESCE(finished_at, 0), window_start, window_end,
+	threshold, COALESCE(site_id, 0), COALESCE(objects, 0), COALESCE(pairs, 0), COALESCE(conjunctions, 0),
+	COALESCE(message, '')`
+
+func scanScreeningRun(row rowScanner, run *models.ScreeningRun) error {
+	return row.Scan(&run.ID, &run.Status, &run.StartedAt, &run.FinishedAt, &run.Start, &run.End,
+		&run.Threshold, &run.SiteID, &run.Objects, &run.Pairs, &run.Conjunctions, &run.Message)
+}
+
+type screeningRepo struct{ db *sqlDB }
+
+func (r screeningRepo) List(ctx context.Context) ([]models.ScreeningRun, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT "+screeningRunColumns+" FROM conjunction_run ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []models.ScreeningRun{}
+	for rows.Next() {
+		var run models.ScreeningRun
+		if err := scanScreeningRun(rows, &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r screeningRepo) Get(ctx context.Context, id int) (models.ScreeningRun, error) {
+	var run models.ScreeningRun
+	err := scanScreeningRun(r.db.queryRow(ctx, r.db, "SELECT "+screeningRunColumns+" FROM conjunction_run WHERE id = ?", id), &run)
+	return run, notFound(err)
+}
+
+func (r screeningRepo) Latest(ctx context.Context) (int, error) {
+	var id int
+	err := r.db.queryRow(ctx, r.db, "SELECT id FROM conjunction_run WHERE status = ? ORDER BY id DESC LIMIT 1",
+		models.ScreeningDone).Scan(&id)
+	return id, notFound(err)
+}
+
+// screeningLock is the key of the lock held by the process running a screening
+const screeningLock = 0x5a7b1a5
+
+func (r screeningRepo) Start(ctx context.Context, run *models.ScreeningRun) (func(), error) {
+	release, ok, err := r.db.tryLock(ctx, screeningLock)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, conflictError("A screening run is already in progress")
+	}
+	err = r.db.inTx(ctx, func(tx *sql.Tx) error {
+		// the lock was free, so any run left running belongs to a process that stopped
+		_, err := r.db.exec(ctx, tx, "UPDATE conjunction_run SET status = ?, message = ? WHERE status = ?",
+			models.ScreeningFailed, "interrupted", models.ScreeningRunning)
+		if err != nil {
+			return err
+		}
+		id, err := r.db.insert(ctx, tx, `INSERT INTO conjunction_run (status, started_at, window_start, window_end, threshold, site_id)
+			VALUES (?, ?, ?, ?, ?, ?)`, run.Status, run.StartedAt, run.Start, run.End, run.Threshold, run.SiteID)
+		if err != nil {
+			return err
+		}
+		run.ID = id
+		return nil
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func (r screeningRepo) Finish(ctx context.Context, run models.ScreeningRun, conjunctions []models.Conjunction) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range conjunctions {
+			// a satellite deleted during the screening takes its conjunctions with it
+			_, err := r.db.exec(ctx, tx, `INSERT INTO conjunction (run_id, sat_id, sat_name, other_noard_id, other_name,
+				tca, miss_distance, relative_velocity, radial, in_track, cross_track)
+				SELECT ?, id, ?, ?, ?, ?, ?, ?, ?, ?, ? FROM satellite WHERE noard_id = ?`,
+				run.ID, c.SatName, c.OtherNoardID, c.OtherName, c.TCA, c.MissDistance, c.RelativeVelocity,
+				c.Radial, c.InTrack, c.CrossTrack, c.SatNoardID)
+			if err != nil {
+				return err
+			}
+		}
+		return affected(r.db.exec(ctx, tx, `UPDATE conjunction_run SET status = ?, finished_at = ?, objects = ?, pairs = ?,
+			conjunctions = ?, message = ? WHERE id = ?`, models.ScreeningDone, time.Now().Unix(), run.Objects, run.Pairs,
+			len(conjunctions), run.Message, run.ID))
+	})
+}
+
+func (r screeningRepo) Fail(ctx context.Context, id int, message string) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE conjunction_run SET status = ?, finished_at = ?, message = ? WHERE id = ?",
+		models.ScreeningFailed, time.Now().Unix(), message, id))
+}
+
+func (r screeningRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var status string
+		if err := r.db.queryRow(ctx, tx, "SELECT status FROM conjunction_run WHERE id = ?", id).Scan(&status); err != nil {
+			return notFound(err)
+		}
+		if status == models.ScreeningRunning {
+			return conflictError("Screening run is still in progress")
+		}
+		if _, err := r.db.exec(ctx, tx, "DELETE FROM conjunction WHERE run_id = ?", id); err != nil {
+			return err
+		}
+		_, err := r.db.exec(ctx, tx, "DELETE FROM conjunction_run WHERE id = ?", id)
+		return err
+	})
+}
+
+func (r screeningRepo) Conjunctions(ctx context.Context, filter ConjunctionFilter) ([]models.Conjunction, error) {
+	query := `SELECT c.id, c.run_id, sat.noard_id, c.sat_name, c.other_noard_id, c.other_name, c.tca,
+		c.miss_distance, c.relative_velocity, c.radial, c.in_track, c.cross_track
+		FROM conjunction c JOIN satellite sat ON sat.id = c.sat_id WHERE c.run_id = ?`
+	args := []interface{}{filter.RunID}
+	if filter.SatNoardID != "" {
+		query += " AND (sat.noard_id = ? OR c.other_noard_id = ?)"
+		args = append(args, filter.SatNoardID, filter.SatNoardID)
+	}
+	if filter.MaxMiss != nil {
+		query += " AND c.miss_distance <= ?"
+		args = append(args, *filter.MaxMiss)
+	}
+	if filter.Start != nil {
+		query += " AND c.tca >= ?"
+		args = append(args, *filter.Start)
+	}
+	if filter.End != nil {
+		query += " AND c.tca <= ?"
+		args = append(args, *filter.End)
+	}
+
+	rows, err := r.db.query(ctx, r.db, query+" ORDER BY c.tca", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conjunctions := []models.Conjunction{}
+	for rows.Next() {
+		var c models.Conjunction
+		if err := rows.Scan(&c.ID, &c.RunID, &c.SatNoardID, &c.SatName, &c.OtherNoardID, &c.OtherName, &c.TCA,
+			&c.MissDistance, &c.RelativeVelocity, &c.Radial, &c.InTrack, &c.CrossTrack); err != nil {
+			return nil, err
+		}
+		conjunctions = append(conjunctions, c)
+	}
+	return conjunctions, rows.Err()
+}
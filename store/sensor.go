@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"satplan/models"
+)
+
+// sensorColumns is the column list read by scanSensor from sensorTable. The satellite
+// NORAD ID and name come from the joined satellite row.
+const sensorColumns = `s.id, s.sat_id, sat.noard_id, COALESCE(sat.name, ''), s.name, s.resolution, s.width,
+	s.right_side_angle, s.left_side_angle, s.observe_angle, s.hex_color, s.init_angle, COALESCE(s.data_rate, 0),
+	COALESCE(s.max_on_time, 0), COALESCE(s.max_acquisitions, 0), COALESCE(s.min_gap, 0), COALESCE(s.eclipse_aware, 0),
+	COALESCE(s.max_pitch_angle, 0), COALESCE(s.sensor_type, 'optical'), COALESCE(s.near_incidence, 0),
+	COALESCE(s.far_incidence, 0), COALESCE(s.look_side, ''), COALESCE(s.orbit_direction, '')`
+
+// sensorTable joins each sensor to its satellite for sensorColumns
+const sensorTable = "sensor s JOIN satellite sat ON sat.id = s.sat_id"
+
+// scanSensor scans a row selected with sensorColumns
+func scanSensor(row rowScanner, s *models.Sensor) error {
+	return row.Scan(&s.ID, &s.SatID, &s.SatNoardID, &s.SatName, &s.Name, &s.Resolution,
+		&s.Width, &s.RightSideAngle, &s.LeftSideAngle, &s.ObserveAngle,
+		&s.HexColor, &s.InitAngle, &s.DataRate,
+		&s.MaxOnTime, &s.MaxAcquisitions, &s.MinGap, &s.EclipseAware, &s.MaxPitchAngle,
+		&s.SensorType, &s.NearIncidence, &s.FarIncidence, &s.LookSide, &s.OrbitDirection)
+}
+
+type sensorRepo struct{ db *sqlDB }
+
+func (r sensorRepo) List(ctx context.Context) ([]models.Sensor, error) {
+	return r.list(ctx, "SELECT "+sensorColumns+" FROM "+sensorTable+" ORDER BY sat.name, s.name")
+}
+
+func (r sensorRepo) ListBySatellite(ctx context.Context, noradID string) ([]models.Sensor, error) {
+	return r.list(ctx, "SELECT "+sensorColumns+" FROM "+sensorTable+" WHERE sat.noard_id = ? ORDER BY s.name", noradID)
+}
+
+func (r sensorRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.Sensor, error) {
+	rows, err := r.db.query(ctx, r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sensors := []models.Sensor{}
+	for rows.Next() {
+		var s models.Sensor
+		if err := scanSensor(rows, &s); err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, rows.Err()
+}
+
+func (r sensorRepo) Get(ctx context.Context, id int) (models.Sensor, error) {
+	var s models.Sensor
+	err := scanSensor(r.db.queryRow(ctx, r.db, "SELECT "+sensorColumns+" FROM "+sensorTable+" WHERE s.id = ?", id), &s)
+	return s, notFound(err)
+}
+
+func (r sensorRepo) Count(ctx context.Context) (int, error) {
+	return count(ctx, r.db, "sensor")
+}
+
+func (r sensorRepo) Create(ctx context.Context, s *models.Sensor) error {
+	if err := r.resolveSatellite(ctx, s); err != nil {
+		return err
+	}
+	id, err := r.db.insert(ctx, r.db, `INSERT INTO sensor (sat_id, name, resolution, width,
+		right_side_angle, left_side_angle, observe_angle, hex_color, init_angle, data_rate,
+		max_on_time, max_acquisitions, min_gap, eclipse_aware, max_pitch_angle, sensor_type,
+		near_incidence, far_incidence, look_side, orbit_direction)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.SatID, s.Name, s.Resolution, s.Width,
+		s.RightSideAngle, s.LeftSideAngle, s.ObserveAngle,
+		s.HexColor, s.InitAngle, s.DataRate,
+		s.MaxOnTime, s.MaxAcquisitions, s.MinGap, boolInt(s.EclipseAware), s.MaxPitchAngle,
+		s.SensorType, s.NearIncidence, s.FarIncidence, s.LookSide, s.OrbitDirection)
+	if isForeignKeyViolation(err) {
+		return ErrSatelliteNotFound
+	} else if err != nil {
+		return err
+	}
+	s.ID = id
+	return nil
+}
+
+func (r sensorRepo) Update(ctx context.Context, s *models.Sensor) error {
+	var exists bool
+	if err := r.db.queryRow(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM sensor WHERE id = ?)", s.ID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return ErrNotFound
+	}
+	if err := r.resolveSatellite(ctx, s); err != nil {
+		return err
+	}
+	err := affected(r.db.exec(ctx, r.db, `UPDATE sensor SET sat_id = ?, name = ?, resolution = ?,
+		width = ?, right_side_angle = ?, left_side_angle = ?, observe_angle = ?,
+		hex_color = ?, init_angle = ?, data_rate = ?, max_on_time = ?, max_acquisitions = ?,
+		min_gap = ?, eclipse_aware = ?, max_pitch_angle = ?, sensor_type = ?, near_incidence = ?,
+		far_incidence = ?, look_side = ?, orbit_direction = ? WHERE id = ?`,
+		s.SatID, s.Name, s.Resolution, s.Width,
+		s.RightSideAngle, s.LeftSideAngle, s.ObserveAngle,
+		s.HexColor, s.InitAngle, s.DataRate,
+		s.MaxOnTime, s.MaxAcquisitions, s.MinGap, boolInt(s.EclipseAware), s.MaxPitchAngle,
+		s.SensorType, s.NearIncidence, s.FarIncidence, s.LookSide, s.OrbitDirection, s.ID))
+	if isForeignKeyViolation(err) {
+		return ErrSatelliteNotFound
+	}
+	return err
+}
+
+func (r sensorRepo) Delete(ctx context.Context, id int) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range []string{
+			"DELETE FROM band WHERE mode_id IN (SELECT id FROM sensor_mode WHERE sensor_id = ?)",
+			"DELETE FROM sensor_mode WHERE sensor_id = ?",
+		} {
+			if _, err := r.db.exec(ctx, tx, stmt, id); err != nil {
+				return err
+			}
+		}
+		return affected(r.db.exec(ctx, tx, "DELETE FROM sensor WHERE id = ?", id))
+	})
+}
+
+// resolveSatellite looks up the satellite of a sensor by SatID or, when that is zero, by
+// SatNoardID and fills in the other satellite fields
+func (r sensorRepo) resolveSatellite(ctx context.Context, s *models.Sensor) error {
+	var err error
+	if s.SatID != 0 {
+		err = r.db.queryRow(ctx, r.db, "SELECT noard_id, COALESCE(name, '') FROM satellite WHERE id = ?", s.SatID).
+			Scan(&s.SatNoardID, &s.SatName)
+	} else {
+		err = r.db.queryRow(ctx, r.db, "SELECT id, COALESCE(name, '') FROM satellite WHERE noard_id = ?", s.SatNoardID).
+			Scan(&s.SatID, &s.SatName)
+	}
+	if err == sql.ErrNoRows {
+		return ErrSatelliteNotFound
+	}
+	return err
+}
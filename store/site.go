@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+
+	"satplan/models"
+)
+
+type tleSiteRepo struct{ db *sqlDB }
+
+func (r tleSiteRepo) List(ctx context.Context) ([]models.TLESite, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT id, site, url, description FROM tle_site ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := []models.TLESite{}
+	for rows.Next() {
+		var site models.TLESite
+		if err := rows.Scan(&site.ID, &site.Site, &site.URL, &site.Description); err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+func (r tleSiteRepo) Get(ctx context.Context, id int) (models.TLESite, error) {
+	var site models.TLESite
+	err := r.db.queryRow(ctx, r.db, "SELECT id, site, url, description FROM tle_site WHERE id = ?", id).
+		Scan(&site.ID, &site.Site, &site.URL, &site.Description)
+	return site, notFound(err)
+}
+
+func (r tleSiteRepo) Create(ctx context.Context, site *models.TLESite) error {
+	id, err := r.db.insert(ctx, r.db, "INSERT INTO tle_site (site, url, description) VALUES (?, ?, ?)",
+		site.Site, site.URL, site.Description)
+	if err != nil {
+		return err
+	}
+	site.ID = id
+	return nil
+}
+
+func (r tleSiteRepo) Update(ctx context.Context, site models.TLESite) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE tle_site SET site = ?, url = ?, description = ? WHERE id = ?",
+		site.Site, site.URL, site.Description, site.ID))
+}
+
+func (r tleSiteRepo) Delete(ctx context.Context, id int) error {
+	return affected(r.db.exec(ctx, r.db, "DELETE FROM tle_site WHERE id = ?", id))
+}
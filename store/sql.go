@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"satplan/database"
+	"satplan/planner"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sqlDB is a database reached through database/sql. Queries are written for SQLite with
+// ? placeholders and SQL that PostgreSQL accepts as well; rebind adapts the placeholders.
+type sqlDB struct {
+	*sql.DB
+	postgres bool
+
+	mu   sync.Mutex
+	held map[int64]bool // locks taken by tryLock on SQLite
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rebind numbers the ? placeholders of a query as $1, $2, ... for PostgreSQL
+func (d *sqlDB) rebind(query string) string {
+	if !d.postgres {
+		return query
+	}
+	return database.Rebind(query)
+}
+
+func (d *sqlDB) exec(ctx context.Context, q querier, query string, args ...interface{}) (sql.Result, error) {
+	return q.ExecContext(ctx, d.rebind(query), args...)
+}
+
+func (d *sqlDB) query(ctx context.Context, q querier, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.QueryContext(ctx, d.rebind(query), args...)
+}
+
+func (d *sqlDB) queryRow(ctx context.Context, q querier, query string, args ...interface{}) *sql.Row {
+	return q.QueryRowContext(ctx, d.rebind(query), args...)
+}
+
+// insert runs an INSERT statement and returns the ID of the new row
+func (d *sqlDB) insert(ctx context.Context, q querier, query string, args ...interface{}) (int, error) {
+	var id int
+	err := d.queryRow(ctx, q, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// areaCondition matches the plans or tasking requests whose area overlaps a: through the
+// aoi column and its GIST index on PostgreSQL, by comparing bounding boxes on SQLite
+func (d *sqlDB) areaCondition(a planner.TargetArea) (string, []interface{}) {
+	args := []interface{}{a.West, a.South, a.East, a.North}
+	if d.postgres {
+		return "ST_Intersects(aoi, ST_MakeEnvelope(?, ?, ?, ?, 4326))", args
+	}
+	return "max_lon >= ? AND max_lat >= ? AND min_lon <= ? AND min_lat <= ?", args
+}
+
+// inTx runs fn in a transaction, committing when it returns nil
+func (d *sqlDB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// tryLock takes the lock named by key unless it is held and reports whether it did. On
+// PostgreSQL it is a session advisory lock held on a connection of its own, so it keeps
+// out every process sharing the database and goes away with a process that dies; on
+// SQLite it only keeps out the rest of this process. unlock releases it.
+func (d *sqlDB) tryLock(ctx context.Context, key int64) (unlock func(), ok bool, err error) {
+	if !d.postgres {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.held[key] {
+			return nil, false, nil
+		}
+		if d.held == nil {
+			d.held = map[int64]bool{}
+		}
+		d.held[key] = true
+		return func() {
+			d.mu.Lock()
+			delete(d.held, key)
+			d.mu.Unlock()
+		}, true, nil
+	}
+
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		conn.Close()
+	}, true, nil
+}
+
+// conflictError is an ErrConflict whose message says what the conflict is
+type conflictError string
+
+func (e conflictError) Error() string { return string(e) }
+
+func (e conflictError) Is(target error) bool { return target == ErrConflict }
+
+// isUniqueViolation reports whether an error is a unique constraint failure
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// isForeignKeyViolation reports whether an error is a foreign key constraint failure
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23503"
+	}
+	return err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
+
+// notFound turns sql.ErrNoRows into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected returns ErrNotFound when a statement changed no row
+func affected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// count returns the number of rows of a table
+func count(ctx context.Context, db *sqlDB, table string) (int, error) {
+	var n int
+	err := db.queryRow(ctx, db, "SELECT COUNT(*) FROM "+table).Scan(&n)
+	return n, err
+}
+
+// boolInt stores a flag as 0 or 1, the way the INTEGER flag columns hold it
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+
+	"satplan/models"
+)
+
+const stationColumns = "id, name, lat, lon, COALESCE(alt, 0), COALESCE(min_elevation, 0)"
+
+func scanStation(row rowScanner, st *models.GroundStation) error {
+	return row.Scan(&st.ID, &st.Name, &st.Lat, &st.Lon, &st.Alt, &st.MinElevation)
+}
+
+type stationRepo struct{ db *sqlDB }
+
+func (r stationRepo) List(ctx context.Context) ([]models.GroundStation, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT "+stationColumns+" FROM ground_station ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stations := []models.GroundStation{}
+	for rows.Next() {
+		var st models.GroundStation
+		if err := scanStation(rows, &st); err != nil {
+			return nil, err
+		}
+		stations = append(stations, st)
+	}
+	return stations, rows.Err()
+}
+
+func (r stationRepo) Get(ctx context.Context, id int) (models.GroundStation, error) {
+	var st models.GroundStation
+	err := scanStation(r.db.queryRow(ctx, r.db, "SELECT "+stationColumns+" FROM ground_station WHERE id = ?", id), &st)
+	return st, notFound(err)
+}
+
+func (r stationRepo) Create(ctx context.Context, st *models.GroundStation) error {
+	id, err := r.db.insert(ctx, r.db, "INSERT INTO ground_station (name, lat, lon, alt, min_elevation) VALUES (?, ?, ?, ?, ?)",
+		st.Name, st.Lat, st.Lon, st.Alt, st.MinElevation)
+	if err != nil {
+		return err
+	}
+	st.ID = id
+	return nil
+}
+
+func (r stationRepo) Update(ctx context.Context, st models.GroundStation) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE ground_station SET name = ?, lat = ?, lon = ?, alt = ?, min_elevation = ? WHERE id = ?",
+		st.Name, st.Lat, st.Lon, st.Alt, st.MinElevation, st.ID))
+}
+
+func (r stationRepo) Delete(ctx context.Context, id int) error {
+	return affected(r.db.exec(ctx, r.db, "DELETE FROM ground_station WHERE id = ?", id))
+}
@@ -0,0 +1,336 @@
+// Package store holds the repositories through which everything the server keeps is read
+// and written. The same repositories run on SQLite and on PostgreSQL with PostGIS, so that
+// several servers can share one database.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"satplan/models"
+	"satplan/orbit"
+	"satplan/planner"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrSatelliteNotFound is returned when a sensor or TLE refers to a satellite that
+	// does not exist
+	ErrSatelliteNotFound = errors.New("satellite not found")
+	// ErrConflict matches, with errors.Is, the errors returned when a write would break
+	// a uniqueness or reference rule. Their message states the conflict.
+	ErrConflict = errors.New("conflict")
+)
+
+// SatelliteFilter narrows a satellite list. Each slice matches any of its values, case
+// insensitively; empty fields do not filter.
+type SatelliteFilter struct {
+	Status         []string
+	Operator       []string
+	Country        []string
+	OrbitClass     []string
+	LaunchedAfter  string // YYYY-MM-DD, inclusive
+	LaunchedBefore string
+	Query          string // substring of the name, NORAD ID or international designator
+}
+
+// SatelliteRepository stores satellites
+type SatelliteRepository interface {
+	List(ctx context.Context, filter SatelliteFilter) ([]models.Satellite, error)
+	Get(ctx context.Context, id int) (models.Satellite, error)
+	GetByNoradID(ctx context.Context, noradID string) (models.Satellite, error)
+	Count(ctx context.Context) (int, error)
+	// Create inserts a satellite and sets its ID; a NORAD ID in use is an ErrConflict
+	Create(ctx context.Context, sat *models.Satellite) error
+	// Update replaces the satellite with sat.ID. Everything that refers to a satellite does
+	// so by its ID and follows a change of NORAD ID.
+	Update(ctx context.Context, sat models.Satellite) error
+	// Delete removes a satellite with its TLEs, ephemerides, group memberships,
+	// conjunctions and footprints. A satellite that still has sensors is an ErrConflict.
+	Delete(ctx context.Context, id int) error
+	// Import stores catalogue metadata on the satellites with matching NORAD IDs, leaving
+	// empty fields unchanged, and with create adds the missing ones. It is all or nothing.
+	Import(ctx context.Context, sats []models.Satellite, create bool) (models.SatelliteImportResult, error)
+}
+
+// SensorRepository stores sensors. The satellite of a sensor is given by SatID or, when
+// that is zero, by SatNoardID; the other satellite fields are filled in.
+type SensorRepository interface {
+	List(ctx context.Context) ([]models.Sensor, error)
+	ListBySatellite(ctx context.Context, noradID string) ([]models.Sensor, error)
+	Get(ctx context.Context, id int) (models.Sensor, error)
+	Count(ctx context.Context) (int, error)
+	Create(ctx context.Context, s *models.Sensor) error
+	Update(ctx context.Context, s *models.Sensor) error
+	// Delete removes a sensor with its modes and their bands
+	Delete(ctx context.Context, id int) error
+}
+
+// TLEAddResult counts the TLEs stored by TLERepository.Add
+type TLEAddResult struct {
+	Inserted int
+	Skipped  int
+	NotFound []string // NORAD IDs without a satellite
+}
+
+// TLERepository stores TLEs. Lists are ordered newest first.
+type TLERepository interface {
+	List(ctx context.Context, limit int) ([]models.TLE, error)
+	ListBySatellite(ctx context.Context, noradID string) ([]models.TLE, error)
+	// ListRecent returns the TLEs of all satellites fetched within span seconds of the
+	// latest TLE of their satellite, ordered by NORAD ID
+	ListRecent(ctx context.Context, span int64) ([]models.TLE, error)
+	Get(ctx context.Context, id int) (models.TLE, error)
+	// Latest returns the newest TLE of the satellite with the given ID
+	Latest(ctx context.Context, satID int) (models.TLE, error)
+	Count(ctx context.Context) (int, error)
+	// Add stores TLEs by their SatNoardID in one transaction, skipping those of unknown
+	// satellites
+	Add(ctx context.Context, tles []models.TLE) (TLEAddResult, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// TLESiteRepository stores the TLE sources
+type TLESiteRepository interface {
+	List(ctx context.Context) ([]models.TLESite, error)
+	Get(ctx context.Context, id int) (models.TLESite, error)
+	Create(ctx context.Context, site *models.TLESite) error
+	Update(ctx context.Context, site models.TLESite) error
+	Delete(ctx context.Context, id int) error
+}
+
+// UserRepository stores users. Only GetByUsername reads the password hash.
+type UserRepository interface {
+	List(ctx context.Context) ([]models.User, error)
+	Get(ctx context.Context, id int) (models.User, error)
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+	Create(ctx context.Context, u *models.User) error
+	PasswordHash(ctx context.Context, id int) (string, error)
+	SetPasswordHash(ctx context.Context, id int, hash string) error
+}
+
+// FootprintQuery selects stored footprints overlapping an area and a time window. Zero
+// times and sensor IDs do not filter.
+type FootprintQuery struct {
+	Area     planner.TargetArea
+	Start    int64
+	End      int64
+	SensorID int
+}
+
+// FootprintRepository stores the ground footprints of the strips computed for plans
+type FootprintRepository interface {
+	// Replace stores the strips of a plan in place of its previous footprints
+	Replace(ctx context.Context, planID int, strips []models.Strip) error
+	Search(ctx context.Context, q FootprintQuery) ([]models.Footprint, error)
+}
+
+// SensorModeRepository stores sensor modes with their bands
+type SensorModeRepository interface {
+	List(ctx context.Context) ([]models.SensorMode, error)
+	ListBySensor(ctx context.Context, sensorID int) ([]models.SensorMode, error)
+	Get(ctx context.Context, id int) (models.SensorMode, error)
+	// Create inserts a mode of m.SensorID, which is ErrNotFound if the sensor does not exist
+	Create(ctx context.Context, m *models.SensorMode) error
+	// Update replaces the mode with m.ID and its bands
+	Update(ctx context.Context, m *models.SensorMode) error
+	Delete(ctx context.Context, id int) error
+}
+
+// GroupRepository stores satellite groups. Members are given by NORAD ID.
+type GroupRepository interface {
+	// List returns all groups with their direct members
+	List(ctx context.Context) ([]models.SatelliteGroup, error)
+	Get(ctx context.Context, id int) (models.SatelliteGroup, error)
+	// Parent returns the parent ID of a group, zero for a top-level one
+	Parent(ctx context.Context, id int) (int, error)
+	// Create inserts a group with its members. If some members are not known satellites
+	// nothing is stored and their NORAD IDs are returned.
+	Create(ctx context.Context, g *models.SatelliteGroup) ([]string, error)
+	Update(ctx context.Context, g models.SatelliteGroup) error
+	// Delete removes a group. A group with subgroups or plans is an ErrConflict.
+	Delete(ctx context.Context, id int) error
+	// AddMembers adds satellites to a group as Create does, returning the unknown ones
+	AddMembers(ctx context.Context, id int, noradIDs []string) ([]string, error)
+	RemoveMember(ctx context.Context, id int, noradID string) error
+	// Satellites returns the NORAD IDs of the members of a group and its subgroups
+	Satellites(ctx context.Context, id int) ([]string, error)
+	// Sensors returns the sensors of the members of a group and its subgroups
+	Sensors(ctx context.Context, id int) ([]models.Sensor, error)
+}
+
+// PlanFilter narrows a plan list; a nil Area does not filter
+type PlanFilter struct {
+	Area *planner.TargetArea // plans whose area overlaps this box
+}
+
+// PlanRepository stores plans with their sensor and group selections
+type PlanRepository interface {
+	// List returns the plans matching filter, newest first
+	List(ctx context.Context, filter PlanFilter) ([]models.Plan, error)
+	Get(ctx context.Context, id int) (models.Plan, error)
+	Create(ctx context.Context, p *models.Plan) error
+	Update(ctx context.Context, p models.Plan) error
+	// Delete removes a plan with its selections and footprints
+	Delete(ctx context.Context, id int) error
+}
+
+// TaskingRequestFilter narrows a tasking request list; empty fields do not filter
+type TaskingRequestFilter struct {
+	Status string
+	Area   *planner.TargetArea // requests whose area overlaps this box
+}
+
+// RequestTransition is a tasking request state change allowed from any of From
+type RequestTransition struct {
+	From []string
+	To   string
+}
+
+// TaskingRequestRepository stores tasking requests and their audit trail. Each write
+// records an event.
+type TaskingRequestRepository interface {
+	// List returns the requests by priority and deadline
+	List(ctx context.Context, filter TaskingRequestFilter) ([]models.TaskingRequest, error)
+	Get(ctx context.Context, id int) (models.TaskingRequest, error)
+	// Create inserts a request and records its creation by t.UserID
+	Create(ctx context.Context, t *models.TaskingRequest) error
+	// Update replaces a draft request, recording the changes made by userID. A request
+	// that is no longer a draft is an ErrConflict.
+	Update(ctx context.Context, t models.TaskingRequest, userID int, changes map[string][2]interface{}) error
+	// Delete removes a draft request, recording its deletion by userID in its events,
+	// which are kept; any other request is an ErrConflict
+	Delete(ctx context.Context, id, userID int) error
+	// Transition applies action to the requests keyed in notes, recording each with its
+	// note, in one transaction. If any request does not allow it nothing changes and the
+	// current status of each such request is returned, empty for a missing one.
+	Transition(ctx context.Context, action string, t RequestTransition, userID int, notes map[int]string) (map[int]string, error)
+	// Events returns the audit trail of a request, oldest first, also once it is deleted
+	Events(ctx context.Context, id int) ([]models.TaskingRequestEvent, error)
+}
+
+// GroundStationRepository stores ground stations
+type GroundStationRepository interface {
+	List(ctx context.Context) ([]models.GroundStation, error)
+	Get(ctx context.Context, id int) (models.GroundStation, error)
+	Create(ctx context.Context, st *models.GroundStation) error
+	Update(ctx context.Context, st models.GroundStation) error
+	Delete(ctx context.Context, id int) error
+}
+
+// PointTargetRepository stores point targets
+type PointTargetRepository interface {
+	List(ctx context.Context) ([]models.PointTarget, error)
+	Get(ctx context.Context, id int) (models.PointTarget, error)
+	// Create inserts targets in one transaction and sets their IDs
+	Create(ctx context.Context, targets []models.PointTarget) error
+	Update(ctx context.Context, t models.PointTarget) error
+	Delete(ctx context.Context, id int) error
+}
+
+// EphemerisRepository stores imported precise ephemerides
+type EphemerisRepository interface {
+	// List returns the ephemerides of a satellite, or of all with an empty NORAD ID
+	List(ctx context.Context, noradID string) ([]models.Ephemeris, error)
+	Get(ctx context.Context, id int) (models.Ephemeris, error)
+	// Create stores an ephemeris of e.SatNoardID with its points; an unknown satellite is
+	// ErrSatelliteNotFound
+	Create(ctx context.Context, e *models.Ephemeris, points []orbit.EphemerisPoint) error
+	Delete(ctx context.Context, id int) error
+	// Covering returns the newest ephemeris of a satellite spanning start to end, or nil
+	// if there is none
+	Covering(ctx context.Context, noradID string, start, end int64) (*orbit.Ephemeris, error)
+}
+
+// ConjunctionFilter narrows the conjunctions of a screening run; nil and empty fields do
+// not filter
+type ConjunctionFilter struct {
+	RunID      int
+	SatNoardID string   // either object of the pair
+	MaxMiss    *float64 // km
+	Start      *float64 // unix seconds
+	End        *float64
+}
+
+// ScreeningRepository stores conjunction screening runs and the approaches they found
+type ScreeningRepository interface {
+	// List returns all runs, newest first
+	List(ctx context.Context) ([]models.ScreeningRun, error)
+	Get(ctx context.Context, id int) (models.ScreeningRun, error)
+	// Latest returns the ID of the newest finished run
+	Latest(ctx context.Context) (int, error)
+	// Start inserts a running run and claims it until release is called. While another
+	// run is claimed, by this process or another sharing the database, it is an
+	// ErrConflict; runs left running by a process that stopped are marked interrupted.
+	Start(ctx context.Context, run *models.ScreeningRun) (release func(), err error)
+	// Finish stores the conjunctions of a run and its Objects, Pairs and Message, and
+	// marks it done
+	Finish(ctx context.Context, run models.ScreeningRun, conjunctions []models.Conjunction) error
+	Fail(ctx context.Context, id int, message string) error
+	// Delete removes a run with its conjunctions; a running one is an ErrConflict
+	Delete(ctx context.Context, id int) error
+	Conjunctions(ctx context.Context, filter ConjunctionFilter) ([]models.Conjunction, error)
+}
+
+// FeedTokenRepository stores the calendar feed tokens of users by the hash of the token
+type FeedTokenRepository interface {
+	List(ctx context.Context, userID int) ([]models.FeedToken, error)
+	Create(ctx context.Context, t *models.FeedToken, hash string) error
+	// Delete removes a token of userID; one of another user is ErrNotFound
+	Delete(ctx context.Context, id, userID int) error
+	// Lookup returns the ID of the token with the given hash and the user it belongs to
+	Lookup(ctx context.Context, hash string) (int, models.User, error)
+	MarkUsed(ctx context.Context, id int, at int64) error
+}
+
+// Store bundles the repositories of one database
+type Store struct {
+	Satellites  SatelliteRepository
+	Sensors     SensorRepository
+	Modes       SensorModeRepository
+	TLEs        TLERepository
+	TLESites    TLESiteRepository
+	Groups      GroupRepository
+	Plans       PlanRepository
+	Requests    TaskingRequestRepository
+	Stations    GroundStationRepository
+	Targets     PointTargetRepository
+	Ephemerides EphemerisRepository
+	Screenings  ScreeningRepository
+	FeedTokens  FeedTokenRepository
+	Users       UserRepository
+	Footprints  FootprintRepository
+}
+
+// NewSQLite returns the repositories of a SQLite database
+func NewSQLite(db *sql.DB) *Store {
+	return newStore(&sqlDB{DB: db})
+}
+
+// NewPostgres returns the repositories of a PostgreSQL database with the PostGIS
+// extension
+func NewPostgres(db *sql.DB) *Store {
+	return newStore(&sqlDB{DB: db, postgres: true})
+}
+
+func newStore(db *sqlDB) *Store {
+	return &Store{
+		Satellites:  satelliteRepo{db},
+		Sensors:     sensorRepo{db},
+		Modes:       modeRepo{db},
+		TLEs:        tleRepo{db},
+		TLESites:    tleSiteRepo{db},
+		Groups:      groupRepo{db},
+		Plans:       planRepo{db},
+		Requests:    taskingRequestRepo{db},
+		Stations:    stationRepo{db},
+		Targets:     targetRepo{db},
+		Ephemerides: ephemerisRepo{db},
+		Screenings:  screeningRepo{db},
+		FeedTokens:  feedTokenRepo{db},
+		Users:       userRepo{db},
+		Footprints:  footprintRepo{db},
+	}
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"satplan/models"
+)
+
+const targetColumns = "id, name, lat, lon, COALESCE(alt, 0)"
+
+func scanTarget(row rowScanner, t *models.PointTarget) error {
+	return row.Scan(&t.ID, &t.Name, &t.Lat, &t.Lon, &t.Alt)
+}
+
+type targetRepo struct{ db *sqlDB }
+
+func (r targetRepo) List(ctx context.Context) ([]models.PointTarget, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT "+targetColumns+" FROM point_target ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []models.PointTarget{}
+	for rows.Next() {
+		var t models.PointTarget
+		if err := scanTarget(rows, &t); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, rows.Err()
+}
+
+func (r targetRepo) Get(ctx context.Context, id int) (models.PointTarget, error) {
+	var t models.PointTarget
+	err := scanTarget(r.db.queryRow(ctx, r.db, "SELECT "+targetColumns+" FROM point_target WHERE id = ?", id), &t)
+	return t, notFound(err)
+}
+
+func (r targetRepo) Create(ctx context.Context, targets []models.PointTarget) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for i := range targets {
+			id, err := r.db.insert(ctx, tx, "INSERT INTO point_target (name, lat, lon, alt) VALUES (?, ?, ?, ?)",
+				targets[i].Name, targets[i].Lat, targets[i].Lon, targets[i].Alt)
+			if err != nil {
+				return err
+			}
+			targets[i].ID = id
+		}
+		return nil
+	})
+}
+
+func (r targetRepo) Update(ctx context.Context, t models.PointTarget) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE point_target SET name = ?, lat = ?, lon = ?, alt = ? WHERE id = ?",
+		t.Name, t.Lat, t.Lon, t.Alt, t.ID))
+}
+
+func (r targetRepo) Delete(ctx context.Context, id int) error {
+	return affected(r.db.exec(ctx, r.db, "DELETE FROM point_target WHERE id = ?", id))
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"satplan/models"
+)
+
+// tleColumns is the column list of a TLE read from tleTable, with the NORAD ID taken from
+// the satellite
+const tleColumns = `t.id, t.sat_id, sat.noard_id, t."time", t.line1, t.line2`
+
+const tleTable = "tle t JOIN satellite sat ON sat.id = t.sat_id"
+
+type tleRepo struct{ db *sqlDB }
+
+func (r tleRepo) List(ctx context.Context, limit int) ([]models.TLE, error) {
+	return r.list(ctx, "SELECT "+tleColumns+" FROM "+tleTable+` ORDER BY t."time" DESC LIMIT ?`, limit)
+}
+
+func (r tleRepo) ListBySatellite(ctx context.Context, noradID string) ([]models.TLE, error) {
+	return r.list(ctx, "SELECT "+tleColumns+" FROM "+tleTable+` WHERE sat.noard_id = ? ORDER BY t."time" DESC`, noradID)
+}
+
+func (r tleRepo) ListRecent(ctx context.Context, span int64) ([]models.TLE, error) {
+	return r.list(ctx, "SELECT "+tleColumns+" FROM "+tleTable+` WHERE t."time" >= (SELECT MAX(l."time") FROM tle l
+		WHERE l.sat_id = t.sat_id) - ? ORDER BY sat.noard_id, t."time" DESC`, span)
+}
+
+func (r tleRepo) Get(ctx context.Context, id int) (models.TLE, error) {
+	var t models.TLE
+	err := r.db.queryRow(ctx, r.db, "SELECT "+tleColumns+" FROM "+tleTable+" WHERE t.id = ?", id).
+		Scan(&t.ID, &t.SatID, &t.SatNoardID, &t.Time, &t.Line1, &t.Line2)
+	return t, notFound(err)
+}
+
+func (r tleRepo) Latest(ctx context.Context, satID int) (models.TLE, error) {
+	var t models.TLE
+	err := r.db.queryRow(ctx, r.db, "SELECT "+tleColumns+" FROM "+tleTable+` WHERE t.sat_id = ? ORDER BY t."time" DESC LIMIT 1`, satID).
+		Scan(&t.ID, &t.SatID, &t.SatNoardID, &t.Time, &t.Line1, &t.Line2)
+	return t, notFound(err)
+}
+
+func (r tleRepo) Count(ctx context.Context) (int, error) {
+	return count(ctx, r.db, "tle")
+}
+
+func (r tleRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.TLE, error) {
+	rows, err := r.db.query(ctx, r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tles := []models.TLE{}
+	for rows.Next() {
+		var t models.TLE
+		if err := rows.Scan(&t.ID, &t.SatID, &t.SatNoardID, &t.Time, &t.Line1, &t.Line2); err != nil {
+			return nil, err
+		}
+		tles = append(tles, t)
+	}
+	return tles, rows.Err()
+}
+
+func (r tleRepo) Add(ctx context.Context, tles []models.TLE) (TLEAddResult, error) {
+	result := TLEAddResult{NotFound: []string{}}
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		satIDs := map[string]int{}
+		rows, err := r.db.query(ctx, tx, "SELECT id, noard_id FROM satellite")
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int
+			var noradID string
+			if err := rows.Scan(&id, &noradID); err != nil {
+				rows.Close()
+				return err
+			}
+			satIDs[noradID] = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, tle := range tles {
+			satID, ok := satIDs[tle.SatNoardID]
+			if !ok {
+				result.NotFound = append(result.NotFound, tle.SatNoardID)
+				result.Skipped++
+				continue
+			}
+			if _, err := r.db.exec(ctx, tx, `INSERT INTO tle (sat_id, "time", line1, line2) VALUES (?, ?, ?, ?)`,
+				satID, tle.Time, tle.Line1, tle.Line2); err != nil {
+				return fmt.Errorf("failed to insert TLE for satellite %s: %v", tle.SatNoardID, err)
+			}
+			result.Inserted++
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (r tleRepo) Delete(ctx context.Context, id int) error {
+	return affected(r.db.exec(ctx, r.db, "DELETE FROM tle WHERE id = ?", id))
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"satplan/models"
+)
+
+type userRepo struct{ db *sqlDB }
+
+func (r userRepo) List(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.query(ctx, r.db, "SELECT id, user_name, email FROM sys_user ORDER BY user_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r userRepo) Get(ctx context.Context, id int) (models.User, error) {
+	var u models.User
+	err := r.db.queryRow(ctx, r.db, "SELECT id, user_name, email FROM sys_user WHERE id = ?", id).
+		Scan(&u.ID, &u.Username, &u.Email)
+	return u, notFound(err)
+}
+
+func (r userRepo) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	var u models.User
+	err := r.db.queryRow(ctx, r.db, "SELECT id, user_name, password, email FROM sys_user WHERE user_name = ?", username).
+		Scan(&u.ID, &u.Username, &u.Password, &u.Email)
+	return u, notFound(err)
+}
+
+// Create inserts a user with u.Password as the password hash. User names are checked
+// here as well as by the unique index, which the SQLite schema does not have.
+func (r userRepo) Create(ctx context.Context, u *models.User) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := r.db.queryRow(ctx, tx, "SELECT EXISTS(SELECT 1 FROM sys_user WHERE user_name = ?)", u.Username).Scan(&exists); err != nil {
+			return err
+		}
+		conflict := conflictError("A user named " + u.Username + " already exists")
+		if exists {
+			return conflict
+		}
+		id, err := r.db.insert(ctx, tx, "INSERT INTO sys_user (user_name, password, email) VALUES (?, ?, ?)",
+			u.Username, u.Password, u.Email)
+		if isUniqueViolation(err) {
+			return conflict
+		} else if err != nil {
+			return err
+		}
+		u.ID = id
+		return nil
+	})
+}
+
+func (r userRepo) PasswordHash(ctx context.Context, id int) (string, error) {
+	var hash string
+	err := r.db.queryRow(ctx, r.db, "SELECT password FROM sys_user WHERE id = ?", id).Scan(&hash)
+	return hash, notFound(err)
+}
+
+func (r userRepo) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	return affected(r.db.exec(ctx, r.db, "UPDATE sys_user SET password = ? WHERE id = ?", hash, id))
+}